@@ -7,13 +7,13 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
-	"strings"
 	"time"
 
 	"telegraph_uploader_v2/internal/config"
 	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/imageformat"
+	"telegraph_uploader_v2/internal/ordering"
 	"telegraph_uploader_v2/internal/repository"
 	"telegraph_uploader_v2/internal/service"
 	"telegraph_uploader_v2/internal/telegram"
@@ -21,6 +21,7 @@ import (
 	"telegraph_uploader_v2/internal/uploader"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+	"gorm.io/gorm"
 )
 
 type App struct {
@@ -30,6 +31,10 @@ type App struct {
 	// Services
 	mangaService *service.MangaService
 	pubService   *service.PublicationService
+	accountService *service.AccountService
+	statsService   *service.StatsService
+	linkChecker    *service.LinkChecker
+	migrations     *service.MigrationService
 	
 	// Infrastructure
 	r2Uploader *uploader.R2Uploader
@@ -44,6 +49,7 @@ type App struct {
 	telegram     *telegram.Client
 	
 	dialogs      DialogProvider
+	events       EventEmitter
 
 	authPasswordChan chan string
 	authHandler      *WailsAuthHandler
@@ -67,12 +73,32 @@ func NewApp() *App {
 	}
 	log.Println("[App] Database initialized")
 
+	tgClient := telegraph.New(cfg)
+	log.Println("[App] Telegraph client initialized")
+
+	tgApp, err := telegram.New(cfg)
+	if err != nil {
+		log.Println("[App] Telegram client init error:", err)
+	} else {
+		log.Println("[App] Telegram client initialized")
+	}
+
+	return newApp(cfg, dbInstance, tgClient, tgApp)
+}
+
+// newApp связывает репозитории и сервисы поверх готовых БД и клиентов
+func newApp(cfg *config.Config, dbInstance *gorm.DB, tgClient *telegraph.Client, tgApp *telegram.Client) *App {
 	// 2. Init Repositories
 	settingsRepo := repository.NewSettingsRepository(dbInstance)
 	historyRepo := repository.NewHistoryRepository(dbInstance)
 	titleRepo := repository.NewTitleRepository(dbInstance)
 	templateRepo := repository.NewTemplateRepository(dbInstance)
 	cacheRepo := repository.NewImageCacheRepository(dbInstance)
+	accountRepo := repository.NewAccountRepository(dbInstance)
+	viewsRepo := repository.NewViewsRepository(dbInstance)
+	revisionRepo := repository.NewRevisionRepository(dbInstance)
+	linkRepo := repository.NewLinkRepository(dbInstance)
+	migrationRepo := repository.NewMigrationRepository(dbInstance)
 
 	// 3. Init Infrastructure Clients
 	r2Uploader, err := uploader.New(cfg, cacheRepo)
@@ -82,19 +108,20 @@ func NewApp() *App {
 		log.Println("[App] Uploader initialized")
 	}
 
-	tgClient := telegraph.New(cfg)
-	log.Println("[App] Telegraph client initialized")
-
-	tgApp, err := telegram.New(cfg)
-	if err != nil {
-		log.Println("[App] Telegram client init error:", err)
-	} else {
-		log.Println("[App] Telegram client initialized")
-	}
-
 	// 4. Init Services
 	mangaService := service.NewMangaService(r2Uploader)
-	pubService := service.NewPublicationService(tgClient, tgApp, historyRepo, titleRepo)
+	pubService := service.NewPublicationService(tgClient, tgApp, historyRepo, titleRepo, accountRepo, revisionRepo, templateRepo)
+	accountService := service.NewAccountService(tgClient, accountRepo)
+	statsService := service.NewStatsService(tgClient, historyRepo, viewsRepo)
+	var reuploader service.Reuploader
+	if r2Uploader != nil {
+		reuploader = r2Uploader
+	}
+	linkChecker := service.NewLinkChecker(pubService, historyRepo, linkRepo, reuploader)
+	migrations := service.NewMigrationService(pubService, historyRepo, migrationRepo, cacheRepo)
+	if err := accountService.Init(); err != nil {
+		log.Println("[App] Telegraph accounts init error:", err)
+	}
 
 	pwdChan := make(chan string)
 
@@ -102,6 +129,10 @@ func NewApp() *App {
 		config:           cfg,
 		mangaService:     mangaService,
 		pubService:       pubService,
+		accountService:   accountService,
+		statsService:     statsService,
+		linkChecker:      linkChecker,
+		migrations:       migrations,
 		r2Uploader:       r2Uploader,
 		settingsRepo:     settingsRepo,
 		historyRepo:      historyRepo,
@@ -109,6 +140,7 @@ func NewApp() *App {
 		templateRepo:     templateRepo,
 		telegram:         tgApp,
 		dialogs:          &WailsDialogProvider{},
+		events:           &WailsEventEmitter{},
 		authPasswordChan: pwdChan,
 		authHandler:      &WailsAuthHandler{passwordChan: pwdChan},
 	}
@@ -122,6 +154,10 @@ func (a *App) startup(ctx context.Context) {
 	if a.telegram != nil {
 		a.telegram.Start(ctx)
 	}
+
+	if a.statsService != nil {
+		a.statsService.Start(ctx)
+	}
 }
 
 // === МЕТОДЫ ===
@@ -131,7 +167,7 @@ func (a *App) UploadChapter(filePaths []string, resizeSettings uploader.ResizeSe
 	
 	onProgress := func(current, total int) {
 		percentage := int(float64(current) / float64(total) * 100)
-		wailsRuntime.EventsEmit(a.ctx, "upload_progress", map[string]int{
+		a.emit("upload_progress", map[string]int{
 			"current":    current,
 			"total":      total,
 			"percentage": percentage,
@@ -146,10 +182,54 @@ func (a *App) UploadChapter(filePaths []string, resizeSettings uploader.ResizeSe
 	} else {
 		log.Printf("[App] UploadChapter failed. Error: %s", result.Error)
 	}
+	for _, w := range result.Warnings {
+		log.Printf("[App] UploadChapter warning: %s", w)
+	}
+	for _, d := range result.Spreads {
+		if d.Spread {
+			log.Printf("[App] UploadChapter spread: %s (%dx%d) -> %s, pages: %d", filepath.Base(d.Path), d.Width, d.Height, d.Action, d.Pages)
+		}
+	}
 
 	return result
 }
 
+// EstimateChapter — пробный прогон UploadChapter: считает размеры, кэш и объём страницы без загрузки.
+// sampleSize > 0 кодирует только часть страниц, остальные экстраполируются.
+func (a *App) EstimateChapter(filePaths []string, resizeSettings uploader.ResizeSettings, sampleSize int) uploader.EstimateResult {
+	log.Printf("[App] EstimateChapter called. Files: %d, Sample: %d, Settings: %+v", len(filePaths), sampleSize, resizeSettings)
+
+	onProgress := func(current, total int) {
+		percentage := int(float64(current) / float64(total) * 100)
+		a.emit("estimate_progress", map[string]int{
+			"current":    current,
+			"total":      total,
+			"percentage": percentage,
+		})
+	}
+
+	result := a.mangaService.EstimateChapter(a.ctx, filePaths, resizeSettings, sampleSize, onProgress)
+
+	if result.Success {
+		log.Printf("[App] EstimateChapter finished. Output: %d images, ~%d bytes (ratio %.2f), cache hits: %d, page content: %d bytes",
+			result.OutputImages, result.EstimatedOutputBytes, result.CompressionRatio, result.CacheHits, result.PageContentBytes)
+	} else {
+		log.Printf("[App] EstimateChapter failed. Error: %s", result.Error)
+	}
+
+	return result
+}
+
+// LintChapter проверяет выбранные файлы до вызова UploadChapter
+func (a *App) LintChapter(filePaths []string) uploader.LintReport {
+	log.Printf("[App] LintChapter called. Files: %d", len(filePaths))
+
+	report := a.mangaService.LintChapter(a.ctx, filePaths)
+
+	log.Printf("[App] LintChapter finished. Issues: %d, errors: %d", len(report.Issues), report.Errors)
+	return report
+}
+
 func (a *App) ListFiles() ([]uploader.RemoteFile, error) {
 	if a.r2Uploader == nil {
 		return nil, fmt.Errorf("uploader service not available")
@@ -165,9 +245,15 @@ func (a *App) DeleteFiles(filenames []string) error {
 }
 
 func (a *App) CreateTelegraphPage(title string, imageUrls []string, titleID int) CreatePageResponse {
-	log.Printf("[App] CreateTelegraphPage called. Title: '%s', Images: %d, TitleID: %d", title, len(imageUrls), titleID)
+	return a.CreateTelegraphPageWithNotes(title, imageUrls, nil, titleID)
+}
+
+// CreateTelegraphPageWithNotes публикует главу с подписями под картинками и заметками между ними
+// (ключи notes — номера страниц с 1, 0 — перед первой картинкой)
+func (a *App) CreateTelegraphPageWithNotes(title string, imageUrls []string, notes service.ChapterNotes, titleID int) CreatePageResponse {
+	log.Printf("[App] CreateTelegraphPage called. Title: '%s', Images: %d, Notes: %d, TitleID: %d", title, len(imageUrls), len(notes), titleID)
 
-	res, err := a.pubService.CreatePage(title, imageUrls, titleID)
+	res, err := a.pubService.CreatePageWithNotes(a.ctx, title, imageUrls, notes, titleID)
 	
 	if err != nil {
 		log.Printf("[App] Failed to create page: %v", err)
@@ -178,31 +264,76 @@ func (a *App) CreateTelegraphPage(title string, imageUrls []string, titleID int)
 	}
 
 	log.Printf("[App] Page created successfully: %s", res.URL)
+	if len(res.Parts) > 1 {
+		log.Printf("[App] Chapter was split into %d parts: %v", len(res.Parts), res.Parts)
+	}
+	for _, w := range res.Warnings {
+		log.Printf("[App] CreateTelegraphPage warning: %s", w)
+	}
 	return CreatePageResponse{
 		Success:   true,
 		Url:       res.URL,
 		HistoryID: res.HistoryID,
+		Parts:     res.Parts,
+		Warnings:  res.Warnings,
 	}
 }
 
-func (a *App) EditTelegraphPage(path string, title string, imageUrls []string, token string) string {
-	log.Printf("[App] EditTelegraphPage called. Path: '%s', Title: '%s', Images: %d", path, title, len(imageUrls))
+// LoadChapterNotes читает notes.json или notes.txt из папки главы (по пути любого её файла).
+// Ключи — имена файлов или номера страниц, без файла заметок возвращается пустой результат.
+func (a *App) LoadChapterNotes(filePaths []string) (map[string]service.ImageNote, error) {
+	if len(filePaths) == 0 {
+		return nil, nil
+	}
+	notes, err := service.LoadChapterNotes(filepath.Dir(filePaths[0]))
+	if err != nil {
+		log.Printf("[App] Failed to load chapter notes: %v", err)
+		return nil, err
+	}
+	if len(notes) > 0 {
+		log.Printf("[App] Loaded %d chapter notes", len(notes))
+	}
+	return notes, nil
+}
 
-	url := a.pubService.EditPage(path, title, imageUrls, token)
+// CreateTextPage публикует текстовую страницу из Markdown или HTML (format: "markdown" | "html")
+func (a *App) CreateTextPage(title string, format string, source string) CreatePageResponse {
+	log.Printf("[App] CreateTextPage called. Title: '%s', Format: %s, Length: %d", title, format, len(source))
 
-	if len(url) > 4 && url[:4] == "http" {
-		log.Printf("[App] Page edited successfully: %s", url)
-	} else {
-		log.Printf("[App] Failed to edit page. Result URL/Error: %s", url)
+	res, err := a.pubService.CreateTextPage(a.ctx, title, format, source)
+	if err != nil {
+		log.Printf("[App] Failed to create text page: %v", err)
+		return CreatePageResponse{
+			Success: false,
+			Error:   err.Error(),
+		}
 	}
 
-	return url
+	log.Printf("[App] Text page created successfully: %s", res.URL)
+	return CreatePageResponse{
+		Success:   true,
+		Url:       res.URL,
+		HistoryID: res.HistoryID,
+	}
+}
+
+func (a *App) EditTelegraphPage(path string, title string, imageUrls []string, token string) (string, error) {
+	log.Printf("[App] EditTelegraphPage called. Path: '%s', Title: '%s', Images: %d", path, title, len(imageUrls))
+
+	url, err := a.pubService.EditPage(a.ctx, path, title, imageUrls, token)
+	if err != nil {
+		log.Printf("[App] Failed to edit page: %v", err)
+		return "", err
+	}
+
+	log.Printf("[App] Page edited successfully: %s", url)
+	return url, nil
 }
 
 func (a *App) GetTelegraphPage(pageUrl string) (ChapterResponse, error) {
 	log.Printf("[App] GetTelegraphPage called. URL: %s", pageUrl)
 
-	title, images, err := a.pubService.GetPage(pageUrl)
+	title, images, err := a.pubService.GetPage(a.ctx, pageUrl)
 	if err != nil {
 		log.Printf("[App] Error getting page: %v", err)
 		return ChapterResponse{}, err
@@ -218,6 +349,57 @@ func (a *App) GetTelegraphPage(pageUrl string) (ChapterResponse, error) {
 	}, nil
 }
 
+// DownloadTelegraphChapter скачивает картинки страницы Telegraph в выбранную папку (или в CBZ)
+func (a *App) DownloadTelegraphChapter(pageUrl string, asCBZ bool) (service.DownloadResult, error) {
+	log.Printf("[App] DownloadTelegraphChapter called. URL: %s, CBZ: %v", pageUrl, asCBZ)
+	return a.downloadChapter(func(dir string, progress func(int, int)) (service.DownloadResult, error) {
+		return a.pubService.DownloadPage(a.ctx, pageUrl, dir, asCBZ, progress)
+	})
+}
+
+// DownloadHistoryChapter скачивает главу из истории (все части по порядку) в выбранную папку (или в CBZ)
+func (a *App) DownloadHistoryChapter(historyID uint, asCBZ bool) (service.DownloadResult, error) {
+	log.Printf("[App] DownloadHistoryChapter called (id: %d, CBZ: %v)", historyID, asCBZ)
+	return a.downloadChapter(func(dir string, progress func(int, int)) (service.DownloadResult, error) {
+		return a.pubService.DownloadHistory(a.ctx, historyID, dir, asCBZ, progress)
+	})
+}
+
+// downloadChapter спрашивает папку и запускает скачивание; отмена диалога — пустой результат
+func (a *App) downloadChapter(download func(dir string, progress func(int, int)) (service.DownloadResult, error)) (service.DownloadResult, error) {
+	dir, err := a.dialogs.OpenDirectory(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: "Куда сохранить главу",
+	})
+	if err != nil {
+		log.Printf("[App] OpenDirectoryDialog error: %v", err)
+		return service.DownloadResult{}, err
+	}
+	if dir == "" {
+		log.Println("[App] Download canceled by user")
+		return service.DownloadResult{}, nil
+	}
+
+	res, err := download(dir, func(done, total int) {
+		a.emit("download_progress", map[string]int{
+			"current": done,
+			"total":   total,
+		})
+	})
+	if err != nil {
+		log.Printf("[App] Download failed: %v", err)
+		return res, err
+	}
+	for _, f := range res.Failed {
+		log.Printf("[App] Download warning: %s", f)
+	}
+	if res.Archive != "" {
+		log.Printf("[App] Chapter saved to %s", res.Archive)
+	} else {
+		log.Printf("[App] Saved %d images to %s", len(res.Files), res.Dir)
+	}
+	return res, nil
+}
+
 // Диалог выбора папки
 func (a *App) OpenFolderDialog() (ChapterResponse, error) {
 	log.Println("[App] OpenFolderDialog called")
@@ -236,15 +418,6 @@ func (a *App) OpenFolderDialog() (ChapterResponse, error) {
 
 	log.Printf("[App] Folder selected: %s", selection)
 
-	images, err := getImagesInDir(selection)
-	if err != nil {
-		log.Printf("[App] Error reading directory: %v", err)
-		return ChapterResponse{}, err
-	}
-
-	title := filepath.Base(selection)
-	log.Printf("[App] Found %d images in folder '%s'", len(images), title)
-
 	var detectedID *uint
 	// Используем репозиторий вместо прямого вызова БД
 	dbTitle, err := a.titleRepo.FindByPath(selection)
@@ -253,6 +426,16 @@ func (a *App) OpenFolderDialog() (ChapterResponse, error) {
 		log.Printf("[App] Detected title: %s (ID: %d)", dbTitle.Name, dbTitle.ID)
 	}
 
+	order := a.resolveOrdering(dbTitle)
+	images, err := getImagesInDir(selection, order)
+	if err != nil {
+		log.Printf("[App] Error reading directory: %v", err)
+		return ChapterResponse{}, err
+	}
+
+	title := filepath.Base(selection)
+	log.Printf("[App] Found %d images in folder '%s' (order: %s)", len(images), title, order.Mode)
+
 	return ChapterResponse{
 		Path:            selection,
 		Title:           title,
@@ -271,7 +454,7 @@ func (a *App) OpenFilesDialog() ([]string, error) {
 		Filters: []wailsRuntime.FileFilter{
 			{
 				DisplayName: "Images",
-				Pattern:     "*.jpg;*.jpeg;*.png;*.webp",
+				Pattern:     imageformat.DialogPattern(),
 			},
 		},
 	})
@@ -283,14 +466,79 @@ func (a *App) OpenFilesDialog() ([]string, error) {
 
 	if len(selection) == 0 {
 		log.Println("[App] OpenFilesDialog canceled or no files selected")
-	} else {
-		log.Printf("[App] Selected %d files", len(selection))
+		return selection, nil
+	}
+
+	log.Printf("[App] Selected %d files", len(selection))
+	dbTitle, _ := a.titleRepo.FindByPath(filepath.Dir(selection[0]))
+	if err := ordering.Sort(selection, a.resolveOrdering(dbTitle)); err != nil {
+		log.Printf("[App] Error sorting files: %v", err)
+		return nil, err
 	}
 
 	return selection, nil
 }
 
-func getImagesInDir(dirPath string) ([]string, error) {
+// GetSupportedExtensions возвращает расширения, которые приложение умеет декодировать
+func (a *App) GetSupportedExtensions() []string {
+	return imageformat.Extensions()
+}
+
+// SortFiles упорядочивает пути (drag-and-drop и т.п.) так же, как OpenFolderDialog.
+// Если titleID == 0, тайтл определяется по папке первого файла.
+func (a *App) SortFiles(paths []string, titleID uint) ([]string, error) {
+	log.Printf("[App] SortFiles called. Files: %d, TitleID: %d", len(paths), titleID)
+	if len(paths) == 0 {
+		return paths, nil
+	}
+
+	var dbTitle database.Title
+	if titleID > 0 {
+		dbTitle, _ = a.titleRepo.GetByID(titleID)
+	} else {
+		dbTitle, _ = a.titleRepo.FindByPath(filepath.Dir(paths[0]))
+	}
+
+	sorted := append([]string(nil), paths...)
+	if err := ordering.Sort(sorted, a.resolveOrdering(dbTitle)); err != nil {
+		log.Printf("[App] Error sorting files: %v", err)
+		return nil, err
+	}
+	return sorted, nil
+}
+
+// SaveTitleOrdering запоминает порядок страниц для тайтла
+func (a *App) SaveTitleOrdering(titleID uint, order ordering.Options) error {
+	log.Printf("[App] SaveTitleOrdering called. TitleID: %d, Order: %+v", titleID, order)
+	if err := order.Validate(); err != nil {
+		return err
+	}
+	return a.titleRepo.UpdateOrdering(titleID, order.Mode, order.Reverse, order.Pattern)
+}
+
+// SaveTitleSpreadMode запоминает обработку разворотов для тайтла (пустая строка — как в настройках)
+func (a *App) SaveTitleSpreadMode(titleID uint, mode string) error {
+	log.Printf("[App] SaveTitleSpreadMode called. TitleID: %d, Mode: %s", titleID, mode)
+	switch mode {
+	case "", uploader.SpreadKeep, uploader.SpreadSplitRTL, uploader.SpreadSplitLTR, uploader.SpreadRotate:
+	default:
+		return fmt.Errorf("unknown spread mode: %s", mode)
+	}
+	return a.titleRepo.UpdateSpreadMode(titleID, mode)
+}
+
+// resolveOrdering выбирает порядок: настройки тайтла, затем общие настройки, затем естественный
+func (a *App) resolveOrdering(t database.Title) ordering.Options {
+	if t.SortMode != "" {
+		return ordering.Options{Mode: t.SortMode, Reverse: t.SortReverse, Pattern: t.SortPattern}
+	}
+	if s, err := a.settingsRepo.Get(); err == nil && s.SortMode != "" {
+		return ordering.Options{Mode: s.SortMode, Reverse: s.SortReverse, Pattern: s.SortPattern}
+	}
+	return ordering.Default()
+}
+
+func getImagesInDir(dirPath string, order ordering.Options) ([]string, error) {
 	entries, err := os.ReadDir(dirPath)
 	if err != nil {
 		return nil, err
@@ -300,13 +548,13 @@ func getImagesInDir(dirPath string) ([]string, error) {
 		if entry.IsDir() {
 			continue
 		}
-		lower := strings.ToLower(entry.Name())
-		if strings.HasSuffix(lower, ".jpg") || strings.HasSuffix(lower, ".png") ||
-			strings.HasSuffix(lower, ".jpeg") || strings.HasSuffix(lower, ".webp") {
+		if imageformat.IsSupported(entry.Name()) {
 			images = append(images, filepath.Join(dirPath, entry.Name()))
 		}
 	}
-	sort.Strings(images)
+	if err := ordering.Sort(images, order); err != nil {
+		return nil, err
+	}
 	return images, nil
 }
 
@@ -328,12 +576,28 @@ func (a *App) GetSettings() FrontendSettings {
 		LastChannelID:    strconv.FormatInt(s.LastChannelID, 10),
 		LastChannelHash:  strconv.FormatInt(s.LastChannelHash, 10),
 		LastChannelTitle: s.LastChannelTitle,
+		SortMode:         s.SortMode,
+		SortReverse:      s.SortReverse,
+		SortPattern:      s.SortPattern,
+		AnimationMode:    s.AnimationMode,
+		SpreadMode:       s.SpreadMode,
+		ResizeMode:       s.ResizeMode,
+		ResizeHeight:     s.ResizeHeight,
+		MaxMegapixels:    s.MaxMegapixels,
+		ResizePercent:    s.ResizePercent,
+		StripHeight:      s.StripHeight,
+		ResizeFilter:     s.ResizeFilter,
 	}
 }
 
 // SaveSettings вызывается фронтендом при любом изменении
-func (a *App) SaveSettings(s FrontendSettings) {
+func (a *App) SaveSettings(s FrontendSettings) error {
 	log.Printf("[App] SaveSettings called: %+v", s)
+	order := ordering.Options{Mode: s.SortMode, Reverse: s.SortReverse, Pattern: s.SortPattern}
+	if err := order.Validate(); err != nil {
+		log.Printf("[App] Invalid page ordering: %v", err)
+		return err
+	}
 
 	cID, _ := strconv.ParseInt(s.LastChannelID, 10, 64)
 	cHash, _ := strconv.ParseInt(s.LastChannelHash, 10, 64)
@@ -345,13 +609,25 @@ func (a *App) SaveSettings(s FrontendSettings) {
 		LastChannelID:    cID,
 		LastChannelHash:  cHash,
 		LastChannelTitle: s.LastChannelTitle,
+		SortMode:         s.SortMode,
+		SortReverse:      s.SortReverse,
+		SortPattern:      s.SortPattern,
+		AnimationMode:    s.AnimationMode,
+		SpreadMode:       s.SpreadMode,
+		ResizeMode:       s.ResizeMode,
+		ResizeHeight:     s.ResizeHeight,
+		MaxMegapixels:    s.MaxMegapixels,
+		ResizePercent:    s.ResizePercent,
+		StripHeight:      s.StripHeight,
+		ResizeFilter:     s.ResizeFilter,
 	})
 	
 	if err != nil {
 		log.Printf("[App] Error saving settings: %v", err)
-	} else {
-		log.Println("[App] Settings saved")
+		return err
 	}
+	log.Println("[App] Settings saved")
+	return nil
 }
 
 func (a *App) GetHistory(limit int, offset int) []database.HistoryItem {
@@ -371,6 +647,209 @@ func (a *App) ClearHistory() {
 	log.Println("[App] History cleared")
 }
 
+// DeleteHistoryItem удаляет главу из истории (страница в Telegraph остаётся)
+// и пересобирает оглавление её тайтла
+func (a *App) DeleteHistoryItem(id uint) error {
+	log.Printf("[App] DeleteHistoryItem called (id: %d)", id)
+	warnings, err := a.pubService.DeleteHistory(a.ctx, id)
+	if err != nil {
+		log.Printf("[App] Error deleting history item: %v", err)
+		return err
+	}
+	for _, w := range warnings {
+		log.Printf("[App] Warning: %s", w)
+	}
+	return nil
+}
+
+// GetPageRevisions — ревизии страниц главы, новые первыми
+func (a *App) GetPageRevisions(historyID uint) []database.PageRevision {
+	revs, err := a.pubService.Revisions(historyID)
+	if err != nil {
+		log.Printf("[App] Error getting revisions: %v", err)
+		return []database.PageRevision{}
+	}
+	return revs
+}
+
+// DiffPageRevisions — какие картинки добавлены, удалены и переставлены между двумя ревизиями
+func (a *App) DiffPageRevisions(fromID uint, toID uint) (service.RevisionDiff, error) {
+	return a.pubService.DiffRevisions(fromID, toID)
+}
+
+// RollbackPageRevision возвращает страницу к ревизии и возвращает её адрес
+func (a *App) RollbackPageRevision(revisionID uint) (string, error) {
+	log.Printf("[App] RollbackPageRevision called (revision: %d)", revisionID)
+	url, err := a.pubService.RollbackRevision(a.ctx, revisionID)
+	if err != nil {
+		log.Printf("[App] Rollback failed: %v", err)
+		return "", err
+	}
+	log.Printf("[App] Page rolled back: %s", url)
+	return url, nil
+}
+
+// GetLatestViews — последние собранные просмотры глав (history_id -> просмотры)
+func (a *App) GetLatestViews() map[uint]int {
+	views, err := a.statsService.LatestViews()
+	if err != nil {
+		log.Printf("[App] Error getting views: %v", err)
+		return map[uint]int{}
+	}
+	return views
+}
+
+// GetPageViews — динамика просмотров главы за последние days дней (0 — за всё время)
+func (a *App) GetPageViews(historyID uint, days int) []database.ViewPoint {
+	points, err := a.statsService.PageViews(historyID, days)
+	if err != nil {
+		log.Printf("[App] Error getting page views: %v", err)
+		return []database.ViewPoint{}
+	}
+	return points
+}
+
+// GetTitleViews — суммарная динамика просмотров глав тайтла
+func (a *App) GetTitleViews(titleID uint, days int) []database.ViewPoint {
+	points, err := a.statsService.TitleViews(titleID, days)
+	if err != nil {
+		log.Printf("[App] Error getting title views: %v", err)
+		return []database.ViewPoint{}
+	}
+	return points
+}
+
+// CollectViewsNow запускает внеочередной сбор просмотров
+func (a *App) CollectViewsNow() (int, error) {
+	log.Println("[App] CollectViewsNow called")
+	return a.statsService.Collect(a.ctx)
+}
+
+// RehostTelegraphPage переносит сторонние картинки страницы (telegra.ph/file, imgur и т.п.) в наш бакет
+func (a *App) RehostTelegraphPage(pageUrl string, resizeSettings uploader.ResizeSettings) (service.RehostResult, error) {
+	log.Printf("[App] RehostTelegraphPage called. URL: %s", pageUrl)
+	if a.r2Uploader == nil {
+		return service.RehostResult{}, fmt.Errorf("uploader service not available")
+	}
+	res, err := a.pubService.RehostPage(a.ctx, pageUrl, a.r2Uploader, resizeSettings)
+	if err != nil {
+		log.Printf("[App] Rehost failed: %v", err)
+		return res, err
+	}
+	for _, f := range res.Failed {
+		log.Printf("[App] Rehost warning: %s: %s", f.URL, f.Error)
+	}
+	log.Printf("[App] Rehosted %d of %d external images", res.Rehosted, res.External)
+	return res, nil
+}
+
+// CheckImageLinks проверяет картинки на всех страницах истории и сохраняет битые и медленные ссылки
+func (a *App) CheckImageLinks() (service.LinkReport, error) {
+	log.Println("[App] CheckImageLinks called")
+	report, err := a.linkChecker.Check(a.ctx, func(done, total int) {
+		a.emit("linkcheck_progress", map[string]int{
+			"current": done,
+			"total":   total,
+		})
+	})
+	if err != nil {
+		log.Printf("[App] Link check failed: %v", err)
+		return report, err
+	}
+	for _, e := range report.Errors {
+		log.Printf("[App] Link check warning: %s", e)
+	}
+	log.Printf("[App] Checked %d images on %d pages: %d broken, %d slow", report.Images, report.Pages, report.Broken, report.Slow)
+	return report, nil
+}
+
+// GetLinkIssues — битые и медленные картинки по результатам последней проверки
+func (a *App) GetLinkIssues() []database.LinkIssue {
+	issues, err := a.linkChecker.Issues()
+	if err != nil {
+		log.Printf("[App] Error getting link issues: %v", err)
+		return []database.LinkIssue{}
+	}
+	return issues
+}
+
+// RepairImageLinks перезаливает битые картинки главы из исходных файлов и правит страницу
+func (a *App) RepairImageLinks(historyID uint, resizeSettings uploader.ResizeSettings) (service.RepairResult, error) {
+	log.Printf("[App] RepairImageLinks called (history: %d)", historyID)
+	res, err := a.linkChecker.Repair(a.ctx, historyID, resizeSettings)
+	if err != nil {
+		log.Printf("[App] Repair failed: %v", err)
+		return res, err
+	}
+	for _, f := range res.Failed {
+		log.Printf("[App] Repair warning: %s", f)
+	}
+	log.Printf("[App] Repaired %d images", res.Fixed)
+	return res, nil
+}
+
+// PreviewDomainMigration — пробный прогон переноса картинок на новый домен, страницы не меняются
+func (a *App) PreviewDomainMigration(oldPrefix string, newPrefix string) (service.MigrationReport, error) {
+	log.Printf("[App] PreviewDomainMigration called (%s -> %s)", oldPrefix, newPrefix)
+	return a.migrations.Preview(a.ctx, oldPrefix, newPrefix, func(done, total int) {
+		a.emit("migration_progress", map[string]int{
+			"current": done,
+			"total":   total,
+		})
+	})
+}
+
+// RunDomainMigration переписывает адреса картинок на всех страницах истории.
+// Прерванный перенос с теми же префиксами продолжается с места остановки.
+func (a *App) RunDomainMigration(oldPrefix string, newPrefix string) (service.MigrationReport, error) {
+	log.Printf("[App] RunDomainMigration called (%s -> %s)", oldPrefix, newPrefix)
+	report, err := a.migrations.Migrate(a.ctx, oldPrefix, newPrefix, func(done, total int) {
+		a.emit("migration_progress", map[string]int{
+			"current": done,
+			"total":   total,
+		})
+	})
+	if err != nil {
+		log.Printf("[App] Migration stopped after %d pages: %v", report.Pages, err)
+		return report, err
+	}
+	for _, e := range report.Errors {
+		log.Printf("[App] Migration warning: %s", e)
+	}
+	log.Printf("[App] Migration finished: %d images on %d pages", report.Images, report.Pages)
+	return report, nil
+}
+
+// GetDomainMigrations — история переносов (незавершённые можно продолжить)
+func (a *App) GetDomainMigrations() []database.DomainMigration {
+	ms, err := a.migrations.History()
+	if err != nil {
+		log.Printf("[App] Error getting migrations: %v", err)
+		return []database.DomainMigration{}
+	}
+	return ms
+}
+
+// ImportTelegraphPages добавляет в историю страницы, созданные известными аккаунтами вне приложения
+func (a *App) ImportTelegraphPages() (service.ImportResult, error) {
+	log.Println("[App] ImportTelegraphPages called")
+	res, err := a.pubService.ImportPages(a.ctx, func(done, total int) {
+		a.emit("import_progress", map[string]int{
+			"current": done,
+			"total":   total,
+		})
+	})
+	if err != nil {
+		log.Printf("[App] Import failed: %v", err)
+		return res, err
+	}
+	for _, e := range res.Errors {
+		log.Printf("[App] Import warning: %s", e)
+	}
+	log.Printf("[App] Imported %d pages (%d matched to titles, %d skipped)", res.Imported, res.Matched, res.Skipped)
+	return res, nil
+}
+
 func (a *App) TelegramLoginQR() string {
 	log.Println("[App] Starting Telegram QR login...")
 
@@ -378,16 +857,16 @@ func (a *App) TelegramLoginQR() string {
 		displayQR := func(qrImage []byte) {
 			base64Image := base64.StdEncoding.EncodeToString(qrImage)
 			log.Printf("[App] Emitting QR code, size: %d bytes", len(qrImage))
-			wailsRuntime.EventsEmit(a.ctx, "tg_qr_code", base64Image)
+			a.emit("tg_qr_code", base64Image)
 		}
 
 		err := a.telegram.LoginQR(a.ctx, displayQR, a.authHandler)
 		if err != nil {
 			log.Printf("[App] QR Login failed: %v", err)
-			wailsRuntime.EventsEmit(a.ctx, "tg_auth_error", err.Error())
+			a.emit("tg_auth_error", err.Error())
 		} else {
 			log.Println("[App] QR Login success!")
-			wailsRuntime.EventsEmit(a.ctx, "tg_auth_success", true)
+			a.emit("tg_auth_success", true)
 		}
 	}()
 
@@ -453,10 +932,96 @@ func (a *App) GetTitleByID(id uint) (database.Title, error) {
 	return a.titleRepo.GetByID(id)
 }
 
+// SetTitleIndex включает/выключает страницу-оглавление тайтла и сохраняет обложку и описание.
+// При включении оглавление сразу пересобирается; возвращает его адрес.
+func (a *App) SetTitleIndex(titleID uint, enabled bool, coverURL string, description string) (string, error) {
+	log.Printf("[App] SetTitleIndex called (title: %d, enabled: %v)", titleID, enabled)
+	if err := a.titleRepo.UpdateIndexSettings(titleID, enabled, coverURL, description); err != nil {
+		return "", err
+	}
+	if !enabled {
+		return "", nil
+	}
+	return a.RegenerateTitleIndex(titleID)
+}
+
+// RegenerateTitleIndex принудительно пересобирает оглавление тайтла
+func (a *App) RegenerateTitleIndex(titleID uint) (string, error) {
+	url, err := a.pubService.UpdateTitleIndex(a.ctx, titleID)
+	if err != nil {
+		log.Printf("[App] Error updating title index: %v", err)
+		return "", err
+	}
+	log.Printf("[App] Title index updated: %s", url)
+	return url, nil
+}
+
+// --- Telegraph Accounts ---
+
+func (a *App) GetTelegraphAccounts() []database.TelegraphAccount {
+	accounts, err := a.accountService.GetAll()
+	if err != nil {
+		log.Printf("[App] Error getting Telegraph accounts: %v", err)
+		return []database.TelegraphAccount{}
+	}
+	return accounts
+}
+
+func (a *App) CreateTelegraphAccount(shortName string, authorName string, authorURL string) (database.TelegraphAccount, error) {
+	log.Printf("[App] CreateTelegraphAccount called (%s)", shortName)
+	return a.accountService.Create(a.ctx, shortName, authorName, authorURL)
+}
+
+// ImportTelegraphAccount добавляет существующий аккаунт по токену
+func (a *App) ImportTelegraphAccount(token string) (database.TelegraphAccount, error) {
+	log.Println("[App] ImportTelegraphAccount called")
+	return a.accountService.Import(a.ctx, token)
+}
+
+// RefreshTelegraphAccount обновляет данные аккаунта (getAccountInfo)
+func (a *App) RefreshTelegraphAccount(id uint) (database.TelegraphAccount, error) {
+	return a.accountService.Refresh(a.ctx, id)
+}
+
+// EditTelegraphAccount меняет имя и автора (editAccountInfo)
+func (a *App) EditTelegraphAccount(id uint, shortName string, authorName string, authorURL string) (database.TelegraphAccount, error) {
+	log.Printf("[App] EditTelegraphAccount called (id: %d)", id)
+	return a.accountService.Edit(a.ctx, id, shortName, authorName, authorURL)
+}
+
+// RevokeTelegraphToken отзывает токен аккаунта и перепривязывает историю к новому
+func (a *App) RevokeTelegraphToken(id uint) (database.TelegraphAccount, error) {
+	log.Printf("[App] RevokeTelegraphToken called (id: %d)", id)
+	acc, rebound, err := a.accountService.Revoke(a.ctx, id)
+	if err != nil {
+		log.Printf("[App] Error revoking token: %v", err)
+		return acc, err
+	}
+	log.Printf("[App] Token revoked, %d history items rebound", rebound)
+	return acc, nil
+}
+
+func (a *App) SetDefaultTelegraphAccount(id uint) error {
+	return a.accountService.SetDefault(id)
+}
+
+func (a *App) DeleteTelegraphAccount(id uint) error {
+	return a.accountService.Delete(id)
+}
+
+// SetTitleAccount привязывает тайтл к аккаунту (0 — аккаунт по умолчанию)
+func (a *App) SetTitleAccount(titleID uint, accountID uint) error {
+	if accountID == 0 {
+		return a.titleRepo.SetAccount(titleID, nil)
+	}
+	return a.titleRepo.SetAccount(titleID, &accountID)
+}
+
 // --- Template Management ---
 
+// GetTemplates — шаблоны постов Telegram (шаблоны страниц — GetPageTemplates)
 func (a *App) GetTemplates() []database.Template {
-	t, _ := a.templateRepo.GetAll()
+	t, _ := a.templateRepo.GetByKind(database.TemplatePost)
 	return t
 }
 
@@ -472,6 +1037,69 @@ func (a *App) DeleteTemplate(id uint) error {
 	return a.templateRepo.Delete(id)
 }
 
+// GetPageTemplates — шаблоны шапки и подвала страниц Telegraph
+func (a *App) GetPageTemplates() []database.Template {
+	t, _ := a.templateRepo.GetByKind(database.TemplatePage)
+	return t
+}
+
+// CreatePageTemplate создаёт шаблон блока страницы в Markdown или HTML (format: "markdown" | "html")
+func (a *App) CreatePageTemplate(name, format, content string) error {
+	if _, err := telegraph.ConvertText(format, content); err != nil {
+		return err
+	}
+	return a.templateRepo.Add(&database.Template{Name: name, Content: content, Kind: database.TemplatePage, Format: format})
+}
+
+// UpdatePageTemplate меняет название, формат и текст шаблона страницы
+func (a *App) UpdatePageTemplate(id uint, name, format, content string) error {
+	log.Printf("[App] UpdatePageTemplate called. ID: %d, Format: %s", id, format)
+	tmpl, err := a.pageTemplate(id)
+	if err != nil {
+		return err
+	}
+	if _, err := telegraph.ConvertText(format, content); err != nil {
+		return err
+	}
+	tmpl.Name, tmpl.Format, tmpl.Content = name, format, content
+	return a.templateRepo.Update(tmpl)
+}
+
+// SetTitlePageTemplates задаёт шапку и подвал глав тайтла (0 — без блока)
+func (a *App) SetTitlePageTemplates(titleID uint, headerID uint, footerID uint) error {
+	log.Printf("[App] SetTitlePageTemplates called. TitleID: %d, Header: %d, Footer: %d", titleID, headerID, footerID)
+	optional := func(id uint) (*uint, error) {
+		if id == 0 {
+			return nil, nil
+		}
+		if _, err := a.pageTemplate(id); err != nil {
+			return nil, err
+		}
+		return &id, nil
+	}
+	header, err := optional(headerID)
+	if err != nil {
+		return err
+	}
+	footer, err := optional(footerID)
+	if err != nil {
+		return err
+	}
+	return a.titleRepo.SetPageTemplates(titleID, header, footer)
+}
+
+// pageTemplate загружает шаблон и проверяет, что это шаблон страницы, а не поста
+func (a *App) pageTemplate(id uint) (database.Template, error) {
+	tmpl, err := a.templateRepo.GetByID(id)
+	if err != nil {
+		return tmpl, err
+	}
+	if tmpl.Kind != database.TemplatePage {
+		return tmpl, fmt.Errorf("шаблон %d — не шаблон страницы", id)
+	}
+	return tmpl, nil
+}
+
 // --- Telegram Feature ---
 
 func (a *App) SearchChannels(query string) ([]TelegramChannel, error) {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"image"
 	"image/png"
@@ -10,11 +11,13 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"telegraph_uploader_v2/internal/config"
 	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/ordering"
 	"telegraph_uploader_v2/internal/repository"
 	"telegraph_uploader_v2/internal/service"
 	"telegraph_uploader_v2/internal/telegram"
@@ -43,6 +46,15 @@ func (m *MockDialogProvider) OpenMultipleFiles(ctx context.Context, options wail
 	return m.FileSelection, m.Err
 }
 
+// MockEventEmitter records emitted events
+type MockEventEmitter struct {
+	Events []string
+}
+
+func (m *MockEventEmitter) Emit(ctx context.Context, name string, data ...interface{}) {
+	m.Events = append(m.Events, name)
+}
+
 func setupTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
 	if err != nil {
@@ -50,7 +62,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	}
 
 	// Migrate
-	err = db.AutoMigrate(&database.Settings{}, &database.HistoryEntry{}, &database.Title{}, &database.TitleFolder{}, &database.TitleVariable{}, &database.Template{})
+	err = db.AutoMigrate(&database.Settings{}, &database.HistoryEntry{}, &database.Title{}, &database.TitleFolder{}, &database.TitleVariable{}, &database.Template{}, &database.HistoryPart{}, &database.TelegraphAccount{}, &database.ViewSnapshot{}, &database.PageRevision{}, &database.LinkIssue{}, &database.DomainMigration{})
 	if err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
@@ -80,6 +92,10 @@ func setupTestApp(t *testing.T) (*App, *httptest.Server, *httptest.Server) {
 			w.Write([]byte(`{"ok": true, "result": {"url": "http://telegra.ph/edited"}}`))
 			return
 		}
+		if r.URL.Path == "/getPage/path" {
+			w.Write([]byte(`{"ok": true, "result": {"title": "Title", "content": [{"tag": "img", "attrs": {"src": "http://old.jpg"}}]}}`))
+			return
+		}
 		if r.URL.Path == "/getPage/slug" {
 			w.Write([]byte(`{"ok": true, "result": {"title": "T", "content": []}}`))
 			return
@@ -125,7 +141,7 @@ func setupTestApp(t *testing.T) (*App, *httptest.Server, *httptest.Server) {
 		Creds:  credentials.NewStaticV4("key", "secret", ""),
 		Secure: false,
 	})
-	upl := uploader.NewWithClient(minioClient, cfg)
+	upl := uploader.NewWithClient(minioClient, cfg, nil)
 	
 	// Services
 	mangaService := service.NewMangaService(upl)
@@ -154,7 +170,7 @@ func setupTestApp(t *testing.T) (*App, *httptest.Server, *httptest.Server) {
 	// So passing nil is fine for now.
 	var tgApp *telegram.Client // nil
 
-	pubService := service.NewPublicationService(tgClient, tgApp, historyRepo, titleRepo)
+	pubService := service.NewPublicationService(tgClient, tgApp, historyRepo, titleRepo, nil, nil, nil)
 
 	pwdChan := make(chan string)
 	app := &App{
@@ -167,6 +183,7 @@ func setupTestApp(t *testing.T) (*App, *httptest.Server, *httptest.Server) {
 		titleRepo:        titleRepo,
 		templateRepo:     templateRepo,
 		dialogs:          &MockDialogProvider{},
+		events:           &MockEventEmitter{},
 		authPasswordChan: pwdChan,
 		authHandler:      &WailsAuthHandler{passwordChan: pwdChan},
 	}
@@ -236,6 +253,23 @@ func TestApp_OpenFolderDialog_Errors(t *testing.T) {
 	}
 }
 
+func TestApp_DownloadChapter_Dialog(t *testing.T) {
+	app, ts1, ts2 := setupTestApp(t)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	app.dialogs = &MockDialogProvider{DirSelection: ""} // User canceled
+	res, err := app.DownloadTelegraphChapter("http://telegra.ph/slug", false)
+	if err != nil || res.Dir != "" {
+		t.Errorf("expected empty result on cancel, got %+v %v", res, err)
+	}
+
+	app.dialogs = &MockDialogProvider{Err: fmt.Errorf("dialog error")}
+	if _, err := app.DownloadHistoryChapter(1, true); err == nil {
+		t.Error("expected error from dialog")
+	}
+}
+
 func TestApp_OpenFilesDialog_Errors(t *testing.T) {
 	app, ts1, ts2 := setupTestApp(t)
 	defer ts1.Close()
@@ -304,7 +338,7 @@ func TestApp_UploadChapter_Error(t *testing.T) {
 		Creds:  credentials.NewStaticV4("key", "secret", ""),
 		Secure: false,
 	})
-	upl := uploader.NewWithClient(minioClient, cfg)
+	upl := uploader.NewWithClient(minioClient, cfg, nil)
 	
 	// Manually wire app
 	ms := service.NewMangaService(upl)
@@ -352,7 +386,7 @@ func TestApp_CreateTelegraphPage(t *testing.T) {
 	tgClient := telegraph.New(cfg)
 	tgClient.BaseURL = tsFail.URL
 	
-	app.pubService = service.NewPublicationService(tgClient, nil, app.historyRepo, app.titleRepo)
+	app.pubService = service.NewPublicationService(tgClient, nil, app.historyRepo, app.titleRepo, nil, nil, nil)
 
 	resp = app.CreateTelegraphPage("Title", nil, 0)
 	// It should log failure and return error string
@@ -361,14 +395,32 @@ func TestApp_CreateTelegraphPage(t *testing.T) {
 	}
 }
 
+func TestApp_LoadChapterNotes(t *testing.T) {
+	app, ts1, ts2 := setupTestApp(t)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	dir := t.TempDir()
+	if notes, err := app.LoadChapterNotes([]string{filepath.Join(dir, "001.jpg")}); err != nil || len(notes) != 0 {
+		t.Fatalf("expected no notes, got %v %v", notes, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("[001.jpg]\nподпись: Обложка\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := app.LoadChapterNotes([]string{filepath.Join(dir, "001.jpg")})
+	if err != nil || notes["001.jpg"].Caption != "Обложка" {
+		t.Errorf("unexpected notes: %+v %v", notes, err)
+	}
+}
+
 func TestApp_EditTelegraphPage(t *testing.T) {
 	app, ts1, ts2 := setupTestApp(t)
 	defer ts1.Close()
 	defer ts2.Close()
 
-	url := app.EditTelegraphPage("path", "Title", []string{"http://img.jpg"}, "token")
-	if url != "http://telegra.ph/edited" {
-		t.Errorf("expected url, got %s", url)
+	url, err := app.EditTelegraphPage("path", "Title", []string{"http://img.jpg"}, "token")
+	if err != nil || url != "http://telegra.ph/edited" {
+		t.Errorf("expected url, got %s (%v)", url, err)
 	}
 
 	// Test failure
@@ -380,11 +432,11 @@ func TestApp_EditTelegraphPage(t *testing.T) {
 	cfg := &config.Config{TelegraphToken: "t"}
 	tgClient := telegraph.New(cfg)
 	tgClient.BaseURL = tsFail.URL
-	app.pubService = service.NewPublicationService(tgClient, nil, app.historyRepo, app.titleRepo)
+	app.pubService = service.NewPublicationService(tgClient, nil, app.historyRepo, app.titleRepo, nil, nil, nil)
 
-	url = app.EditTelegraphPage("path", "Title", nil, "")
-	if url[:4] == "http" {
-		t.Error("expected error string")
+	url, err = app.EditTelegraphPage("path", "Title", nil, "")
+	if err == nil || url != "" {
+		t.Errorf("expected error, got %q", url)
 	}
 }
 
@@ -410,7 +462,7 @@ func TestApp_GetTelegraphPage(t *testing.T) {
 	cfg := &config.Config{TelegraphToken: "t"}
 	tgClient := telegraph.New(cfg)
 	tgClient.BaseURL = tsFail.URL
-	app.pubService = service.NewPublicationService(tgClient, nil, app.historyRepo, app.titleRepo)
+	app.pubService = service.NewPublicationService(tgClient, nil, app.historyRepo, app.titleRepo, nil, nil, nil)
 
 	_, err = app.GetTelegraphPage("http://t.ph/bad")
 	if err == nil {
@@ -449,7 +501,9 @@ func TestApp_Settings(t *testing.T) {
 		LastChannelTitle: "C",
 	}
 
-	app.SaveSettings(newSettings)
+	if err := app.SaveSettings(newSettings); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
 	s = app.GetSettings()
 	if s.ResizeTo != 2000 {
 		t.Errorf("expected 2000, got %d", s.ResizeTo)
@@ -457,6 +511,17 @@ func TestApp_Settings(t *testing.T) {
 	if s.LastChannelID != "1" {
 		t.Errorf("expected 1, got %s", s.LastChannelID)
 	}
+
+	// Неверное выражение сортировки не сохраняется
+	newSettings.ResizeTo = 1200
+	newSettings.SortMode = "regex"
+	newSettings.SortPattern = "p(\\d+"
+	if err := app.SaveSettings(newSettings); err == nil {
+		t.Error("expected error for an invalid sort pattern")
+	}
+	if s = app.GetSettings(); s.ResizeTo != 2000 || s.SortMode != "" {
+		t.Errorf("invalid settings must not be saved, got %+v", s)
+	}
 }
 
 func TestApp_History(t *testing.T) {
@@ -492,7 +557,7 @@ func TestGetImagesInDir(t *testing.T) {
 	f3, _ := os.Create(filepath.Join(tmpDir, "ignore.txt"))
 	f3.Close()
 
-	imgs, err := getImagesInDir(tmpDir)
+	imgs, err := getImagesInDir(tmpDir, ordering.Default())
 	if err != nil {
 		t.Fatalf("getImagesInDir failed: %v", err)
 	}
@@ -501,6 +566,50 @@ func TestGetImagesInDir(t *testing.T) {
 	}
 }
 
+func TestGetImagesInDir_NaturalOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"10.jpg", "2.jpg", "1.jpg"} {
+		f, _ := os.Create(filepath.Join(tmpDir, name))
+		f.Close()
+	}
+
+	imgs, err := getImagesInDir(tmpDir, ordering.Default())
+	if err != nil {
+		t.Fatalf("getImagesInDir failed: %v", err)
+	}
+	if filepath.Base(imgs[0]) != "1.jpg" || filepath.Base(imgs[2]) != "10.jpg" {
+		t.Errorf("expected natural order, got %v", imgs)
+	}
+}
+
+func TestApp_SortFiles(t *testing.T) {
+	app, ts1, ts2 := setupTestApp(t)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	if err := app.CreateTitle("Sorted", "C:/Manga/Sorted"); err != nil {
+		t.Fatal(err)
+	}
+	titles := app.GetTitles()
+	id := titles[len(titles)-1].ID
+	defer app.DeleteTitle(id)
+
+	if err := app.SaveTitleOrdering(id, ordering.Options{Mode: ordering.ModeNatural, Reverse: true}); err != nil {
+		t.Fatalf("SaveTitleOrdering failed: %v", err)
+	}
+	if err := app.SaveTitleOrdering(id, ordering.Options{Mode: ordering.ModeRegex}); err == nil {
+		t.Error("expected validation error for regex without pattern")
+	}
+
+	sorted, err := app.SortFiles([]string{"/x/1.jpg", "/x/10.jpg", "/x/2.jpg"}, id)
+	if err != nil {
+		t.Fatalf("SortFiles failed: %v", err)
+	}
+	if sorted[0] != "/x/10.jpg" || sorted[2] != "/x/1.jpg" {
+		t.Errorf("expected reversed natural order from title settings, got %v", sorted)
+	}
+}
+
 func TestApp_PublishPost_Errors(t *testing.T) {
 	app, ts1, ts2 := setupTestApp(t)
 	defer ts1.Close()
@@ -603,4 +712,193 @@ func TestApp_TemplateCRUD(t *testing.T) {
 	if err != nil {
 		t.Errorf("DeleteTemplate failed: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestApp_PageTemplates(t *testing.T) {
+	app, ts1, ts2 := setupTestApp(t)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	if err := app.CreateTemplate("Пост", "{{Title}}"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.CreatePageTemplate("Подвал", "markdown", "[Канал](https://t.me/chan)"); err != nil {
+		t.Fatalf("CreatePageTemplate failed: %v", err)
+	}
+	if err := app.CreatePageTemplate("Плохой", "rst", "x"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+
+	// Шаблоны постов и страниц не смешиваются
+	if posts := app.GetTemplates(); len(posts) != 1 || posts[0].Name != "Пост" {
+		t.Errorf("unexpected post templates: %+v", posts)
+	}
+	pages := app.GetPageTemplates()
+	if len(pages) != 1 || pages[0].Kind != database.TemplatePage || pages[0].Format != "markdown" {
+		t.Fatalf("unexpected page templates: %+v", pages)
+	}
+
+	if err := app.CreateTitle("Манга", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	title := app.GetTitles()[0]
+	if err := app.SetTitlePageTemplates(title.ID, 0, pages[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	title, _ = app.GetTitleByID(title.ID)
+	if title.HeaderTemplateID != nil || title.FooterTemplateID == nil || *title.FooterTemplateID != pages[0].ID {
+		t.Errorf("unexpected title templates: %v %v", title.HeaderTemplateID, title.FooterTemplateID)
+	}
+
+	// Удалённый шаблон отвязывается от тайтла
+	if err := app.DeleteTemplate(pages[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	title, _ = app.GetTitleByID(title.ID)
+	if title.FooterTemplateID != nil {
+		t.Errorf("deleted template must be unlinked, got %v", *title.FooterTemplateID)
+	}
+}
+// Проверяет связку NewApp: шаблоны страниц тайтла доходят до публикации
+func TestApp_CreateTelegraphPage_WithTemplates(t *testing.T) {
+	var content []telegraph.Node
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/createPage" {
+			if err := json.Unmarshal([]byte(r.FormValue("content")), &content); err != nil {
+				t.Errorf("bad content: %v", err)
+			}
+			w.Write([]byte(`{"ok": true, "result": {"url": "https://telegra.ph/page"}}`))
+			return
+		}
+		w.Write([]byte(`{"ok": false, "error": "unknown"}`))
+	}))
+	defer ts.Close()
+
+	cfg := &config.Config{TelegraphToken: "test_token"}
+	tgClient := telegraph.New(cfg)
+	tgClient.BaseURL = ts.URL
+
+	app := newApp(cfg, setupTestDB(t), tgClient, nil)
+	app.ctx = context.Background()
+	app.dialogs = &MockDialogProvider{}
+	app.events = &MockEventEmitter{}
+
+	// База общая для тестов пакета, поэтому имена уникальные
+	if err := app.CreateTitle("Манга с шаблонами", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.CreatePageTemplate("Шапка главы", "markdown", "{{Name}}, глава {{Chapter}}"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.CreatePageTemplate("Подвал главы", "markdown", "[Канал](https://t.me/chan)"); err != nil {
+		t.Fatal(err)
+	}
+	var title database.Title
+	for _, tt := range app.GetTitles() {
+		if tt.Name == "Манга с шаблонами" {
+			title = tt
+		}
+	}
+	var headerID, footerID uint
+	for _, tmpl := range app.GetPageTemplates() {
+		switch tmpl.Name {
+		case "Шапка главы":
+			headerID = tmpl.ID
+		case "Подвал главы":
+			footerID = tmpl.ID
+		}
+	}
+	if err := app.SetTitlePageTemplates(title.ID, headerID, footerID); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := app.CreateTelegraphPage("Глава 1", []string{"http://img.jpg"}, int(title.ID))
+	if !resp.Success {
+		t.Fatalf("expected success, got error %s", resp.Error)
+	}
+
+	data, _ := json.Marshal(content)
+	got := string(data)
+	if !strings.Contains(got, "Манга с шаблонами, глава 1") {
+		t.Errorf("header template not rendered: %s", got)
+	}
+	if !strings.Contains(got, "https://t.me/chan") {
+		t.Errorf("footer template not rendered: %s", got)
+	}
+	if first := content[0]; first.Tag == "img" {
+		t.Errorf("header must precede images: %s", got)
+	}
+
+	// Шаблон поста нельзя поставить шапкой страницы
+	if err := app.CreateTemplate("Пост для шаблонов страниц", "{{Title}}"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range app.GetTemplates() {
+		if tmpl.Name == "Пост для шаблонов страниц" {
+			if err := app.SetTitlePageTemplates(title.ID, tmpl.ID, 0); err == nil {
+				t.Error("expected error for a post template used as page header")
+			}
+			if err := app.UpdatePageTemplate(tmpl.ID, "x", "markdown", "x"); err == nil {
+				t.Error("expected error when updating a post template as a page template")
+			}
+		}
+	}
+
+	// Правка шаблона страницы попадает в следующие главы
+	if err := app.UpdatePageTemplate(headerID, "Шапка главы", "html", "<p>Новая шапка {{Chapter}}</p>"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.UpdatePageTemplate(headerID, "Шапка главы", "rst", "x"); err == nil {
+		t.Error("expected error for an unsupported format")
+	}
+	if resp := app.CreateTelegraphPage("Глава 2", []string{"http://img.jpg"}, int(title.ID)); !resp.Success {
+		t.Fatalf("expected success, got error %s", resp.Error)
+	}
+	if data, _ := json.Marshal(content[0]); !strings.Contains(string(data), "Новая шапка 2") {
+		t.Errorf("updated header not rendered: %s", data)
+	}
+}
+
+func TestApp_GetSupportedExtensions(t *testing.T) {
+	app, ts1, ts2 := setupTestApp(t)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	exts := app.GetSupportedExtensions()
+	for _, want := range []string{".jpg", ".png", ".webp", ".gif", ".bmp", ".tif", ".tiff"} {
+		found := false
+		for _, e := range exts {
+			if e == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing %s in %v", want, exts)
+		}
+	}
+}
+
+func TestApp_SaveTitleSpreadMode(t *testing.T) {
+	app, ts1, ts2 := setupTestApp(t)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	if err := app.CreateTitle("Spreads", "C:/Manga/Spreads"); err != nil {
+		t.Fatal(err)
+	}
+	titles := app.GetTitles()
+	id := titles[len(titles)-1].ID
+	defer app.DeleteTitle(id)
+
+	if err := app.SaveTitleSpreadMode(id, uploader.SpreadSplitRTL); err != nil {
+		t.Fatalf("SaveTitleSpreadMode failed: %v", err)
+	}
+	if err := app.SaveTitleSpreadMode(id, "diagonal"); err == nil {
+		t.Error("expected error for unknown spread mode")
+	}
+	title, err := app.titleRepo.GetByID(id)
+	if err != nil || title.SpreadMode != uploader.SpreadSplitRTL {
+		t.Errorf("expected split_rtl, got %q (%v)", title.SpreadMode, err)
+	}
+}
@@ -11,10 +11,12 @@ type ChapterResponse struct {
 }
 
 type CreatePageResponse struct {
-	Success   bool   `json:"success"`
-	Url       string `json:"url"`
-	HistoryID uint   `json:"history_id"`
-	Error     string `json:"error"`
+	Success   bool     `json:"success"`
+	Url       string   `json:"url"`
+	HistoryID uint     `json:"history_id"`
+	Error     string   `json:"error"`
+	Parts     []string `json:"parts"`
+	Warnings  []string `json:"warnings"`
 }
 
 type TelegramChannel struct {
@@ -24,11 +26,22 @@ type TelegramChannel struct {
 }
 
 type FrontendSettings struct {
-	Resize           bool   `json:"resize"`
-	ResizeTo         int    `json:"resize_to"`
-	WebpQuality      int    `json:"webp_quality"`
-	MockR2           bool   `json:"mock_r2"`
-	LastChannelID    string `json:"last_channel_id"`
-	LastChannelHash  string `json:"last_channel_hash"`
-	LastChannelTitle string `json:"last_channel_title"`
+	Resize           bool    `json:"resize"`
+	ResizeTo         int     `json:"resize_to"`
+	WebpQuality      int     `json:"webp_quality"`
+	MockR2           bool    `json:"mock_r2"`
+	LastChannelID    string  `json:"last_channel_id"`
+	LastChannelHash  string  `json:"last_channel_hash"`
+	LastChannelTitle string  `json:"last_channel_title"`
+	SortMode         string  `json:"sort_mode"`
+	SortReverse      bool    `json:"sort_reverse"`
+	SortPattern      string  `json:"sort_pattern"`
+	AnimationMode    string  `json:"animation_mode"`
+	SpreadMode       string  `json:"spread_mode"`
+	ResizeMode       string  `json:"resize_mode"`
+	ResizeHeight     int     `json:"resize_height"`
+	MaxMegapixels    float64 `json:"max_megapixels"`
+	ResizePercent    int     `json:"resize_percent"`
+	StripHeight      int     `json:"strip_height"`
+	ResizeFilter     string  `json:"resize_filter"`
 }
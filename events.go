@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// EventEmitter interface mocks Wails events
+type EventEmitter interface {
+	Emit(ctx context.Context, name string, data ...interface{})
+}
+
+// WailsEventEmitter implements EventEmitter using real Wails runtime
+type WailsEventEmitter struct{}
+
+func (w *WailsEventEmitter) Emit(ctx context.Context, name string, data ...interface{}) {
+	wailsRuntime.EventsEmit(ctx, name, data...)
+}
+
+// emit отправляет событие во фронтенд, если эмиттер настроен
+func (a *App) emit(name string, data ...interface{}) {
+	if a.events == nil {
+		return
+	}
+	a.events.Emit(a.ctx, name, data...)
+}
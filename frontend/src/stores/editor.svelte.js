@@ -2,9 +2,11 @@ import {
     OpenFilesDialog,
     OpenFolderDialog,
     UploadChapter,
-    CreateTelegraphPage,
+    CreateTelegraphPageWithNotes,
     EditTelegraphPage,
-    GetTelegraphPage
+    GetTelegraphPage,
+    GetSupportedExtensions,
+    LoadChapterNotes
 } from "../../wailsjs/go/main/App";
 import { EventsOn, EventsOff } from "../../wailsjs/runtime/runtime";
 
@@ -14,11 +16,27 @@ import { navigationStore } from "./navigation.svelte.js";
 
 class EditorStore {
     images = $state([]);
+    // Расширения, для которых на бэкенде есть декодер (AVIF/JXL — только при установленных утилитах)
+    supportedExtensions = $state([".jpg", ".jpeg", ".png", ".webp", ".gif"]);
+
+    constructor() {
+        GetSupportedExtensions().then((exts) => {
+            if (exts && exts.length > 0) this.supportedExtensions = exts;
+        });
+    }
+
+    isSupportedFile(path) {
+        const dot = path.lastIndexOf(".");
+        return dot !== -1 && this.supportedExtensions.includes(path.slice(dot).toLowerCase());
+    }
+
     chapterTitle = $state("");
     isProcessing = $state(false);
     uploadProgress = $state(0);
     statusMsg = $state("");
     finalUrl = $state("");
+    // Заметка перед первой страницей (подписи и заметки к страницам хранятся в самих картинках)
+    introNote = $state("");
 
     // Edit Mode State
     editMode = $state(false);
@@ -80,7 +98,7 @@ class EditorStore {
         const existingPaths = new Set(this.images.map(img => img.originalPath));
         const newImages = paths
             .map((fullPath) => {
-                if (!fullPath.match(/\.(jpg|jpeg|png|webp)$/i)) return null;
+                if (!this.isSupportedFile(fullPath)) return null;
 
                 const fileName = fullPath.replace(/^.*[\\/]/, "");
                 if (existingPaths.has(fullPath)) return null;
@@ -91,7 +109,9 @@ class EditorStore {
                     thumbnailSrc: `/thumbnail/${encodeURIComponent(fullPath)}`,
                     originalPath: fullPath,
                     selected: true,
-                    type: 'file'
+                    type: 'file',
+                    caption: "",
+                    note: ""
                 };
             })
             .filter(Boolean);
@@ -99,6 +119,35 @@ class EditorStore {
         if (newImages.length > 0) {
             this.images.push(...newImages);
             this.statusMsg = `Добавлено ${newImages.length} файлов`;
+            this.applyChapterNotes(newImages.map(img => img.originalPath));
+        }
+    }
+
+    // Подписи и заметки из notes.json / notes.txt в папке главы.
+    // Ключ — имя файла или номер картинки в списке («0» — текст перед первой страницей).
+    async applyChapterNotes(paths) {
+        try {
+            const notes = await LoadChapterNotes(paths);
+            if (!notes) return;
+            let applied = 0;
+            for (const [key, entry] of Object.entries(notes)) {
+                if (key === "0") {
+                    this.introNote = entry.note || "";
+                    applied++;
+                    continue;
+                }
+                const img = this.images.find(i => i.name === key) ||
+                    (/^\d+$/.test(key) ? this.images[Number(key) - 1] : null);
+                if (!img) continue;
+                img.caption = entry.caption || "";
+                img.note = entry.note || "";
+                applied++;
+            }
+            if (applied > 0) {
+                this.statusMsg = `Загружены подписи и заметки: ${applied}`;
+            }
+        } catch (e) {
+            this.statusMsg = "Ошибка чтения заметок: " + e;
         }
     }
 
@@ -109,6 +158,7 @@ class EditorStore {
     clearAll() {
         this.images = [];
         this.chapterTitle = "";
+        this.introNote = "";
         this.statusMsg = "";
         this.finalUrl = "";
         this.editMode = false;
@@ -209,10 +259,15 @@ class EditorStore {
 
         try {
             const settingsSnapshot = $state.snapshot(settingsStore.settings);
+            // Режим разворотов тайтла важнее общего
+            const selectedTitle = titlesStore.titles.find(t => t.id === titlesStore.selectedTitleId);
+            if (selectedTitle?.spread_mode) {
+                settingsSnapshot.spread_mode = selectedTitle.spread_mode;
+            }
 
             const localFiles = selectedImages.filter(img => img.type === 'file').map(img => img.originalPath);
             
-            let newLinks = [];
+            let fileLinks = [];
             if (localFiles.length > 0) {
                 this.statusMsg = `Загрузка ${localFiles.length} новых изображений...`;
                 this.uploadProgress = 0;
@@ -234,39 +289,56 @@ class EditorStore {
                 }
 
                 if (!uploadRes.success) throw new Error(uploadRes.error);
-                newLinks = uploadRes.links;
+                // Разрезанный разворот даёт две ссылки на один файл
+                fileLinks = uploadRes.file_links || uploadRes.links.map(link => [link]);
             }
 
             let localFileIndex = 0;
-            const finalImageUrls = selectedImages.map(img => {
+            const finalImageUrls = selectedImages.flatMap(img => {
                 if (img.type === 'url') {
-                    return img.originalPath;
+                    return [img.originalPath];
                 } else {
-                    const link = newLinks[localFileIndex];
+                    const links = fileLinks[localFileIndex];
                     localFileIndex++;
-                    return link;
+                    return links;
                 }
             });
 
             if (this.editMode) {
+                // Подписи и заметки задаются только при публикации: правка меняет картинки,
+                // а уже опубликованные подписи и заметки остаются на странице (поля в режиме правки скрыты)
                 this.statusMsg = "Обновление статьи в Telegraph...";
                 const path = this.editArticlePath;
-                const resultUrl = await EditTelegraphPage(path, this.chapterTitle, finalImageUrls, this.editAccessToken);
-
-                if (resultUrl.startsWith("http")) {
-                    this.finalUrl = resultUrl;
-                    this.statusMsg = "Статья обновлена!";
-                    this.refreshImagesAfterSave(finalImageUrls);
-                } else {
-                    throw new Error(resultUrl);
-                }
+                // Ошибка Go приходит отклонённым промисом и попадает в catch ниже
+                this.finalUrl = await EditTelegraphPage(path, this.chapterTitle, finalImageUrls, this.editAccessToken);
+                this.statusMsg = "Статья обновлена!";
+                this.refreshImagesAfterSave(finalImageUrls);
 
             } else {
                 this.statusMsg = "Создание статьи в Telegraph...";
+                // Номера страниц считаются после резки разворотов: подпись — под первой страницей
+                // картинки, заметка — после последней
+                const notes = {};
+                if (this.introNote.trim()) notes[0] = { caption: "", note: this.introNote };
+                let page = 0;
+                let fileIndex = 0;
+                for (const img of selectedImages) {
+                    const first = page + 1;
+                    page += img.type === 'url' ? 1 : fileLinks[fileIndex++].length;
+                    if (img.caption?.trim()) notes[first] = { note: "", ...notes[first], caption: img.caption };
+                    if (img.note?.trim()) notes[page] = { caption: "", ...notes[page], note: img.note };
+                }
+
                 const titleIdToUse = titlesStore.selectedTitleId ? titlesStore.selectedTitleId : 0;
-                const response = await CreateTelegraphPage(this.chapterTitle, finalImageUrls, titleIdToUse);
+                const response = await CreateTelegraphPageWithNotes(this.chapterTitle, finalImageUrls, notes, titleIdToUse);
 
-                if (response.success) {
+                if (response.success && response.parts && response.parts.length > 1) {
+                    // Глава разбита на несколько страниц — редактировать её как одну страницу нельзя
+                    this.finalUrl = response.url;
+                    this.currentHistoryId = response.history_id;
+                    this.statusMsg = `Готово! Глава разбита на ${response.parts.length} частей`;
+                    this.refreshImagesAfterSave(finalImageUrls);
+                } else if (response.success) {
                     this.finalUrl = response.url;
                     this.currentHistoryId = response.history_id;
                     this.statusMsg = "Готово!";
@@ -284,7 +356,8 @@ class EditorStore {
             }
 
         } catch (e) {
-            this.statusMsg = "Ошибка: " + e.message;
+            // Wails отклоняет промис строкой, а не Error
+            this.statusMsg = "Ошибка: " + (e?.message ?? e);
         } finally {
             this.isProcessing = false;
         }
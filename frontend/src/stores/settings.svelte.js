@@ -4,13 +4,26 @@ class SettingsStore {
     settings = $state({
         resize: false,
         resize_to: 1600,
+        resize_mode: "width",
+        resize_height: 0,
+        max_megapixels: 0,
+        resize_percent: 100,
+        strip_height: 0,
+        resize_filter: "mitchell",
         webp_quality: 80,
         mock_r2: false,
+        spread_mode: "keep",
+        animation_mode: "",
+        sort_mode: "",
+        sort_reverse: false,
+        sort_pattern: "",
         last_channel_id: "0",
         last_channel_hash: "0",
         last_channel_title: ""
     })
 
+    // Ошибка последнего сохранения (например, неверное выражение сортировки)
+    saveError = $state("");
     isInitialized = false;
     saveTimer = null;
 
@@ -40,8 +53,19 @@ class SettingsStore {
             const settingsToSave = $state.snapshot(this.settings);
             // Ensure integer for quality
             settingsToSave.webp_quality = Math.round(settingsToSave.webp_quality);
-            SaveSettings(settingsToSave);
-            console.log("Settings saved");
+            settingsToSave.resize_height = Math.round(Number(settingsToSave.resize_height) || 0);
+            settingsToSave.resize_percent = Math.round(Number(settingsToSave.resize_percent) || 0);
+            settingsToSave.strip_height = Math.round(Number(settingsToSave.strip_height) || 0);
+            settingsToSave.max_megapixels = Number(settingsToSave.max_megapixels) || 0;
+            SaveSettings(settingsToSave)
+                .then(() => {
+                    this.saveError = "";
+                    console.log("Settings saved");
+                })
+                .catch((e) => {
+                    this.saveError = String(e?.message ?? e);
+                    console.error("Failed to save settings:", e);
+                });
         }, 500);
     }
 }
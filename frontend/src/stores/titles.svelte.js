@@ -1,4 +1,4 @@
-import { GetTitles, CreateTitle } from "../../wailsjs/go/main/App";
+import { GetTitles, CreateTitle, SetTitleIndex } from "../../wailsjs/go/main/App";
 
 class TitlesStore {
     titles = $state([]);
@@ -33,6 +33,19 @@ class TitlesStore {
             this.statusMsg = "Ошибка создания тайтла: " + e;
         }
     }
+
+    async setIndexAction(titleId, enabled, coverUrl, description) {
+        try {
+            const url = await SetTitleIndex(titleId, enabled, coverUrl || "", description || "");
+            await this.loadTitles();
+            this.statusMsg = enabled ? "Оглавление обновлено: " + url : "Оглавление отключено";
+            return url;
+        } catch (e) {
+            console.error("Failed to update title index:", e);
+            this.statusMsg = "Ошибка оглавления: " + e;
+            return "";
+        }
+    }
 }
 
 export const titlesStore = new TitlesStore();
@@ -10,14 +10,17 @@ import (
 )
 
 type Config struct {
-	R2AccountId     string `json:"r2_account_id"`
-	R2AccessKey     string `json:"r2_access_key"`
-	R2SecretKey     string `json:"r2_secret_key"`
-	BucketName      string `json:"bucket_name"`
-	PublicDomain    string `json:"public_domain"`
-	TelegraphToken  string `json:"telegraph_token"`
-	TelegramAppId   int    `json:"telegram_app_id"`
-	TelegramApiHash string `json:"telegram_app_hash"`
+	R2AccountId    string `json:"r2_account_id"`
+	R2AccessKey    string `json:"r2_access_key"`
+	R2SecretKey    string `json:"r2_secret_key"`
+	BucketName     string `json:"bucket_name"`
+	PublicDomain   string `json:"public_domain"`
+	TelegraphToken string `json:"telegraph_token"`
+	// Таймаут запросов к Telegraph и максимальное ожидание при FLOOD_WAIT, в секундах (0 — по умолчанию)
+	TelegraphTimeout      int    `json:"telegraph_timeout"`
+	TelegraphMaxFloodWait int    `json:"telegraph_max_flood_wait"`
+	TelegramAppId         int    `json:"telegram_app_id"`
+	TelegramApiHash       string `json:"telegram_app_hash"`
 }
 
 // loadConfig ищет config.json рядом с исполняемым файлом, а также поддерживает переменные окружения
@@ -72,6 +75,16 @@ func Load() (*Config, error) {
 	if val := os.Getenv("TELEGRAPH_TOKEN"); val != "" {
 		cfg.TelegraphToken = val
 	}
+	if val := os.Getenv("TELEGRAPH_TIMEOUT"); val != "" {
+		if sec, err := strconv.Atoi(val); err == nil {
+			cfg.TelegraphTimeout = sec
+		}
+	}
+	if val := os.Getenv("TELEGRAPH_MAX_FLOOD_WAIT"); val != "" {
+		if sec, err := strconv.Atoi(val); err == nil {
+			cfg.TelegraphMaxFloodWait = sec
+		}
+	}
 	if val := os.Getenv("TELEGRAM_APP_ID"); val != "" {
 		// Parse int
 		if id, err := strconv.Atoi(val); err == nil {
@@ -71,6 +71,24 @@ func TestLoad_Success(t *testing.T) {
 	}
 }
 
+func TestLoad_TelegraphEnv(t *testing.T) {
+	jsonContent := `{"r2_account_id": "acc1", "r2_access_key": "key1", "r2_secret_key": "sec1", "telegraph_max_flood_wait": 10}`
+	if err := os.WriteFile("config.json", []byte(jsonContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("config.json")
+	t.Setenv("TELEGRAPH_TIMEOUT", "30")
+	t.Setenv("TELEGRAPH_MAX_FLOOD_WAIT", "45")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TelegraphTimeout != 30 || cfg.TelegraphMaxFloodWait != 45 {
+		t.Errorf("expected env overrides 30/45, got %d/%d", cfg.TelegraphTimeout, cfg.TelegraphMaxFloodWait)
+	}
+}
+
 // Helper to mock executable path if needed, but Load() uses os.Executable()
 // which in `go test` returns the path to the test binary in a temporary folder.
 // The fallback logic in Load() checks CWD if not found near Executable.
@@ -19,6 +19,17 @@ type Settings struct {
 	LastChannelID    int64
 	LastChannelHash  int64
 	LastChannelTitle string
+	SortMode         string
+	SortReverse      bool
+	SortPattern      string
+	AnimationMode    string
+	SpreadMode       string
+	ResizeMode       string
+	ResizeHeight     int
+	MaxMegapixels    float64
+	ResizePercent    int
+	StripHeight      int
+	ResizeFilter     string
 }
 
 type Title struct {
@@ -26,6 +37,29 @@ type Title struct {
 	Name      string          `gorm:"unique" json:"name"`
 	Folders   []TitleFolder   `gorm:"foreignKey:TitleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"folders"`
 	Variables []TitleVariable `gorm:"foreignKey:TitleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"variables"`
+
+	// Порядок страниц, запомненный для тайтла (пустой SortMode — использовать общие настройки)
+	SortMode    string `json:"sort_mode"`
+	SortReverse bool   `json:"sort_reverse"`
+	SortPattern string `json:"sort_pattern"`
+
+	// Обработка разворотов для тайтла (пустая — использовать общие настройки)
+	SpreadMode string `json:"spread_mode"`
+
+	// Страница-оглавление тайтла в Telegraph, пересобирается при изменении списка глав
+	IndexEnabled bool   `json:"index_enabled"`
+	CoverURL     string `json:"cover_url"`
+	Description  string `json:"description"`
+	IndexPath    string `json:"index_path"`
+	IndexURL     string `json:"index_url"`
+	IndexToken   string `json:"index_token"`
+
+	// Аккаунт Telegraph, от имени которого публикуются главы (nil — аккаунт по умолчанию)
+	AccountID *uint `json:"account_id"`
+
+	// Шаблоны страниц, которые вставляются перед картинками и после них (nil — без шапки/подвала)
+	HeaderTemplateID *uint `json:"header_template_id"`
+	FooterTemplateID *uint `json:"footer_template_id"`
 }
 
 type TitleFolder struct {
@@ -41,18 +75,115 @@ type TitleVariable struct {
 	Value   string `json:"value"`
 }
 
+// Виды шаблонов: текст поста в Telegram или блок страницы Telegraph (шапка или подвал главы)
+const (
+	TemplatePost = ""
+	TemplatePage = "page"
+)
+
 type Template struct {
 	ID      uint   `gorm:"primaryKey" json:"id"`
 	Name    string `gorm:"unique" json:"name"`
 	Content string `json:"content"`
+	Kind    string `gorm:"index" json:"kind"`
+	Format  string `json:"format"` // markdown или html — для шаблонов страниц
 }
 
-type UploadedFile struct {
-	Hash      string    `gorm:"primaryKey" json:"hash"` // Unique hash (SHA-256)
-	URL       string    `json:"url"`                    // URL in R2
+// TelegraphAccount — сохранённый аккаунт Telegraph. Токен нужен для правки созданных им страниц.
+type TelegraphAccount struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ShortName   string    `json:"short_name"`
+	AuthorName  string    `json:"author_name"`
+	AuthorURL   string    `json:"author_url"`
+	AccessToken string    `gorm:"uniqueIndex" json:"access_token"`
+	AuthURL     string    `json:"auth_url"`
+	PageCount   int       `json:"page_count"`
+	IsDefault   bool      `json:"is_default"`
+	CreatedAt   time.Time `json:"created_at"`
+	// RevokedToken — предыдущий токен, отозванный через приложение; по нему узнаём устаревший токен в конфиге
+	RevokedToken string `gorm:"index" json:"-"`
+}
+
+// ViewSnapshot — снимок просмотров страницы главы. Все снимки одного прохода сборщика
+// имеют одинаковый TakenAt, по нему строятся графики тайтла.
+// Year/Month/Day/Hour — просмотры за год, месяц, день и час, в которые сделан снимок.
+type ViewSnapshot struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	HistoryID uint      `gorm:"index" json:"history_id"`
+	TitleID   *uint     `gorm:"index" json:"title_id"`
+	TakenAt   time.Time `gorm:"index" json:"taken_at"`
+	Total     int       `json:"total"`
+	Year      int       `json:"year"`
+	Month     int       `json:"month"`
+	Day       int       `json:"day"`
+	Hour      int       `json:"hour"`
+}
+
+// PageRevision — снимок страницы главы после создания или правки; по нему можно откатить страницу.
+// У разбитой на части главы ревизии ведутся для каждой части отдельно (различаются Path).
+type PageRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	HistoryID uint      `gorm:"index" json:"history_id"`
+	Path      string    `json:"path"`
+	Title     string    `json:"title"`
+	Content   string    `json:"-"` // JSON узлов страницы
+	ImgCount  int       `json:"img_count"`
+	Reason    string    `json:"reason"` // create, edit, navigation, rollback
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// ViewPoint — точка графика просмотров (для тайтла — сумма по главам)
+type ViewPoint struct {
+	TakenAt time.Time `json:"taken_at"`
+	Total   int       `json:"total"`
+	Day     int       `json:"day"`
+	Hour    int       `json:"hour"`
+}
+
+// DomainMigration — перенос картинок страниц истории со старого префикса адреса на новый.
+// Главы обходятся по возрастанию ID; LastHistoryID — последняя обработанная, с неё продолжается
+// прерванный перенос.
+type DomainMigration struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	OldPrefix     string    `json:"old_prefix"`
+	NewPrefix     string    `json:"new_prefix"`
+	Status        string    `json:"status"`
+	LastHistoryID uint      `json:"last_history_id"`
+	Pages         int       `json:"pages"`  // изменено страниц
+	Images        int       `json:"images"` // заменено адресов
+	LastError     string    `json:"last_error"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Состояния DomainMigration
+const (
+	MigrationRunning = "running"
+	MigrationFailed  = "failed"
+	MigrationDone    = "done"
+)
+
+type UploadedFile struct {
+	Hash       string    `gorm:"primaryKey" json:"hash"` // Unique hash (SHA-256)
+	URL        string    `gorm:"index" json:"url"`       // URL in R2
+	SourcePath string    `json:"source_path"`            // локальный файл, из которого сделана картинка
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// LinkIssue — битая или медленная картинка на опубликованной странице главы.
+// Результаты проверки страницы заменяют предыдущие, исправные ссылки не хранятся.
+type LinkIssue struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	HistoryID uint      `gorm:"index" json:"history_id"`
+	Path      string    `json:"path"` // страница (у разбитой главы — часть)
+	URL       string    `json:"url"`
+	Status    int       `json:"status"` // HTTP-код, 0 — запрос не удался
+	Error     string    `json:"error"`
+	LatencyMs int64     `json:"latency_ms"`
+	Broken    bool      `json:"broken"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
 // HistoryEntry maps to database table "history_items"
 type HistoryEntry struct {
 	ID        uint `gorm:"primaryKey"`
@@ -62,21 +193,33 @@ type HistoryEntry struct {
 	ImgCount  int
 	TgphToken string
 	TitleID   *uint
+	// Parts — страницы, на которые разбита слишком длинная глава (пусто, если страница одна)
+	Parts []HistoryPart `gorm:"foreignKey:HistoryID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
 
 func (HistoryEntry) TableName() string {
 	return "history_items"
 }
 
-// HistoryItem is the DTO for Frontend
-type HistoryItem struct {
-	ID        uint   `json:"id"`
-	Date      string `json:"date"`
-	Title     string `json:"title"`
+// HistoryPart — одна из страниц главы, разбитой на части
+type HistoryPart struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	HistoryID uint   `gorm:"index" json:"history_id"`
+	Part      int    `json:"part"` // номер части, с 1
 	Url       string `json:"url"`
 	ImgCount  int    `json:"img_count"`
-	TgphToken string `json:"tgph_token"`
-	TitleID   *uint  `json:"title_id"`
+}
+
+// HistoryItem is the DTO for Frontend
+type HistoryItem struct {
+	ID        uint          `json:"id"`
+	Date      string        `json:"date"`
+	Title     string        `json:"title"`
+	Url       string        `json:"url"`
+	ImgCount  int           `json:"img_count"`
+	TgphToken string        `json:"tgph_token"`
+	TitleID   *uint         `json:"title_id"`
+	Parts     []HistoryPart `json:"parts"`
 }
 
 // Init инициализирует БД и создает файл database.db
@@ -111,7 +254,7 @@ func InitWithFile(dbPath string) (*gorm.DB, error) {
 	}
 
 	// Автоматическая миграция
-	err = db.AutoMigrate(&Settings{}, &HistoryEntry{}, &Title{}, &TitleFolder{}, &TitleVariable{}, &Template{}, &UploadedFile{})
+	err = db.AutoMigrate(&Settings{}, &HistoryEntry{}, &Title{}, &TitleFolder{}, &TitleVariable{}, &Template{}, &UploadedFile{}, &HistoryPart{}, &TelegraphAccount{}, &ViewSnapshot{}, &PageRevision{}, &LinkIssue{}, &DomainMigration{})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,119 @@
+package imageformat
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AVIF и JPEG XL декодируются внешними утилитами libavif (avifdec) и libjxl (djxl),
+// если они есть в PATH. Без них форматы не попадают в списки поддерживаемых.
+
+// externalDecodeTimeout ограничивает время работы внешней утилиты на одном файле
+const externalDecodeTimeout = time.Minute
+
+func init() {
+	Register(Format{
+		Name:        "avif",
+		Extensions:  []string{".avif"},
+		ContentType: "image/avif",
+		Native:      true,
+		Match:       isAVIF,
+		Decode:      externalDecoder("avifdec", ".avif"),
+		Available:   toolAvailable("avifdec"),
+	})
+	Register(Format{
+		Name:        "jxl",
+		Extensions:  []string{".jxl"},
+		ContentType: "image/jxl",
+		Match:       isJXL,
+		Decode:      externalDecoder("djxl", ".jxl"),
+		Available:   toolAvailable("djxl"),
+	})
+}
+
+// isAVIF ищет бренд avif/avis в ftyp: основным брендом может быть и общий mif1/msf1
+func isAVIF(h []byte) bool {
+	if len(h) < 12 || string(h[4:8]) != "ftyp" {
+		return false
+	}
+	end := int(binary.BigEndian.Uint32(h))
+	if end > len(h) || end < 12 {
+		end = len(h)
+	}
+	// major_brand, затем minor_version и список совместимых брендов
+	for i := 8; i+4 <= end; i += 4 {
+		if i == 12 {
+			continue
+		}
+		if brand := string(h[i : i+4]); brand == "avif" || brand == "avis" {
+			return true
+		}
+	}
+	return false
+}
+
+func isJXL(h []byte) bool {
+	if len(h) >= 2 && h[0] == 0xFF && h[1] == 0x0A {
+		return true
+	}
+	return len(h) >= 12 && bytes.Equal(h[:12], []byte{0, 0, 0, 0x0C, 'J', 'X', 'L', ' ', 0x0D, 0x0A, 0x87, 0x0A})
+}
+
+// toolAvailable кэширует результат поиска утилиты в PATH
+func toolAvailable(tool string) func() bool {
+	var once sync.Once
+	var ok bool
+	return func() bool {
+		once.Do(func() {
+			_, err := exec.LookPath(tool)
+			ok = err == nil
+		})
+		return ok
+	}
+}
+
+// externalDecoder запускает `tool input output.png` во временной папке
+func externalDecoder(tool, ext string) func([]byte) (image.Image, error) {
+	return func(data []byte) (image.Image, error) {
+		dir, err := os.MkdirTemp("", "imgdecode")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		in := filepath.Join(dir, "input"+ext)
+		out := filepath.Join(dir, "output.png")
+		if err := os.WriteFile(in, data, 0600); err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), externalDecodeTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, tool, in, out)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("%s: timed out after %s", tool, externalDecodeTimeout)
+			}
+			return nil, fmt.Errorf("%s: %v: %s", tool, err, bytes.TrimSpace(stderr.Bytes()))
+		}
+
+		f, err := os.Open(out)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return png.Decode(f)
+	}
+}
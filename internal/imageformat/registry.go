@@ -0,0 +1,143 @@
+package imageformat
+
+import (
+	"bytes"
+	"image"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	// Декодеры, регистрирующиеся в image.Decode
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "github.com/chai2010/webp"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+)
+
+// Format описывает входной формат изображений.
+// Список форматов — единственный источник правды для диалога выбора файлов,
+// сканирования папок, сервера превью и декодирования.
+type Format struct {
+	Name        string
+	Extensions  []string // с точкой, в нижнем регистре
+	ContentType string
+	// Native — браузер (WebView) умеет показывать формат сам; иначе превью перекодируется
+	Native bool
+	// Match распознаёт формат по первым байтам (нужно только для внешних декодеров)
+	Match func(header []byte) bool
+	// Decode — собственный декодер; nil означает image.Decode
+	Decode func(data []byte) (image.Image, error)
+	// Available сообщает, доступен ли декодер сейчас; nil — всегда
+	Available func() bool
+}
+
+func (f Format) available() bool {
+	return f.Available == nil || f.Available()
+}
+
+var (
+	mu      sync.RWMutex
+	formats []Format
+)
+
+// Register добавляет формат в реестр
+func Register(f Format) {
+	mu.Lock()
+	defer mu.Unlock()
+	formats = append(formats, f)
+}
+
+func init() {
+	Register(Format{Name: "jpeg", Extensions: []string{".jpg", ".jpeg"}, ContentType: "image/jpeg", Native: true})
+	Register(Format{Name: "png", Extensions: []string{".png"}, ContentType: "image/png", Native: true})
+	Register(Format{Name: "webp", Extensions: []string{".webp"}, ContentType: "image/webp", Native: true})
+	Register(Format{Name: "gif", Extensions: []string{".gif"}, ContentType: "image/gif", Native: true})
+	Register(Format{Name: "bmp", Extensions: []string{".bmp"}, ContentType: "image/bmp", Native: true})
+	Register(Format{Name: "tiff", Extensions: []string{".tif", ".tiff"}, ContentType: "image/tiff"})
+}
+
+// Formats возвращает форматы, для которых сейчас есть декодер
+func Formats() []Format {
+	mu.RLock()
+	defer mu.RUnlock()
+	var res []Format
+	for _, f := range formats {
+		if f.available() {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// Lookup находит доступный формат по расширению файла
+func Lookup(path string) (Format, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, f := range Formats() {
+		for _, e := range f.Extensions {
+			if e == ext {
+				return f, true
+			}
+		}
+	}
+	return Format{}, false
+}
+
+// IsSupported — можно ли брать файл в главу
+func IsSupported(path string) bool {
+	_, ok := Lookup(path)
+	return ok
+}
+
+// Extensions возвращает все поддерживаемые расширения (с точкой)
+func Extensions() []string {
+	var exts []string
+	for _, f := range Formats() {
+		exts = append(exts, f.Extensions...)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// DialogPattern — фильтр для нативного диалога: "*.jpg;*.png;..."
+func DialogPattern() string {
+	exts := Extensions()
+	patterns := make([]string, len(exts))
+	for i, e := range exts {
+		patterns[i] = "*" + e
+	}
+	return strings.Join(patterns, ";")
+}
+
+// Decode декодирует данные: сначала внешние/особые декодеры по сигнатуре, затем image.Decode
+func Decode(data []byte) (image.Image, string, error) {
+	for _, f := range Formats() {
+		if f.Decode != nil && f.Match != nil && f.Match(data) {
+			img, err := f.Decode(data)
+			return img, f.Name, err
+		}
+	}
+	img, name, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return img, name, nil
+}
+
+// DecodeConfig возвращает размеры без полного декодирования, где это возможно
+func DecodeConfig(data []byte) (image.Config, string, error) {
+	for _, f := range Formats() {
+		if f.Decode != nil && f.Match != nil && f.Match(data) {
+			img, err := f.Decode(data)
+			if err != nil {
+				return image.Config{}, "", err
+			}
+			b := img.Bounds()
+			return image.Config{ColorModel: img.ColorModel(), Width: b.Dx(), Height: b.Dy()}, f.Name, nil
+		}
+	}
+	return image.DecodeConfig(bytes.NewReader(data))
+}
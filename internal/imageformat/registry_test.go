@@ -0,0 +1,104 @@
+package imageformat
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 7))
+	img.Set(3, 2, color.RGBA{255, 0, 0, 255})
+	return img
+}
+
+func TestDecode_BuiltinFormats(t *testing.T) {
+	var tiffBuf, bmpBuf bytes.Buffer
+	if err := tiff.Encode(&tiffBuf, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := bmp.Encode(&bmpBuf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{"tiff": tiffBuf.Bytes(), "bmp": bmpBuf.Bytes()} {
+		img, format, err := Decode(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if format != name {
+			t.Errorf("expected format %s, got %s", name, format)
+		}
+		if img.Bounds().Dx() != 10 || img.Bounds().Dy() != 7 {
+			t.Errorf("%s: unexpected bounds %v", name, img.Bounds())
+		}
+		cfg, _, err := DecodeConfig(data)
+		if err != nil || cfg.Width != 10 || cfg.Height != 7 {
+			t.Errorf("%s: DecodeConfig = %+v, %v", name, cfg, err)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, path := range []string{"a.JPG", "b.jpeg", "c.png", "d.webp", "e.gif", "f.bmp", "g.tif", "h.TIFF"} {
+		if !IsSupported(path) {
+			t.Errorf("%s should be supported", path)
+		}
+	}
+	for _, path := range []string{"a.txt", "noext", "c.psd"} {
+		if IsSupported(path) {
+			t.Errorf("%s should not be supported", path)
+		}
+	}
+
+	f, ok := Lookup("scan.tif")
+	if !ok || f.ContentType != "image/tiff" || f.Native {
+		t.Errorf("unexpected tiff format: %+v", f)
+	}
+}
+
+func TestUnavailableFormatIsHidden(t *testing.T) {
+	Register(Format{
+		Name:       "fake",
+		Extensions: []string{".fake"},
+		Available:  func() bool { return false },
+	})
+	if IsSupported("x.fake") {
+		t.Error("format without decoder must not be supported")
+	}
+	if strings.Contains(DialogPattern(), ".fake") {
+		t.Error("format without decoder must not be in dialog pattern")
+	}
+	if !strings.Contains(DialogPattern(), "*.tiff") {
+		t.Errorf("dialog pattern misses tiff: %s", DialogPattern())
+	}
+}
+
+func TestSignatures(t *testing.T) {
+	if !isAVIF([]byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00")) {
+		t.Error("avif signature not detected")
+	}
+	if !isAVIF([]byte("\x00\x00\x00\x1cftypmif1\x00\x00\x00\x00mif1avifmiaf")) {
+		t.Error("avif as compatible brand of mif1 not detected")
+	}
+	if isAVIF([]byte("\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00mif1heic")) {
+		t.Error("heic detected as avif")
+	}
+	if isAVIF([]byte("\x00\x00\x00\x10ftypmif1\x00\x00\x00\x00avif")) {
+		t.Error("brand past the end of ftyp box detected as avif")
+	}
+	if isAVIF([]byte("\x00\x00\x00\x1cftypisom\x00\x00\x00\x00")) {
+		t.Error("mp4 detected as avif")
+	}
+	if !isJXL([]byte{0xFF, 0x0A, 0x00}) {
+		t.Error("jxl codestream not detected")
+	}
+	if !isJXL([]byte{0, 0, 0, 0x0C, 'J', 'X', 'L', ' ', 0x0D, 0x0A, 0x87, 0x0A, 0}) {
+		t.Error("jxl container not detected")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build darwin
+
+package ordering
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func creationTime(path string, info os.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Birthtimespec.Sec, st.Birthtimespec.Nsec)
+	}
+	return info.ModTime()
+}
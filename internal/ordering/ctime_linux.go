@@ -0,0 +1,20 @@
+//go:build linux
+
+package ordering
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// creationTime берёт btime через statx; если ФС его не хранит — время изменения
+func creationTime(path string, info os.FileInfo) time.Time {
+	var stx unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME, &stx)
+	if err != nil || stx.Mask&unix.STATX_BTIME == 0 {
+		return info.ModTime()
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec))
+}
@@ -0,0 +1,12 @@
+//go:build !windows && !darwin && !linux
+
+package ordering
+
+import (
+	"os"
+	"time"
+)
+
+func creationTime(path string, info os.FileInfo) time.Time {
+	return info.ModTime()
+}
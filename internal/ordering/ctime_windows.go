@@ -0,0 +1,16 @@
+//go:build windows
+
+package ordering
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func creationTime(path string, info os.FileInfo) time.Time {
+	if attrs, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, attrs.CreationTime.Nanoseconds())
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,176 @@
+package ordering
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Стратегии сортировки страниц
+const (
+	ModeName    = "name"    // побайтовое сравнение имён (старое поведение)
+	ModeNatural = "natural" // 2.jpg < 10.jpg
+	ModeModTime = "mtime"   // по времени изменения файла
+	ModeCreated = "ctime"   // по времени создания файла
+	ModeRegex   = "regex"   // номер страницы из пользовательского выражения
+)
+
+// Options описывает выбранный порядок страниц
+type Options struct {
+	Mode    string `json:"mode"`
+	Reverse bool   `json:"reverse"`
+	// Pattern — регулярное выражение с группой, захватывающей номер страницы (для ModeRegex)
+	Pattern string `json:"pattern"`
+}
+
+// Default возвращает порядок, который используется, если ничего не выбрано
+func Default() Options {
+	return Options{Mode: ModeNatural}
+}
+
+// Validate проверяет режим и выражение, не трогая файловую систему
+func (o Options) Validate() error {
+	switch o.Mode {
+	case "", ModeName, ModeNatural, ModeModTime, ModeCreated:
+		return nil
+	case ModeRegex:
+		_, err := compilePattern(o.Pattern)
+		return err
+	default:
+		return fmt.Errorf("неизвестный режим сортировки: %s", o.Mode)
+	}
+}
+
+// Sort упорядочивает пути на месте согласно opts
+func Sort(paths []string, opts Options) error {
+	var less func(i, j int) bool
+
+	switch opts.Mode {
+	case ModeName:
+		less = func(i, j int) bool { return paths[i] < paths[j] }
+
+	case "", ModeNatural:
+		less = func(i, j int) bool { return NaturalLess(filepath.Base(paths[i]), filepath.Base(paths[j])) }
+
+	case ModeModTime, ModeCreated:
+		times := make(map[string]time.Time, len(paths))
+		for _, p := range paths {
+			info, err := os.Stat(p)
+			if err != nil {
+				return err
+			}
+			if opts.Mode == ModeCreated {
+				times[p] = creationTime(p, info)
+			} else {
+				times[p] = info.ModTime()
+			}
+		}
+		less = func(i, j int) bool {
+			ti, tj := times[paths[i]], times[paths[j]]
+			if ti.Equal(tj) {
+				return NaturalLess(filepath.Base(paths[i]), filepath.Base(paths[j]))
+			}
+			return ti.Before(tj)
+		}
+
+	case ModeRegex:
+		re, err := compilePattern(opts.Pattern)
+		if err != nil {
+			return err
+		}
+		keys := make(map[string]string, len(paths))
+		for _, p := range paths {
+			if m := re.FindStringSubmatch(filepath.Base(p)); m != nil {
+				keys[p] = m[1]
+			}
+		}
+		// Файлы без совпадения уходят в конец в естественном порядке
+		less = func(i, j int) bool {
+			ki, okI := keys[paths[i]]
+			kj, okJ := keys[paths[j]]
+			switch {
+			case okI && okJ && ki != kj:
+				return NaturalLess(ki, kj)
+			case okI != okJ:
+				return okI
+			default:
+				return NaturalLess(filepath.Base(paths[i]), filepath.Base(paths[j]))
+			}
+		}
+
+	default:
+		return fmt.Errorf("неизвестный режим сортировки: %s", opts.Mode)
+	}
+
+	sort.SliceStable(paths, less)
+
+	if opts.Reverse {
+		for i, j := 0, len(paths)-1; i < j; i, j = i+1, j-1 {
+			paths[i], paths[j] = paths[j], paths[i]
+		}
+	}
+	return nil
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("не задано регулярное выражение для сортировки")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка в регулярном выражении: %w", err)
+	}
+	if re.NumSubexp() < 1 {
+		return nil, fmt.Errorf("регулярное выражение должно содержать группу с номером страницы")
+	}
+	return re, nil
+}
+
+// NaturalLess сравнивает строки, учитывая числа целиком: "2.jpg" < "10.jpg"
+func NaturalLess(a, b string) bool {
+	la, lb := strings.ToLower(a), strings.ToLower(b)
+	i, j := 0, 0
+	for i < len(la) && j < len(lb) {
+		ca, cb := la[i], lb[j]
+		if isDigit(ca) && isDigit(cb) {
+			si := i
+			for i < len(la) && isDigit(la[i]) {
+				i++
+			}
+			sj := j
+			for j < len(lb) && isDigit(lb[j]) {
+				j++
+			}
+			na := strings.TrimLeft(la[si:i], "0")
+			nb := strings.TrimLeft(lb[sj:j], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			// 01 и 1 равны по значению — короткая запись идёт первой
+			if i-si != j-sj {
+				return i-si < j-sj
+			}
+			continue
+		}
+		if ca != cb {
+			return ca < cb
+		}
+		i++
+		j++
+	}
+	if len(la)-i != len(lb)-j {
+		return len(la)-i < len(lb)-j
+	}
+	return a < b
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
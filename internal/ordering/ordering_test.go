@@ -0,0 +1,113 @@
+package ordering
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNaturalLess(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"2.jpg", "10.jpg", true},
+		{"10.jpg", "2.jpg", false},
+		{"page_9.png", "page_10.png", true},
+		{"01.jpg", "1.jpg", false},
+		{"1.jpg", "01.jpg", true},
+		{"a.jpg", "B.jpg", true},
+		{"img", "img1", true},
+	}
+	for _, c := range cases {
+		if got := NaturalLess(c.a, c.b); got != c.want {
+			t.Errorf("NaturalLess(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestSort_Natural(t *testing.T) {
+	paths := []string{"/c/10.jpg", "/c/2.jpg", "/c/1.jpg"}
+	if err := Sort(paths, Options{Mode: ModeNatural}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/c/1.jpg", "/c/2.jpg", "/c/10.jpg"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected %v, got %v", want, paths)
+	}
+
+	if err := Sort(paths, Options{Mode: ModeNatural, Reverse: true}); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"/c/10.jpg", "/c/2.jpg", "/c/1.jpg"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected reversed %v, got %v", want, paths)
+	}
+
+	// Old behaviour stays available
+	if err := Sort(paths, Options{Mode: ModeName}); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"/c/1.jpg", "/c/10.jpg", "/c/2.jpg"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected lexicographic %v, got %v", want, paths)
+	}
+}
+
+func TestSort_Regex(t *testing.T) {
+	paths := []string{"v01_p10_scan.jpg", "credits.jpg", "v01_p2_scan.jpg", "v01_p1_scan.jpg"}
+	if err := Sort(paths, Options{Mode: ModeRegex, Pattern: `_p(\d+)_`}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"v01_p1_scan.jpg", "v01_p2_scan.jpg", "v01_p10_scan.jpg", "credits.jpg"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected %v, got %v", want, paths)
+	}
+
+	if err := Sort(paths, Options{Mode: ModeRegex, Pattern: `_p\d+_`}); err == nil {
+		t.Error("expected error for pattern without capture group")
+	}
+	if err := Sort(paths, Options{Mode: ModeRegex, Pattern: `(`}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestSort_ModTime(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{"a.jpg", "b.jpg", "c.jpg"}
+	base := time.Now().Add(-time.Hour)
+	var paths []string
+	for i, name := range names {
+		p := filepath.Join(dir, name)
+		os.WriteFile(p, []byte("x"), 0644)
+		// c oldest, a newest
+		mt := base.Add(time.Duration(len(names)-i) * time.Minute)
+		os.Chtimes(p, mt, mt)
+		paths = append(paths, p)
+	}
+
+	if err := Sort(paths, Options{Mode: ModeModTime}); err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(paths[0]) != "c.jpg" || filepath.Base(paths[2]) != "a.jpg" {
+		t.Errorf("unexpected mtime order: %v", paths)
+	}
+
+	if err := Sort([]string{filepath.Join(dir, "missing.jpg")}, Options{Mode: ModeModTime}); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	if err := (Options{}).Validate(); err != nil {
+		t.Errorf("empty options should be valid: %v", err)
+	}
+	if err := (Options{Mode: "random"}).Validate(); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+	if err := (Options{Mode: ModeRegex, Pattern: `(\d+)`}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"telegraph_uploader_v2/internal/database"
+
+	"gorm.io/gorm"
+)
+
+type AccountRepository interface {
+	Create(acc *database.TelegraphAccount) error
+	GetAll() ([]database.TelegraphAccount, error)
+	GetByID(id uint) (database.TelegraphAccount, error)
+	GetByToken(token string) (database.TelegraphAccount, error)
+	GetByRevokedToken(token string) (database.TelegraphAccount, error)
+	GetDefault() (database.TelegraphAccount, error)
+	Update(acc database.TelegraphAccount) error
+	SetDefault(id uint) error
+	ReplaceToken(id uint, oldToken, newToken string) (int64, error)
+	Delete(id uint) error
+}
+
+type accountRepo struct {
+	db *gorm.DB
+}
+
+func NewAccountRepository(db *gorm.DB) AccountRepository {
+	return &accountRepo{db: db}
+}
+
+func (r *accountRepo) Create(acc *database.TelegraphAccount) error {
+	return r.db.Create(acc).Error
+}
+
+func (r *accountRepo) GetAll() ([]database.TelegraphAccount, error) {
+	var accounts []database.TelegraphAccount
+	err := r.db.Order("id asc").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r *accountRepo) GetByID(id uint) (database.TelegraphAccount, error) {
+	var acc database.TelegraphAccount
+	err := r.db.First(&acc, id).Error
+	return acc, err
+}
+
+func (r *accountRepo) GetByToken(token string) (database.TelegraphAccount, error) {
+	var acc database.TelegraphAccount
+	err := r.db.Where("access_token = ?", token).First(&acc).Error
+	return acc, err
+}
+
+// GetByRevokedToken ищет аккаунт, у которого этот токен был отозван
+func (r *accountRepo) GetByRevokedToken(token string) (database.TelegraphAccount, error) {
+	var acc database.TelegraphAccount
+	err := r.db.Where("revoked_token = ?", token).First(&acc).Error
+	return acc, err
+}
+
+func (r *accountRepo) GetDefault() (database.TelegraphAccount, error) {
+	var acc database.TelegraphAccount
+	err := r.db.Where("is_default = ?", true).First(&acc).Error
+	return acc, err
+}
+
+func (r *accountRepo) Update(acc database.TelegraphAccount) error {
+	return r.db.Save(&acc).Error
+}
+
+// SetDefault делает аккаунт основным, снимая флаг с остальных
+func (r *accountRepo) SetDefault(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&database.TelegraphAccount{}).Where("is_default = ?", true).Update("is_default", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&database.TelegraphAccount{}).Where("id = ?", id).Update("is_default", true).Error
+	})
+}
+
+// ReplaceToken записывает новый токен аккаунта (старый запоминается как отозванный)
+// и перепривязывает к нему историю и оглавления тайтлов.
+// Возвращает число перепривязанных записей истории.
+func (r *accountRepo) ReplaceToken(id uint, oldToken, newToken string) (int64, error) {
+	var rebound int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&database.TelegraphAccount{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"access_token":  newToken,
+			"revoked_token": oldToken,
+		}).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&database.HistoryEntry{}).Where("tgph_token = ?", oldToken).Update("tgph_token", newToken)
+		if res.Error != nil {
+			return res.Error
+		}
+		rebound = res.RowsAffected
+		return tx.Model(&database.Title{}).Where("index_token = ?", oldToken).Update("index_token", newToken).Error
+	})
+	return rebound, err
+}
+
+// Delete удаляет аккаунт; тайтлы, привязанные к нему, переходят на аккаунт по умолчанию
+func (r *accountRepo) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&database.Title{}).Where("account_id = ?", id).Update("account_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&database.TelegraphAccount{}, id).Error
+	})
+}
@@ -8,7 +8,11 @@ import (
 
 type ImageCacheRepository interface {
 	GetURL(hash string) (string, bool)
-	Save(hash, url string) error
+	// FindByURL — запись кэша по адресу картинки (ключ и исходный файл)
+	FindByURL(url string) (database.UploadedFile, bool)
+	Save(hash, url, sourcePath string) error
+	// ReplacePrefix переносит адреса в кэше на новый домен, чтобы повторные загрузки не вели на старый
+	ReplacePrefix(oldPrefix, newPrefix string) (int64, error)
 }
 
 type imageCacheRepo struct {
@@ -28,10 +32,27 @@ func (r *imageCacheRepo) GetURL(hash string) (string, bool) {
 	return item.URL, true
 }
 
-func (r *imageCacheRepo) Save(hash, url string) error {
+func (r *imageCacheRepo) FindByURL(url string) (database.UploadedFile, bool) {
+	var item database.UploadedFile
+	err := r.db.Where("url = ?", url).Order("created_at desc").First(&item).Error
+	if err != nil {
+		return database.UploadedFile{}, false
+	}
+	return item, true
+}
+
+func (r *imageCacheRepo) Save(hash, url, sourcePath string) error {
 	item := database.UploadedFile{
-		Hash: hash,
-		URL:  url,
+		Hash:       hash,
+		URL:        url,
+		SourcePath: sourcePath,
 	}
 	return r.db.Save(&item).Error
 }
+
+func (r *imageCacheRepo) ReplacePrefix(oldPrefix, newPrefix string) (int64, error) {
+	res := r.db.Model(&database.UploadedFile{}).
+		Where("substr(url, 1, ?) = ?", len(oldPrefix), oldPrefix).
+		Update("url", gorm.Expr("? || substr(url, ?)", newPrefix, len(oldPrefix)+1))
+	return res.RowsAffected, res.Error
+}
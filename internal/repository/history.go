@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	"telegraph_uploader_v2/internal/database"
 	"time"
 
@@ -11,6 +12,13 @@ type HistoryRepository interface {
 	Add(title, url string, imgCount int, tgphToken string, titleID *uint) (uint, error)
 	Get(limit, offset int) ([]database.HistoryItem, error)
 	GetByID(id uint) (database.HistoryItem, error)
+	AddParts(historyID uint, parts []database.HistoryPart) error
+	GetByTitle(titleID uint) ([]database.HistoryItem, error)
+	FindByPath(path string) (database.HistoryItem, error)
+	UpdateTitle(id uint, title string) error
+	Delete(id uint) error
+	KnownURLs() (map[string]bool, error)
+	All() ([]database.HistoryItem, error)
 	Clear() error
 }
 
@@ -37,32 +45,124 @@ func (r *historyRepo) Add(title, url string, imgCount int, tgphToken string, tit
 
 func (r *historyRepo) Get(limit, offset int) ([]database.HistoryItem, error) {
 	var dbItems []database.HistoryEntry
-	err := r.db.Order("created_at desc").Limit(limit).Offset(offset).Find(&dbItems).Error
+	err := r.db.Preload("Parts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("part asc")
+	}).Order("created_at desc").Limit(limit).Offset(offset).Find(&dbItems).Error
 	if err != nil {
 		return nil, err
 	}
 
 	result := make([]database.HistoryItem, len(dbItems))
 	for i, item := range dbItems {
-		result[i] = database.HistoryItem{
-			ID:        item.ID,
-			Date:      item.CreatedAt.Format("2006-01-02 15:04:05"),
-			Title:     item.Title,
-			Url:       item.Url,
-			ImgCount:  item.ImgCount,
-			TgphToken: item.TgphToken,
-			TitleID:   item.TitleID,
-		}
+		result[i] = toHistoryItem(item)
 	}
 	return result, nil
 }
 
 func (r *historyRepo) GetByID(id uint) (database.HistoryItem, error) {
 	var item database.HistoryEntry
-	err := r.db.First(&item, id).Error
+	err := r.db.Preload("Parts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("part asc")
+	}).First(&item, id).Error
+	if err != nil {
+		return database.HistoryItem{}, err
+	}
+	return toHistoryItem(item), nil
+}
+
+// GetByTitle возвращает все главы тайтла в порядке публикации
+func (r *historyRepo) GetByTitle(titleID uint) ([]database.HistoryItem, error) {
+	var dbItems []database.HistoryEntry
+	err := r.db.Preload("Parts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("part asc")
+	}).Where("title_id = ?", titleID).Order("created_at asc, id asc").Find(&dbItems).Error
 	if err != nil {
+		return nil, err
+	}
+
+	result := make([]database.HistoryItem, len(dbItems))
+	for i, item := range dbItems {
+		result[i] = toHistoryItem(item)
+	}
+	return result, nil
+}
+
+// FindByPath ищет главу по пути страницы Telegraph (последний сегмент URL).
+// Путь части разбитой главы находит главу, которой часть принадлежит.
+func (r *historyRepo) FindByPath(path string) (database.HistoryItem, error) {
+	var item database.HistoryEntry
+	err := r.db.Preload("Parts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("part asc")
+	}).Where("url LIKE ?", "%/"+path).Order("id desc").First(&item).Error
+	if err == nil {
+		return toHistoryItem(item), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return database.HistoryItem{}, err
+	}
+
+	var part database.HistoryPart
+	if err := r.db.Where("url LIKE ?", "%/"+path).Order("id desc").First(&part).Error; err != nil {
 		return database.HistoryItem{}, err
 	}
+	return r.GetByID(part.HistoryID)
+}
+
+func (r *historyRepo) UpdateTitle(id uint, title string) error {
+	return r.db.Model(&database.HistoryEntry{}).Where("id = ?", id).Update("title", title).Error
+}
+
+// Delete удаляет запись истории вместе с частями, статистикой, ревизиями и результатами проверки ссылок
+// (страницы в Telegraph остаются)
+func (r *historyRepo) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("history_id = ?", id).Delete(&database.HistoryPart{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("history_id = ?", id).Delete(&database.ViewSnapshot{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("history_id = ?", id).Delete(&database.PageRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("history_id = ?", id).Delete(&database.LinkIssue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&database.HistoryEntry{}, id).Error
+	})
+}
+
+// All возвращает всю историю без частей (для фоновых задач)
+func (r *historyRepo) All() ([]database.HistoryItem, error) {
+	var dbItems []database.HistoryEntry
+	if err := r.db.Order("id asc").Find(&dbItems).Error; err != nil {
+		return nil, err
+	}
+	result := make([]database.HistoryItem, len(dbItems))
+	for i, item := range dbItems {
+		result[i] = toHistoryItem(item)
+	}
+	return result, nil
+}
+
+// KnownURLs — адреса всех страниц в истории, включая части разбитых глав
+func (r *historyRepo) KnownURLs() (map[string]bool, error) {
+	var urls []string
+	if err := r.db.Model(&database.HistoryEntry{}).Pluck("url", &urls).Error; err != nil {
+		return nil, err
+	}
+	var partURLs []string
+	if err := r.db.Model(&database.HistoryPart{}).Pluck("url", &partURLs).Error; err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(urls)+len(partURLs))
+	for _, u := range append(urls, partURLs...) {
+		known[u] = true
+	}
+	return known, nil
+}
+
+func toHistoryItem(item database.HistoryEntry) database.HistoryItem {
 	return database.HistoryItem{
 		ID:        item.ID,
 		Date:      item.CreatedAt.Format("2006-01-02 15:04:05"),
@@ -71,9 +171,34 @@ func (r *historyRepo) GetByID(id uint) (database.HistoryItem, error) {
 		ImgCount:  item.ImgCount,
 		TgphToken: item.TgphToken,
 		TitleID:   item.TitleID,
-	}, nil
+		Parts:     item.Parts,
+	}
+}
+
+// AddParts сохраняет части разбитой главы под одной записью истории
+func (r *historyRepo) AddParts(historyID uint, parts []database.HistoryPart) error {
+	if len(parts) == 0 {
+		return nil
+	}
+	for i := range parts {
+		parts[i].HistoryID = historyID
+	}
+	return r.db.Create(&parts).Error
 }
 
 func (r *historyRepo) Clear() error {
+	// SQLite не каскадирует удаление без PRAGMA foreign_keys, чистим части явно
+	if err := r.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&database.HistoryPart{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&database.ViewSnapshot{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&database.PageRevision{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&database.LinkIssue{}).Error; err != nil {
+		return err
+	}
 	return r.db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&database.HistoryEntry{}).Error
 }
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"telegraph_uploader_v2/internal/database"
+
+	"gorm.io/gorm"
+)
+
+type LinkRepository interface {
+	// ReplaceForHistory заменяет результаты прошлой проверки главы новыми
+	ReplaceForHistory(historyID uint, issues []database.LinkIssue) error
+	ForHistory(historyID uint) ([]database.LinkIssue, error)
+	All() ([]database.LinkIssue, error)
+}
+
+type linkRepo struct {
+	db *gorm.DB
+}
+
+func NewLinkRepository(db *gorm.DB) LinkRepository {
+	return &linkRepo{db: db}
+}
+
+func (r *linkRepo) ReplaceForHistory(historyID uint, issues []database.LinkIssue) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("history_id = ?", historyID).Delete(&database.LinkIssue{}).Error; err != nil {
+			return err
+		}
+		if len(issues) == 0 {
+			return nil
+		}
+		for i := range issues {
+			issues[i].HistoryID = historyID
+		}
+		return tx.Create(&issues).Error
+	})
+}
+
+func (r *linkRepo) ForHistory(historyID uint) ([]database.LinkIssue, error) {
+	var issues []database.LinkIssue
+	err := r.db.Where("history_id = ?", historyID).Order("id asc").Find(&issues).Error
+	return issues, err
+}
+
+func (r *linkRepo) All() ([]database.LinkIssue, error) {
+	var issues []database.LinkIssue
+	err := r.db.Order("history_id desc, id asc").Find(&issues).Error
+	return issues, err
+}
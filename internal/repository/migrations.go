@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"telegraph_uploader_v2/internal/database"
+
+	"gorm.io/gorm"
+)
+
+type MigrationRepository interface {
+	Create(m *database.DomainMigration) error
+	Update(m *database.DomainMigration) error
+	// FindUnfinished — незавершённый перенос с теми же префиксами (для продолжения)
+	FindUnfinished(oldPrefix, newPrefix string) (database.DomainMigration, error)
+	GetAll() ([]database.DomainMigration, error)
+}
+
+type migrationRepo struct {
+	db *gorm.DB
+}
+
+func NewMigrationRepository(db *gorm.DB) MigrationRepository {
+	return &migrationRepo{db: db}
+}
+
+func (r *migrationRepo) Create(m *database.DomainMigration) error {
+	return r.db.Create(m).Error
+}
+
+func (r *migrationRepo) Update(m *database.DomainMigration) error {
+	return r.db.Save(m).Error
+}
+
+func (r *migrationRepo) FindUnfinished(oldPrefix, newPrefix string) (database.DomainMigration, error) {
+	var m database.DomainMigration
+	err := r.db.Where("old_prefix = ? AND new_prefix = ? AND status <> ?", oldPrefix, newPrefix, database.MigrationDone).
+		Order("id desc").First(&m).Error
+	return m, err
+}
+
+func (r *migrationRepo) GetAll() ([]database.DomainMigration, error) {
+	var ms []database.DomainMigration
+	err := r.db.Order("id desc").Find(&ms).Error
+	return ms, err
+}
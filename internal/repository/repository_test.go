@@ -16,7 +16,7 @@ func setupTestDB(t testing.TB) *gorm.DB {
 		t.Fatalf("failed to connect database: %v", err)
 	}
 
-	err = db.AutoMigrate(&database.Settings{}, &database.HistoryEntry{}, &database.Title{}, &database.TitleFolder{}, &database.TitleVariable{}, &database.Template{}, &database.UploadedFile{})
+	err = db.AutoMigrate(&database.Settings{}, &database.HistoryEntry{}, &database.Title{}, &database.TitleFolder{}, &database.TitleVariable{}, &database.Template{}, &database.UploadedFile{}, &database.HistoryPart{}, &database.TelegraphAccount{}, &database.ViewSnapshot{}, &database.PageRevision{}, &database.LinkIssue{}, &database.DomainMigration{})
 	if err != nil {
 		t.Fatalf("failed to migrate database: %v", err)
 	}
@@ -86,6 +86,54 @@ func TestHistoryRepo(t *testing.T) {
 		t.Errorf("expected newest first, got %s", items[0].Title)
 	}
 
+	// FindByPath / UpdateTitle
+	_, err = repo.Add("T3", "https://telegra.ph/Page-3", 1, "tok", nil)
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	found, err := repo.FindByPath("Page-3")
+	if err != nil || found.Title != "T3" {
+		t.Fatalf("FindByPath failed: %v %+v", err, found)
+	}
+	if err := repo.AddParts(found.ID, []database.HistoryPart{{Part: 1, Url: "https://telegra.ph/Page-3"}, {Part: 2, Url: "https://telegra.ph/Page-3-part"}}); err != nil {
+		t.Fatalf("AddParts failed: %v", err)
+	}
+	if part, err := repo.FindByPath("Page-3-part"); err != nil || part.ID != found.ID || len(part.Parts) != 2 {
+		t.Fatalf("FindByPath must find the chapter by its part: %v %+v", err, part)
+	}
+	if err := repo.UpdateTitle(found.ID, "T3 edited"); err != nil {
+		t.Fatalf("UpdateTitle failed: %v", err)
+	}
+	if item, _ := repo.GetByID(found.ID); item.Title != "T3 edited" {
+		t.Errorf("expected updated title, got %s", item.Title)
+	}
+
+	// Revisions
+	revisions := NewRevisionRepository(db)
+	for _, reason := range []string{"create", "edit"} {
+		if err := revisions.Add(&database.PageRevision{HistoryID: found.ID, Path: "Page-3", Title: "T3", Content: "[]", Reason: reason}); err != nil {
+			t.Fatalf("Add revision failed: %v", err)
+		}
+	}
+	revs, err := revisions.ForHistory(found.ID)
+	if err != nil || len(revs) != 2 || revs[0].Reason != "edit" {
+		t.Fatalf("expected newest revision first: %v %+v", err, revs)
+	}
+	if rev, err := revisions.GetByID(revs[1].ID); err != nil || rev.Content != "[]" {
+		t.Errorf("GetByID failed: %v %+v", err, rev)
+	}
+
+	// Delete
+	if err := repo.Delete(found.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := repo.FindByPath("Page-3"); err == nil {
+		t.Error("expected deleted item to be gone")
+	}
+	if revs, _ := revisions.ForHistory(found.ID); len(revs) != 0 {
+		t.Errorf("expected revisions deleted with history item, got %d", len(revs))
+	}
+
 	// Clear
 	err = repo.Clear()
 	if err != nil {
@@ -183,6 +231,39 @@ func TestTemplateRepo(t *testing.T) {
 	}
 }
 
+func TestTemplateRepo_PageTemplates(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewTemplateRepository(db)
+	titles := NewTitleRepository(db)
+
+	if err := repo.Create("Post", "{{Title}}"); err != nil {
+		t.Fatal(err)
+	}
+	page := database.Template{Name: "Footer", Content: "[Канал](https://t.me/chan)", Kind: database.TemplatePage, Format: "markdown"}
+	if err := repo.Add(&page); err != nil {
+		t.Fatal(err)
+	}
+	if posts, _ := repo.GetByKind(database.TemplatePost); len(posts) != 1 || posts[0].Name != "Post" {
+		t.Errorf("unexpected post templates: %+v", posts)
+	}
+	if pages, _ := repo.GetByKind(database.TemplatePage); len(pages) != 1 || pages[0].ID != page.ID {
+		t.Errorf("unexpected page templates: %+v", pages)
+	}
+
+	titles.Create("Title", "/tmp/title")
+	all, _ := titles.GetAll()
+	if err := titles.SetPageTemplates(all[0].ID, &page.ID, &page.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(page.ID); err != nil {
+		t.Fatal(err)
+	}
+	title, _ := titles.GetByID(all[0].ID)
+	if title.HeaderTemplateID != nil || title.FooterTemplateID != nil {
+		t.Errorf("deleted template must be unlinked from title: %+v", title)
+	}
+}
+
 func TestImageCacheRepo(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewImageCacheRepository(db)
@@ -197,7 +278,7 @@ func TestImageCacheRepo(t *testing.T) {
 	}
 
 	// Test Save
-	err := repo.Save(hash, url)
+	err := repo.Save(hash, url, "/manga/ch1/001.png")
 	if err != nil {
 		t.Fatalf("Save failed: %v", err)
 	}
@@ -213,7 +294,7 @@ func TestImageCacheRepo(t *testing.T) {
 
 	// Test Save update
 	newURL := "https://example.com/new-image.jpg"
-	err = repo.Save(hash, newURL)
+	err = repo.Save(hash, newURL, "/manga/ch1/001.png")
 	if err != nil {
 		t.Fatalf("Save update failed: %v", err)
 	}
@@ -225,4 +306,26 @@ func TestImageCacheRepo(t *testing.T) {
 	if gotURL != newURL {
 		t.Errorf("expected %s, got %s", newURL, gotURL)
 	}
+
+	// Test FindByURL
+	entry, found := repo.FindByURL(newURL)
+	if !found || entry.Hash != hash || entry.SourcePath != "/manga/ch1/001.png" {
+		t.Errorf("FindByURL failed: %v %+v", found, entry)
+	}
+	if _, found := repo.FindByURL(url); found {
+		t.Error("replaced url must not be found")
+	}
+
+	// Test ReplacePrefix
+	repo.Save("other", "https://other.com/x.jpg", "")
+	n, err := repo.ReplacePrefix("https://example.com/", "https://cdn.example.org/img/")
+	if err != nil || n != 1 {
+		t.Fatalf("ReplacePrefix failed: %v (%d rows)", err, n)
+	}
+	if gotURL, _ := repo.GetURL(hash); gotURL != "https://cdn.example.org/img/new-image.jpg" {
+		t.Errorf("unexpected migrated url %s", gotURL)
+	}
+	if gotURL, _ := repo.GetURL("other"); gotURL != "https://other.com/x.jpg" {
+		t.Errorf("other domains must stay, got %s", gotURL)
+	}
 }
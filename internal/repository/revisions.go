@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"telegraph_uploader_v2/internal/database"
+
+	"gorm.io/gorm"
+)
+
+type RevisionRepository interface {
+	Add(rev *database.PageRevision) error
+	ForHistory(historyID uint) ([]database.PageRevision, error)
+	GetByID(id uint) (database.PageRevision, error)
+}
+
+type revisionRepo struct {
+	db *gorm.DB
+}
+
+func NewRevisionRepository(db *gorm.DB) RevisionRepository {
+	return &revisionRepo{db: db}
+}
+
+func (r *revisionRepo) Add(rev *database.PageRevision) error {
+	return r.db.Create(rev).Error
+}
+
+// ForHistory — ревизии всех страниц главы, новые первыми
+func (r *revisionRepo) ForHistory(historyID uint) ([]database.PageRevision, error) {
+	var revs []database.PageRevision
+	err := r.db.Where("history_id = ?", historyID).Order("id desc").Find(&revs).Error
+	return revs, err
+}
+
+func (r *revisionRepo) GetByID(id uint) (database.PageRevision, error) {
+	var rev database.PageRevision
+	err := r.db.First(&rev, id).Error
+	return rev, err
+}
@@ -8,7 +8,9 @@ import (
 
 type TemplateRepository interface {
 	Create(name, content string) error
+	Add(t *database.Template) error
 	GetAll() ([]database.Template, error)
+	GetByKind(kind string) ([]database.Template, error)
 	GetByID(id uint) (database.Template, error)
 	Update(t database.Template) error
 	Delete(id uint) error
@@ -26,12 +28,24 @@ func (r *templateRepo) Create(name, content string) error {
 	return r.db.Create(&database.Template{Name: name, Content: content}).Error
 }
 
+// Add создаёт шаблон с заданными видом и форматом
+func (r *templateRepo) Add(t *database.Template) error {
+	return r.db.Create(t).Error
+}
+
 func (r *templateRepo) GetAll() ([]database.Template, error) {
 	var t []database.Template
 	err := r.db.Find(&t).Error
 	return t, err
 }
 
+// GetByKind — шаблоны одного вида (TemplatePost или TemplatePage)
+func (r *templateRepo) GetByKind(kind string) ([]database.Template, error) {
+	var t []database.Template
+	err := r.db.Where("kind = ?", kind).Order("name").Find(&t).Error
+	return t, err
+}
+
 func (r *templateRepo) GetByID(id uint) (database.Template, error) {
 	var t database.Template
 	err := r.db.First(&t, id).Error
@@ -42,6 +56,14 @@ func (r *templateRepo) Update(t database.Template) error {
 	return r.db.Save(&t).Error
 }
 
+// Delete удаляет шаблон и отвязывает его от тайтлов, где он был шапкой или подвалом
 func (r *templateRepo) Delete(id uint) error {
-	return r.db.Delete(&database.Template{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, column := range []string{"header_template_id", "footer_template_id"} {
+			if err := tx.Model(&database.Title{}).Where(column+" = ?", id).Update(column, nil).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&database.Template{}, id).Error
+	})
 }
@@ -15,6 +15,12 @@ type TitleRepository interface {
 	Delete(id uint) error
 	AddVariable(titleID uint, key, value string) error
 	FindByPath(path string) (database.Title, error)
+	UpdateOrdering(titleID uint, mode string, reverse bool, pattern string) error
+	UpdateSpreadMode(titleID uint, mode string) error
+	UpdateIndexSettings(titleID uint, enabled bool, coverURL, description string) error
+	SetIndexPage(titleID uint, path, url, token string) error
+	SetAccount(titleID uint, accountID *uint) error
+	SetPageTemplates(titleID uint, headerID, footerID *uint) error
 }
 
 type titleRepo struct {
@@ -90,3 +96,43 @@ func (r *titleRepo) FindByPath(path string) (database.Title, error) {
 
 	return r.GetByID(folder.TitleID)
 }
+
+func (r *titleRepo) UpdateOrdering(titleID uint, mode string, reverse bool, pattern string) error {
+	return r.db.Model(&database.Title{}).Where("id = ?", titleID).Updates(map[string]interface{}{
+		"sort_mode":    mode,
+		"sort_reverse": reverse,
+		"sort_pattern": pattern,
+	}).Error
+}
+
+func (r *titleRepo) UpdateIndexSettings(titleID uint, enabled bool, coverURL, description string) error {
+	return r.db.Model(&database.Title{}).Where("id = ?", titleID).Updates(map[string]interface{}{
+		"index_enabled": enabled,
+		"cover_url":     coverURL,
+		"description":   description,
+	}).Error
+}
+
+func (r *titleRepo) SetIndexPage(titleID uint, path, url, token string) error {
+	return r.db.Model(&database.Title{}).Where("id = ?", titleID).Updates(map[string]interface{}{
+		"index_path":  path,
+		"index_url":   url,
+		"index_token": token,
+	}).Error
+}
+
+func (r *titleRepo) SetAccount(titleID uint, accountID *uint) error {
+	return r.db.Model(&database.Title{}).Where("id = ?", titleID).Update("account_id", accountID).Error
+}
+
+// SetPageTemplates задаёт шаблоны шапки и подвала глав тайтла (nil — без них)
+func (r *titleRepo) SetPageTemplates(titleID uint, headerID, footerID *uint) error {
+	return r.db.Model(&database.Title{}).Where("id = ?", titleID).Updates(map[string]interface{}{
+		"header_template_id": headerID,
+		"footer_template_id": footerID,
+	}).Error
+}
+
+func (r *titleRepo) UpdateSpreadMode(titleID uint, mode string) error {
+	return r.db.Model(&database.Title{}).Where("id = ?", titleID).Update("spread_mode", mode).Error
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"time"
+
+	"telegraph_uploader_v2/internal/database"
+
+	"gorm.io/gorm"
+)
+
+type ViewsRepository interface {
+	AddSnapshots(snaps []database.ViewSnapshot) error
+	ForHistory(historyID uint, since time.Time) ([]database.ViewPoint, error)
+	ForTitle(titleID uint, since time.Time) ([]database.ViewPoint, error)
+	Latest() (map[uint]int, error)
+}
+
+type viewsRepo struct {
+	db *gorm.DB
+}
+
+func NewViewsRepository(db *gorm.DB) ViewsRepository {
+	return &viewsRepo{db: db}
+}
+
+func (r *viewsRepo) AddSnapshots(snaps []database.ViewSnapshot) error {
+	if len(snaps) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(&snaps, 200).Error
+}
+
+// ForHistory — ряд снимков одной главы, от старых к новым
+func (r *viewsRepo) ForHistory(historyID uint, since time.Time) ([]database.ViewPoint, error) {
+	var points []database.ViewPoint
+	err := r.db.Model(&database.ViewSnapshot{}).
+		Select("taken_at, total, day, hour").
+		Where("history_id = ? AND taken_at >= ?", historyID, since).
+		Order("taken_at asc").Scan(&points).Error
+	return points, err
+}
+
+// ForTitle — суммарные просмотры глав тайтла по проходам сборщика
+func (r *viewsRepo) ForTitle(titleID uint, since time.Time) ([]database.ViewPoint, error) {
+	var points []database.ViewPoint
+	err := r.db.Model(&database.ViewSnapshot{}).
+		Select("taken_at, SUM(total) AS total, SUM(day) AS day, SUM(hour) AS hour").
+		Where("title_id = ? AND taken_at >= ?", titleID, since).
+		Group("taken_at").Order("taken_at asc").Scan(&points).Error
+	return points, err
+}
+
+// Latest — последнее известное число просмотров каждой главы (history_id -> total)
+func (r *viewsRepo) Latest() (map[uint]int, error) {
+	var rows []database.ViewSnapshot
+	err := r.db.Raw(`SELECT s.history_id, s.total FROM view_snapshots s
+		JOIN (SELECT history_id, MAX(taken_at) AS taken_at FROM view_snapshots GROUP BY history_id) l
+		ON s.history_id = l.history_id AND s.taken_at = l.taken_at`).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		latest[row.HistoryID] = row.Total
+	}
+	return latest, nil
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"image/png"
 	"io"
 	"log"
 	"net/http"
@@ -8,6 +9,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"telegraph_uploader_v2/internal/imageformat"
 )
 
 // FileLoader обрабатывает запросы на получение локальных изображений
@@ -56,14 +59,8 @@ func (h *FileLoader) ServeHTTP(res http.ResponseWriter, req *http.Request) {
     }
 
     // Проверка расширения (только изображения)
-    ext := strings.ToLower(filepath.Ext(cleanedPath))
-    allowedExtensions := map[string]bool{
-        ".jpg":  true,
-        ".jpeg": true,
-        ".png":  true,
-        ".webp": true,
-    }
-    if !allowedExtensions[ext] {
+    format, ok := imageformat.Lookup(cleanedPath)
+    if !ok {
         http.Error(res, "Invalid file type", http.StatusForbidden)
         log.Printf("Blocked attempt to access non-image file: %s", cleanedPath)
         return
@@ -97,18 +94,15 @@ func (h *FileLoader) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	log.Printf("Opened file for thumbnail: %s", cleanedPath)
 
     // 3. Отдаем файл
-    contentType := "application/octet-stream"
-    switch ext {
-    case ".jpg", ".jpeg":
-        contentType = "image/jpeg"
-    case ".png":
-        contentType = "image/png"
-    case ".webp":
-        contentType = "image/webp"
+    res.Header().Set("Cache-Control", "public, max-age=3600")
+
+    // Форматы, которые WebView не покажет сам (TIFF, JPEG XL), перекодируем в PNG
+    if !format.Native {
+        serveTranscoded(res, file, cleanedPath)
+        return
     }
 
-    res.Header().Set("Content-Type", contentType)
-    res.Header().Set("Cache-Control", "public, max-age=3600")
+    res.Header().Set("Content-Type", format.ContentType)
     log.Printf("Sending thumbnail file: %s", cleanedPath)
     // Копируем содержимое файла напрямую в response
     if _, err := io.Copy(res, file); err != nil {
@@ -117,3 +111,24 @@ func (h *FileLoader) ServeHTTP(res http.ResponseWriter, req *http.Request) {
     }
 	log.Printf("Served thumbnail: %s", cleanedPath)
 }
+
+// serveTranscoded декодирует файл и отдает его как PNG
+func serveTranscoded(res http.ResponseWriter, file *os.File, path string) {
+    data, err := io.ReadAll(file)
+    if err != nil {
+        http.Error(res, "Failed to read file", http.StatusInternalServerError)
+        log.Printf("Error reading file %s: %v", path, err)
+        return
+    }
+    img, _, err := imageformat.Decode(data)
+    if err != nil {
+        http.Error(res, "Failed to decode image", http.StatusUnsupportedMediaType)
+        log.Printf("Error decoding file %s: %v", path, err)
+        return
+    }
+    res.Header().Set("Content-Type", "image/png")
+    if err := png.Encode(res, img); err != nil {
+        log.Printf("Error encoding preview %s: %v", path, err)
+    }
+    log.Printf("Served transcoded thumbnail: %s", path)
+}
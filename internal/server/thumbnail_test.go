@@ -2,12 +2,16 @@ package server
 
 import (
 	"errors"
+	"image"
+	"image/png"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"strings"
 	"testing"
+
+	"golang.org/x/image/tiff"
 )
 
 func TestFileLoader_ServeHTTP(t *testing.T) {
@@ -141,6 +145,9 @@ func TestFileLoader_Extensions(t *testing.T) {
 		{"Allowed JPG", "test.jpg", http.StatusNotFound}, // 404 because file doesn't exist, but passed extension check
 		{"Allowed PNG", "test.png", http.StatusNotFound},
 		{"Allowed WEBP", "test.webp", http.StatusNotFound},
+		{"Allowed GIF", "test.gif", http.StatusNotFound},
+		{"Allowed BMP", "test.bmp", http.StatusNotFound},
+		{"Allowed TIFF", "test.tiff", http.StatusNotFound},
 		{"Disallowed TXT", "test.txt", http.StatusForbidden},
 		{"Disallowed No Ext", "testfile", http.StatusForbidden},
 		{"Disallowed PHP", "script.php", http.StatusForbidden},
@@ -222,3 +229,35 @@ func TestFileLoader_PathTraversal(t *testing.T) {
 		})
 	}
 }
+
+func TestFileLoader_TranscodesTIFF(t *testing.T) {
+	handler := NewFileLoader()
+
+	tmpFile, err := os.CreateTemp("", "thumb*.tif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if err := tiff.Encode(tmpFile, image.NewRGBA(image.Rect(0, 0, 8, 6)), nil); err != nil {
+		t.Fatal(err)
+	}
+	tmpFile.Close()
+
+	req, _ := http.NewRequest("GET", "/thumbnail/"+tmpFile.Name(), nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("expected image/png, got %s", ct)
+	}
+	img, err := png.Decode(rr.Body)
+	if err != nil {
+		t.Fatalf("response is not a PNG: %v", err)
+	}
+	if img.Bounds().Dx() != 8 || img.Bounds().Dy() != 6 {
+		t.Errorf("unexpected size %v", img.Bounds())
+	}
+}
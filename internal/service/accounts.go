@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+// AccountService управляет аккаунтами Telegraph: хранит их в БД, подставляет
+// токен по умолчанию в клиент и перепривязывает историю при отзыве токена.
+type AccountService struct {
+	tgClient *telegraph.Client
+	accounts repository.AccountRepository
+}
+
+func NewAccountService(tg *telegraph.Client, accounts repository.AccountRepository) *AccountService {
+	return &AccountService{tgClient: tg, accounts: accounts}
+}
+
+// Init связывает клиент с сохранёнными аккаунтами. Публикует аккаунт по умолчанию из БД —
+// иначе выбор основного аккаунта и отзыв токена не переживали бы перезапуск.
+// Новый токен в конфиге считается явной сменой аккаунта: он сохраняется и становится основным.
+// Если токен конфига уже сохранён, но основной другой, это только логируется.
+// Аккаунт, созданный клиентом автоматически, сохраняется и становится основным.
+func (s *AccountService) Init() error {
+	s.tgClient.OnAccountCreated = func(acc *telegraph.Account) {
+		if _, err := s.save(acc); err != nil {
+			log.Printf("[Accounts] Failed to save created account: %v", err)
+		} else {
+			log.Printf("[Accounts] Created Telegraph account %s saved", acc.ShortName)
+		}
+	}
+
+	if token := s.tgClient.AccessToken(); token != "" {
+		if err := s.applyConfigToken(token); err != nil {
+			return err
+		}
+	}
+
+	if def, err := s.accounts.GetDefault(); err == nil {
+		s.tgClient.SetToken(def.AccessToken)
+	}
+	return nil
+}
+
+// applyConfigToken сверяет токен из конфига с сохранёнными аккаунтами
+func (s *AccountService) applyConfigToken(token string) error {
+	if acc, err := s.accounts.GetByToken(token); err == nil {
+		if def, err := s.accounts.GetDefault(); err == nil && def.ID != acc.ID {
+			log.Printf("[Accounts] Config Telegraph token belongs to account %s, but %s is the default and publishes; change the default account in the app", acc.ShortName, def.ShortName)
+		}
+		return nil
+	}
+	if acc, err := s.accounts.GetByRevokedToken(token); err == nil {
+		log.Printf("[Accounts] Warning: config Telegraph token was revoked, using account %s instead; update TELEGRAPH_TOKEN", acc.ShortName)
+		return nil
+	}
+
+	rec, err := s.save(&telegraph.Account{ShortName: telegraph.DefaultShortName, AccessToken: token})
+	if err != nil {
+		return err
+	}
+	if !rec.IsDefault {
+		if err := s.accounts.SetDefault(rec.ID); err != nil {
+			return err
+		}
+		log.Printf("[Accounts] New config Telegraph token saved as the default account")
+	}
+	return nil
+}
+
+// save сохраняет аккаунт; первый аккаунт становится основным
+func (s *AccountService) save(acc *telegraph.Account) (database.TelegraphAccount, error) {
+	rec := database.TelegraphAccount{
+		ShortName:   acc.ShortName,
+		AuthorName:  acc.AuthorName,
+		AuthorURL:   acc.AuthorURL,
+		AccessToken: acc.AccessToken,
+		AuthURL:     acc.AuthURL,
+		PageCount:   acc.PageCount,
+	}
+	if _, err := s.accounts.GetDefault(); err != nil {
+		rec.IsDefault = true
+	}
+	err := s.accounts.Create(&rec)
+	return rec, err
+}
+
+// Create создаёт новый аккаунт в Telegraph и сохраняет его
+func (s *AccountService) Create(ctx context.Context, shortName, authorName, authorURL string) (database.TelegraphAccount, error) {
+	acc, err := s.tgClient.CreateAccount(ctx, shortName, authorName, authorURL)
+	if err != nil {
+		return database.TelegraphAccount{}, err
+	}
+	rec, err := s.save(acc)
+	if err == nil && rec.IsDefault && s.tgClient.AccessToken() == "" {
+		s.tgClient.SetToken(rec.AccessToken)
+	}
+	return rec, err
+}
+
+// Import добавляет существующий аккаунт по токену
+func (s *AccountService) Import(ctx context.Context, token string) (database.TelegraphAccount, error) {
+	if _, err := s.accounts.GetByToken(token); err == nil {
+		return database.TelegraphAccount{}, fmt.Errorf("аккаунт с этим токеном уже добавлен")
+	}
+	acc, err := s.tgClient.GetAccountInfo(ctx, token)
+	if err != nil {
+		return database.TelegraphAccount{}, err
+	}
+	acc.AccessToken = token
+	return s.save(acc)
+}
+
+// Refresh запрашивает getAccountInfo и обновляет сохранённые данные
+func (s *AccountService) Refresh(ctx context.Context, id uint) (database.TelegraphAccount, error) {
+	rec, err := s.accounts.GetByID(id)
+	if err != nil {
+		return rec, err
+	}
+	acc, err := s.tgClient.GetAccountInfo(ctx, rec.AccessToken)
+	if err != nil {
+		return rec, err
+	}
+	rec.ShortName, rec.AuthorName, rec.AuthorURL = acc.ShortName, acc.AuthorName, acc.AuthorURL
+	rec.AuthURL, rec.PageCount = acc.AuthURL, acc.PageCount
+	return rec, s.accounts.Update(rec)
+}
+
+// Edit меняет имя и автора аккаунта через editAccountInfo
+func (s *AccountService) Edit(ctx context.Context, id uint, shortName, authorName, authorURL string) (database.TelegraphAccount, error) {
+	rec, err := s.accounts.GetByID(id)
+	if err != nil {
+		return rec, err
+	}
+	acc, err := s.tgClient.EditAccountInfo(ctx, rec.AccessToken, shortName, authorName, authorURL)
+	if err != nil {
+		return rec, err
+	}
+	rec.ShortName, rec.AuthorName, rec.AuthorURL = acc.ShortName, acc.AuthorName, acc.AuthorURL
+	return rec, s.accounts.Update(rec)
+}
+
+// Revoke отзывает токен аккаунта. Записи истории и оглавления со старым токеном
+// перепривязываются к новому, иначе их страницы нельзя будет редактировать.
+// Возвращает обновлённый аккаунт и число перепривязанных глав.
+func (s *AccountService) Revoke(ctx context.Context, id uint) (database.TelegraphAccount, int64, error) {
+	rec, err := s.accounts.GetByID(id)
+	if err != nil {
+		return rec, 0, err
+	}
+	acc, err := s.tgClient.RevokeAccessToken(ctx, rec.AccessToken)
+	if err != nil {
+		return rec, 0, err
+	}
+
+	oldToken := rec.AccessToken
+	rebound, err := s.accounts.ReplaceToken(id, oldToken, acc.AccessToken)
+	if err != nil {
+		return rec, 0, fmt.Errorf("токен отозван, но не сохранён (новый токен: %s): %w", acc.AccessToken, err)
+	}
+	if s.tgClient.AccessToken() == oldToken {
+		s.tgClient.SetToken(acc.AccessToken)
+	}
+
+	rec.AccessToken, rec.RevokedToken = acc.AccessToken, oldToken
+	if acc.AuthURL != "" {
+		rec.AuthURL = acc.AuthURL
+	}
+	return rec, rebound, s.accounts.Update(rec)
+}
+
+// SetDefault делает аккаунт основным: им публикуются главы без своего аккаунта
+func (s *AccountService) SetDefault(id uint) error {
+	rec, err := s.accounts.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.accounts.SetDefault(id); err != nil {
+		return err
+	}
+	s.tgClient.SetToken(rec.AccessToken)
+	return nil
+}
+
+func (s *AccountService) GetAll() ([]database.TelegraphAccount, error) {
+	return s.accounts.GetAll()
+}
+
+// Delete удаляет аккаунт из приложения (в Telegraph он остаётся). Основной аккаунт удалить нельзя.
+func (s *AccountService) Delete(id uint) error {
+	rec, err := s.accounts.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if rec.IsDefault {
+		return fmt.Errorf("нельзя удалить основной аккаунт")
+	}
+	return s.accounts.Delete(id)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+func TestAccountService_PersistsAutoCreatedAccount(t *testing.T) {
+	_, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	accounts := repository.NewAccountRepository(db)
+	client := &telegraph.Client{BaseURL: ts.URL}
+	if err := NewAccountService(client, accounts).Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.CreatePage(context.Background(), "Глава 1", telegraph.ImageNodes([]string{"http://img/1"})); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	def, err := accounts.GetDefault()
+	if err != nil || def.AccessToken != client.AccessToken() {
+		t.Fatalf("auto-created account must be saved as default: %v %+v", err, def)
+	}
+
+	// После «перезапуска» токен берётся из БД, новый аккаунт не создаётся
+	restarted := &telegraph.Client{BaseURL: ts.URL}
+	if err := NewAccountService(restarted, accounts).Init(); err != nil {
+		t.Fatal(err)
+	}
+	if restarted.AccessToken() != def.AccessToken {
+		t.Errorf("expected token %q restored, got %q", def.AccessToken, restarted.AccessToken())
+	}
+}
+
+func TestAccountService_TitleAccountAndRevoke(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	titles := repository.NewTitleRepository(db)
+	accountsRepo := repository.NewAccountRepository(db)
+	client := &telegraph.Client{Token: "main", BaseURL: ts.URL}
+	accounts := NewAccountService(client, accountsRepo)
+	if err := accounts.Init(); err != nil {
+		t.Fatal(err)
+	}
+	pub := NewPublicationService(client, nil, history, titles, accountsRepo, nil, nil)
+
+	team, err := accounts.Create(context.Background(), "Team", "Команда перевода", "https://t.me/team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if team.IsDefault {
+		t.Error("config account must stay default")
+	}
+	titles.Create("Manga", "")
+	all, _ := titles.GetAll()
+	if err := titles.SetAccount(all[0].ID, &team.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	ch, err := pub.CreatePage(context.Background(), "Глава 1", []string{"http://img/1"}, int(all[0].ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := pagePath(ch.URL)
+	if fake.tokens[path] != team.AccessToken || fake.authors[path] != "Команда перевода" {
+		t.Errorf("page must be created by title account: token %q, author %q", fake.tokens[path], fake.authors[path])
+	}
+
+	revoked, rebound, err := accounts.Revoke(context.Background(), team.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebound != 1 || revoked.AccessToken != team.AccessToken+"-revoked" {
+		t.Errorf("unexpected revoke result: %d %+v", rebound, revoked)
+	}
+	if item, _ := history.GetByID(ch.HistoryID); item.TgphToken != revoked.AccessToken {
+		t.Errorf("history must be rebound to new token, got %q", item.TgphToken)
+	}
+	if client.AccessToken() != "main" {
+		t.Errorf("default token must not change, got %q", client.AccessToken())
+	}
+}
+
+func TestAccountService_DefaultSurvivesRestart(t *testing.T) {
+	_, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	accountsRepo := repository.NewAccountRepository(db)
+	client := &telegraph.Client{Token: "main", BaseURL: ts.URL}
+	accounts := NewAccountService(client, accountsRepo)
+	if err := accounts.Init(); err != nil {
+		t.Fatal(err)
+	}
+	team, err := accounts.Create(context.Background(), "Team", "Команда", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := accounts.SetDefault(team.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Токен в конфиге прежний, но основным остаётся выбранный аккаунт
+	restarted := &telegraph.Client{Token: "main", BaseURL: ts.URL}
+	if err := NewAccountService(restarted, accountsRepo).Init(); err != nil {
+		t.Fatal(err)
+	}
+	if restarted.AccessToken() != team.AccessToken {
+		t.Errorf("expected default account token %q after restart, got %q", team.AccessToken, restarted.AccessToken())
+	}
+
+	// Токен из конфига отозван: он не сохраняется заново как отдельный аккаунт
+	main, err := accountsRepo.GetByToken("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := accounts.Revoke(context.Background(), main.ID); err != nil {
+		t.Fatal(err)
+	}
+	restarted = &telegraph.Client{Token: "main", BaseURL: ts.URL}
+	if err := NewAccountService(restarted, accountsRepo).Init(); err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := accountsRepo.GetAll(); len(all) != 2 {
+		t.Errorf("revoked config token must not be saved again, got %d accounts", len(all))
+	}
+	if restarted.AccessToken() != team.AccessToken {
+		t.Errorf("expected default account token %q, got %q", team.AccessToken, restarted.AccessToken())
+	}
+
+	// Новый токен в конфиге — явная смена аккаунта, он становится основным
+	restarted = &telegraph.Client{Token: "fresh", BaseURL: ts.URL}
+	if err := NewAccountService(restarted, accountsRepo).Init(); err != nil {
+		t.Fatal(err)
+	}
+	if restarted.AccessToken() != "fresh" {
+		t.Errorf("changed config token must win, got %q", restarted.AccessToken())
+	}
+	if def, err := accountsRepo.GetDefault(); err != nil || def.AccessToken != "fresh" {
+		t.Errorf("changed config token must become the default account: %v %+v", err, def)
+	}
+}
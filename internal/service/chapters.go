@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+// Тексты ссылок между главами; по ним же находим абзац навигации на уже опубликованных страницах
+const (
+	prevChapterText = "← Предыдущая глава"
+	nextChapterText = "Следующая глава →"
+)
+
+var (
+	chapterKeywordRe = regexp.MustCompile(`(?i)(?:глава|гл\.?|chapter|ch\.?|эпизод|episode|ep\.?)\s*(\d+(?:[.,]\d+)?)`)
+	chapterLastNumRe = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\D*$`)
+)
+
+// chapterNumber достаёт номер главы из заголовка: сначала после «Глава»/«Chapter», иначе последнее число
+func chapterNumber(title string) (float64, bool) {
+	m := chapterKeywordRe.FindStringSubmatch(title)
+	if m == nil {
+		m = chapterLastNumRe.FindStringSubmatch(title)
+	}
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// chapterNeighbors находит соседние главы тайтла для новой главы с заголовком title.
+// Если номер главы не распознан, предыдущей считается последняя опубликованная.
+func (s *PublicationService) chapterNeighbors(titleID int, title string) (prev, next *database.HistoryItem) {
+	if titleID <= 0 || s.historyRepo == nil {
+		return nil, nil
+	}
+	items, err := s.historyRepo.GetByTitle(uint(titleID))
+	if err != nil || len(items) == 0 {
+		return nil, nil
+	}
+
+	num, ok := chapterNumber(title)
+	if !ok {
+		last := items[len(items)-1]
+		return &last, nil
+	}
+
+	var prevNum, nextNum float64
+	for i := range items {
+		n, ok := chapterNumber(items[i].Title)
+		if !ok {
+			continue
+		}
+		if n < num && (prev == nil || n >= prevNum) {
+			prev, prevNum = &items[i], n
+		}
+		if n > num && (next == nil || n < nextNum) {
+			next, nextNum = &items[i], n
+		}
+	}
+	return prev, next
+}
+
+// chapterNavigation — абзац со ссылками на соседние главы
+func chapterNavigation(prevURL, nextURL string) []telegraph.Node {
+	var links []telegraph.Node
+	if prevURL != "" {
+		links = append(links, telegraph.Link(prevURL, telegraph.Text(prevChapterText)))
+	}
+	if nextURL != "" {
+		if len(links) > 0 {
+			links = append(links, telegraph.Text(" | "))
+		}
+		links = append(links, telegraph.Link(nextURL, telegraph.Text(nextChapterText)))
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return []telegraph.Node{telegraph.Paragraph(links...)}
+}
+
+// chapterNavigationReserve — место под навигацию по главам в лимите страницы
+func chapterNavigationReserve() int {
+	placeholder := "https://telegra.ph/" + strings.Repeat("x", 200)
+	return telegraph.ContentSize(chapterNavigation(placeholder, placeholder))
+}
+
+// findChapterNavigation возвращает индекс абзаца навигации по главам и ссылки из него
+func findChapterNavigation(content []telegraph.Node) (idx int, prevURL, nextURL string) {
+	for i, n := range content {
+		if n.Tag != "p" {
+			continue
+		}
+		found := false
+		for _, c := range n.Children {
+			if c.Tag != "a" || len(c.Children) != 1 {
+				continue
+			}
+			switch c.Children[0].Text {
+			case prevChapterText:
+				prevURL, found = c.Attrs["href"], true
+			case nextChapterText:
+				nextURL, found = c.Attrs["href"], true
+			}
+		}
+		if found {
+			return i, prevURL, nextURL
+		}
+	}
+	return -1, "", ""
+}
+
+// withChapterNavigation заменяет (или добавляет в конец) абзац навигации по главам
+func withChapterNavigation(content []telegraph.Node, prevURL, nextURL string) []telegraph.Node {
+	result := make([]telegraph.Node, 0, len(content)+1)
+	if idx, _, _ := findChapterNavigation(content); idx >= 0 {
+		result = append(result, content[:idx]...)
+		result = append(result, content[idx+1:]...)
+	} else {
+		result = append(result, content...)
+	}
+	return append(result, chapterNavigation(prevURL, nextURL)...)
+}
+
+// relinkChapter обновляет навигацию на уже опубликованной странице соседней главы.
+// pageURL — какую страницу править (у разбитой главы: первую часть для «назад», последнюю для «вперёд»).
+func (s *PublicationService) relinkChapter(ctx context.Context, item database.HistoryItem, pageURL string, setPrev, setNext *string) error {
+	path := pagePath(pageURL)
+	page, err := s.tgClient.GetPageContent(ctx, path)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", path, err)
+	}
+
+	_, prevURL, nextURL := findChapterNavigation(page.Content)
+	if setPrev != nil {
+		prevURL = *setPrev
+	}
+	if setNext != nil {
+		nextURL = *setNext
+	}
+
+	token := item.TgphToken
+	if token == "" {
+		token = s.tgClient.AccessToken()
+	}
+	content := withChapterNavigation(page.Content, prevURL, nextURL)
+	if _, err := s.tgClient.EditPage(ctx, path, page.Title, content, token); err != nil {
+		return fmt.Errorf("edit %s: %w", path, err)
+	}
+	s.recordRevision(item.ID, path, page.Title, content, RevisionNavigation)
+	return nil
+}
+
+// linkNeighbors прописывает ссылки на новую главу у соседей. Ошибки не критичны — глава уже опубликована.
+func (s *PublicationService) linkNeighbors(ctx context.Context, newURL string, prev, next *database.HistoryItem) []string {
+	var warnings []string
+	if prev != nil {
+		if err := s.relinkChapter(ctx, *prev, lastPartURL(*prev), nil, &newURL); err != nil {
+			warnings = append(warnings, fmt.Sprintf("Не удалось добавить ссылку в «%s»: %v", prev.Title, err))
+		}
+	}
+	if next != nil {
+		if err := s.relinkChapter(ctx, *next, next.Url, &newURL, nil); err != nil {
+			warnings = append(warnings, fmt.Sprintf("Не удалось добавить ссылку в «%s»: %v", next.Title, err))
+		}
+	}
+	return warnings
+}
+
+// lastPartURL — последняя страница главы (для разбитых на части)
+func lastPartURL(item database.HistoryItem) string {
+	if len(item.Parts) > 0 {
+		return item.Parts[len(item.Parts)-1].Url
+	}
+	return item.Url
+}
+
+func itemURL(item *database.HistoryItem) string {
+	if item == nil {
+		return ""
+	}
+	return item.Url
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeTelegraph хранит страницы в памяти и реализует createPage/editPage/getPage
+// и методы аккаунта createAccount/revokeAccessToken/getPageList, а также getViews
+type fakeTelegraph struct {
+	mu      sync.Mutex
+	pages   map[string]*telegraph.Page
+	tokens  map[string]string // path -> access_token последней правки
+	authors map[string]string // path -> author_name при создании
+	fail    map[string]string // path -> ошибка API для getPage/editPage
+	n       int
+}
+
+func newFakeTelegraph(t *testing.T) (*fakeTelegraph, *httptest.Server) {
+	f := &fakeTelegraph{pages: map[string]*telegraph.Page{}, tokens: map[string]string{}, authors: map[string]string{}, fail: map[string]string{}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		if code := f.fail[r.FormValue("path")]; code != "" && r.URL.Path == "/editPage" {
+			fmt.Fprintf(w, `{"ok": false, "error": %q}`, code)
+			return
+		}
+		if code := f.fail[strings.TrimPrefix(r.URL.Path, "/getPage/")]; code != "" {
+			fmt.Fprintf(w, `{"ok": false, "error": %q}`, code)
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/createPage" || r.URL.Path == "/editPage":
+			var content []telegraph.Node
+			if err := json.Unmarshal([]byte(r.FormValue("content")), &content); err != nil {
+				t.Errorf("bad content: %v", err)
+			}
+			path := r.FormValue("path")
+			if r.URL.Path == "/createPage" {
+				f.n++
+				path = fmt.Sprintf("page-%d", f.n)
+				f.authors[path] = r.FormValue("author_name")
+			} else if f.pages[path] == nil {
+				w.Write([]byte(`{"ok": false, "error": "PAGE_NOT_FOUND"}`))
+				return
+			}
+			f.pages[path] = &telegraph.Page{Title: r.FormValue("title"), Content: content}
+			f.tokens[path] = r.FormValue("access_token")
+			fmt.Fprintf(w, `{"ok": true, "result": {"url": "https://telegra.ph/%s"}}`, path)
+		case strings.HasPrefix(r.URL.Path, "/getPage/"):
+			page := f.pages[strings.TrimPrefix(r.URL.Path, "/getPage/")]
+			if page == nil {
+				w.Write([]byte(`{"ok": false, "error": "PAGE_NOT_FOUND"}`))
+				return
+			}
+			data, _ := json.Marshal(map[string]interface{}{
+				"ok":     true,
+				"result": map[string]interface{}{"title": page.Title, "content": page.Content},
+			})
+			w.Write(data)
+		case r.URL.Path == "/createAccount":
+			f.n++
+			fmt.Fprintf(w, `{"ok": true, "result": {"short_name": %q, "author_name": %q, "access_token": "tok-%d"}}`,
+				r.FormValue("short_name"), r.FormValue("author_name"), f.n)
+		case r.URL.Path == "/getPageList":
+			// Новые страницы первыми, как в настоящем API
+			var owned []map[string]interface{}
+			for i := f.n; i >= 1; i-- {
+				path := fmt.Sprintf("page-%d", i)
+				if f.pages[path] != nil && f.tokens[path] == r.FormValue("access_token") {
+					owned = append(owned, map[string]interface{}{
+						"path": path, "url": "https://telegra.ph/" + path, "title": f.pages[path].Title,
+					})
+				}
+			}
+			offset, _ := strconv.Atoi(r.FormValue("offset"))
+			limit, _ := strconv.Atoi(r.FormValue("limit"))
+			end := min(offset+limit, len(owned))
+			data, _ := json.Marshal(map[string]interface{}{
+				"ok":     true,
+				"result": map[string]interface{}{"total_count": len(owned), "pages": owned[min(offset, end):end]},
+			})
+			w.Write(data)
+		case r.URL.Path == "/getViews":
+			// Просмотры: 100 за всё время, дальше меньше с каждым уровнем разбивки
+			views := 100
+			for _, k := range []string{"year", "month", "day", "hour"} {
+				if r.FormValue(k) != "" {
+					views /= 2
+				}
+			}
+			if f.pages[r.FormValue("path")] == nil {
+				w.Write([]byte(`{"ok": false, "error": "PAGE_NOT_FOUND"}`))
+				return
+			}
+			fmt.Fprintf(w, `{"ok": true, "result": {"views": %d}}`, views)
+		case r.URL.Path == "/revokeAccessToken":
+			fmt.Fprintf(w, `{"ok": true, "result": {"access_token": "%s-revoked", "auth_url": "https://edit.telegra.ph/auth/x"}}`, r.FormValue("access_token"))
+		default:
+			w.Write([]byte(`{"ok": false, "error": "UNKNOWN_METHOD"}`))
+		}
+	}))
+	return f, ts
+}
+
+func (f *fakeTelegraph) page(url string) *telegraph.Page {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pages[pagePath(url)]
+}
+
+func setupHistoryDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&database.HistoryEntry{}, &database.HistoryPart{}, &database.Title{}, &database.TitleFolder{}, &database.TitleVariable{}, &database.TelegraphAccount{}, &database.ViewSnapshot{}, &database.PageRevision{}, &database.LinkIssue{}, &database.DomainMigration{}, &database.Template{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestChapterNumber(t *testing.T) {
+	tests := []struct {
+		title string
+		want  float64
+		ok    bool
+	}{
+		{"Глава 12", 12, true},
+		{"Том 2 Глава 15", 15, true},
+		{"Chapter 7.5: Extra", 7.5, true},
+		{"Гл. 3 — Начало 2", 3, true},
+		{"Ван Пис 1050", 1050, true},
+		{"Экстра 10,5", 10.5, true},
+		{"Пролог", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := chapterNumber(tt.title)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("chapterNumber(%q) = %v, %v; want %v, %v", tt.title, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWithChapterNavigation(t *testing.T) {
+	content := telegraph.NewBuilder().Images("http://a").Paragraph(telegraph.Text("Перевод: команда")).Nodes()
+
+	linked := withChapterNavigation(content, "https://telegra.ph/prev", "")
+	if len(linked) != 3 {
+		t.Fatalf("expected navigation appended, got %d nodes", len(linked))
+	}
+	idx, prevURL, nextURL := findChapterNavigation(linked)
+	if idx != 2 || prevURL != "https://telegra.ph/prev" || nextURL != "" {
+		t.Errorf("unexpected navigation: %d %q %q", idx, prevURL, nextURL)
+	}
+
+	// Повторная правка заменяет абзац, а не добавляет второй
+	relinked := withChapterNavigation(linked, prevURL, "https://telegra.ph/next")
+	if len(relinked) != 3 {
+		t.Fatalf("expected navigation replaced, got %d nodes", len(relinked))
+	}
+	if _, p, n := findChapterNavigation(relinked); p != prevURL || n != "https://telegra.ph/next" {
+		t.Errorf("unexpected links after relink: %q %q", p, n)
+	}
+	if relinked[1].Children[0].Text != "Перевод: команда" {
+		t.Error("other paragraphs must be preserved")
+	}
+}
+
+func TestCreatePage_LinksChapters(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	client := &telegraph.Client{Token: "current", BaseURL: ts.URL}
+	s := NewPublicationService(client, nil, history, repository.NewTitleRepository(db), nil, nil, nil)
+
+	ch1, err := s.CreatePage(context.Background(), "Глава 1", []string{"http://img/1"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Глава 1 создана другим токеном — правки должны идти с ним
+	db.Model(&database.HistoryEntry{}).Where("id = ?", ch1.HistoryID).Update("tgph_token", "old-token")
+
+	ch3, err := s.CreatePage(context.Background(), "Глава 3", []string{"http://img/3"}, 5)
+	if err != nil || len(ch3.Warnings) > 0 {
+		t.Fatalf("chapter 3: %v %v", err, ch3.Warnings)
+	}
+	if _, prevURL, _ := findChapterNavigation(fake.page(ch3.URL).Content); prevURL != ch1.URL {
+		t.Errorf("chapter 3 must link back to chapter 1, got %q", prevURL)
+	}
+	if _, _, nextURL := findChapterNavigation(fake.page(ch1.URL).Content); nextURL != ch3.URL {
+		t.Errorf("chapter 1 must link forward to chapter 3, got %q", nextURL)
+	}
+	if fake.tokens[pagePath(ch1.URL)] != "old-token" {
+		t.Errorf("previous chapter must be edited with its own token, got %q", fake.tokens[pagePath(ch1.URL)])
+	}
+
+	// Глава 2 встаёт между 1 и 3
+	ch2, err := s.CreatePage(context.Background(), "Глава 2", []string{"http://img/2"}, 5)
+	if err != nil || len(ch2.Warnings) > 0 {
+		t.Fatalf("chapter 2: %v %v", err, ch2.Warnings)
+	}
+	if _, p, n := findChapterNavigation(fake.page(ch2.URL).Content); p != ch1.URL || n != ch3.URL {
+		t.Errorf("chapter 2 links: %q %q", p, n)
+	}
+	if _, _, n := findChapterNavigation(fake.page(ch1.URL).Content); n != ch2.URL {
+		t.Errorf("chapter 1 must now link to chapter 2, got %q", n)
+	}
+	if _, p, n := findChapterNavigation(fake.page(ch3.URL).Content); p != ch2.URL || n != "" {
+		t.Errorf("chapter 3 links: %q %q", p, n)
+	}
+	if imgs := telegraph.Images(fake.page(ch1.URL).Content); len(imgs) != 1 || imgs[0] != "http://img/1" {
+		t.Errorf("images of chapter 1 must be preserved, got %v", imgs)
+	}
+
+	// Глава другого тайтла ни на что не ссылается
+	other, err := s.CreatePage(context.Background(), "Глава 2", []string{"http://img/x"}, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx, _, _ := findChapterNavigation(fake.page(other.URL).Content); idx != -1 {
+		t.Error("chapter of another title must not get navigation")
+	}
+}
+
+func TestCreatePage_NeighborEditFailureIsWarning(t *testing.T) {
+	_, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	// Глава, которой нет на сервере
+	id := uint(9)
+	history.Add("Глава 1", "https://telegra.ph/missing", 1, "t", &id)
+
+	s := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+	res, err := s.CreatePage(context.Background(), "Глава 2", []string{"http://img/2"}, 9)
+	if err != nil {
+		t.Fatalf("publication must succeed, got %v", err)
+	}
+	if len(res.Warnings) != 1 {
+		t.Errorf("expected one warning, got %v", res.Warnings)
+	}
+}
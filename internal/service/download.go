@@ -0,0 +1,190 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"telegraph_uploader_v2/internal/uploader"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// downloadConcurrency — сколько картинок главы скачивается одновременно
+const downloadConcurrency = 4
+
+// DownloadResult — итог скачивания главы
+type DownloadResult struct {
+	Title   string   `json:"title"`
+	Dir     string   `json:"dir"`
+	Files   []string `json:"files"`   // сохранённые картинки (пусто, если собран CBZ)
+	Archive string   `json:"archive"` // путь к CBZ
+	Failed  []string `json:"failed"`
+}
+
+// DownloadHistory скачивает все страницы главы из истории (у разбитой — все части по порядку)
+func (s *PublicationService) DownloadHistory(ctx context.Context, historyID uint, dir string, asCBZ bool, progress func(done, total int)) (DownloadResult, error) {
+	item, err := s.historyRepo.GetByID(historyID)
+	if err != nil {
+		return DownloadResult{}, err
+	}
+	return s.downloadPages(ctx, item.Title, historyPages(item), dir, asCBZ, progress)
+}
+
+// DownloadPage скачивает картинки страницы Telegraph по её адресу
+func (s *PublicationService) DownloadPage(ctx context.Context, pageURL string, dir string, asCBZ bool, progress func(done, total int)) (DownloadResult, error) {
+	return s.downloadPages(ctx, "", []string{pageURL}, dir, asCBZ, progress)
+}
+
+// downloadPages скачивает картинки страниц по порядку в dir под именами 001.jpg, 002.png…
+// (ширина номера — по числу картинок, не меньше трёх знаков). С asCBZ картинки
+// упаковываются в «<заголовок>.cbz» вместо отдельных файлов.
+// Нескачанные картинки попадают в Failed, их номера остаются пропущенными.
+// Пустой title берётся из первой страницы.
+func (s *PublicationService) downloadPages(ctx context.Context, title string, pageURLs []string, dir string, asCBZ bool, progress func(done, total int)) (DownloadResult, error) {
+	res := DownloadResult{Title: title, Dir: dir}
+	var images []string
+	for _, pageURL := range pageURLs {
+		pageTitle, srcs, err := s.GetPage(ctx, pageURL)
+		if err != nil {
+			return res, fmt.Errorf("get %s: %w", pagePath(pageURL), err)
+		}
+		if res.Title == "" {
+			res.Title = pageTitle
+		}
+		images = append(images, srcs...)
+	}
+	if len(images) == 0 {
+		return res, fmt.Errorf("no images on %s", strings.Join(pageURLs, ", "))
+	}
+
+	data := make([][]byte, len(images))
+	errs := make([]error, len(images))
+	var done int
+	var mu sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(downloadConcurrency)
+	for i, src := range images {
+		i, src := i, src
+		g.Go(func() error {
+			data[i], errs[i] = uploader.Download(gctx, absoluteImageURL(src))
+			if gctx.Err() != nil {
+				return gctx.Err()
+			}
+			if progress != nil {
+				mu.Lock()
+				done++
+				progress(done, len(images))
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return res, err
+	}
+
+	width := max(3, len(fmt.Sprint(len(images))))
+	names := make([]string, len(images))
+	for i, src := range images {
+		if errs[i] != nil {
+			res.Failed = append(res.Failed, fmt.Sprintf("%s: %v", src, errs[i]))
+			continue
+		}
+		names[i] = fmt.Sprintf("%0*d%s", width, i+1, imageExt(src, data[i]))
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return res, err
+	}
+	if asCBZ {
+		archive := filepath.Join(dir, safeFileName(res.Title)+".cbz")
+		if err := writeCBZ(archive, names, data); err != nil {
+			return res, err
+		}
+		res.Archive = archive
+		return res, nil
+	}
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, data[i], 0o644); err != nil {
+			return res, err
+		}
+		res.Files = append(res.Files, file)
+	}
+	return res, nil
+}
+
+// writeCBZ пишет картинки в zip без сжатия (картинки и так сжаты), в порядке номеров
+func writeCBZ(archive string, names []string, data [][]byte) (err error) {
+	f, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data[i]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// imageExt — расширение по адресу, а если его нет — по содержимому
+func imageExt(src string, data []byte) string {
+	if i := strings.IndexAny(src, "?#"); i >= 0 {
+		src = src[:i]
+	}
+	switch ext := strings.ToLower(path.Ext(src)); ext {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".avif":
+		return ext
+	}
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	}
+	return ".img"
+}
+
+// safeFileName убирает из заголовка символы, недопустимые в именах файлов
+func safeFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < 32 {
+			return '_'
+		}
+		return r
+	}, title)
+	name = strings.TrimSpace(strings.TrimRight(name, ". "))
+	if name == "" {
+		return "chapter"
+	}
+	return name
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+func TestDownloadPage(t *testing.T) {
+	_, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	var pngData bytes.Buffer
+	png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone.jpg":
+			http.NotFound(w, r)
+		case "/noext":
+			w.Write(pngData.Bytes())
+		default:
+			w.Write([]byte("data of " + r.URL.Path))
+		}
+	}))
+	defer images.Close()
+
+	history := repository.NewHistoryRepository(setupHistoryDB(t))
+	s := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+	ch, err := s.CreatePage(context.Background(), "Глава 1: Начало?", []string{
+		images.URL + "/a.webp", images.URL + "/noext", images.URL + "/gone.jpg", images.URL + "/b.JPG?v=1",
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	res, err := s.DownloadHistory(context.Background(), ch.HistoryID, dir, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "001.webp"), filepath.Join(dir, "002.png"), filepath.Join(dir, "004.jpg")}
+	if !reflect.DeepEqual(res.Files, want) || len(res.Failed) != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if data, _ := os.ReadFile(want[2]); string(data) != "data of /b.JPG" {
+		t.Errorf("files must keep page order, got %q", data)
+	}
+
+	cbzDir := t.TempDir()
+	res, err = s.DownloadPage(context.Background(), ch.URL, cbzDir, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Archive != filepath.Join(cbzDir, "Глава 1_ Начало_.cbz") || len(res.Files) != 0 {
+		t.Fatalf("unexpected archive result %+v", res)
+	}
+	zr, err := zip.OpenReader(res.Archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"001.webp", "002.png", "004.jpg"}) {
+		t.Errorf("unexpected archive entries %v", names)
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+// ImportResult — итог импорта страниц из аккаунтов Telegraph
+type ImportResult struct {
+	Imported int      `json:"imported"`
+	Skipped  int      `json:"skipped"`
+	Matched  int      `json:"matched"`
+	Errors   []string `json:"errors"`
+}
+
+// ImportPages забирает через getPageList страницы всех известных токенов (аккаунты из БД и токен клиента)
+// и добавляет в историю те, которых там ещё нет. Страницы сопоставляются с тайтлами по названию.
+// progress вызывается после каждой обработанной страницы.
+func (s *PublicationService) ImportPages(ctx context.Context, progress func(done, total int)) (ImportResult, error) {
+	var res ImportResult
+
+	known, err := s.historyRepo.KnownURLs()
+	if err != nil {
+		return res, err
+	}
+	var titles []database.Title
+	if s.titleRepo != nil {
+		if titles, err = s.titleRepo.GetAll(); err != nil {
+			return res, err
+		}
+	}
+	// Оглавления тайтлов — не главы
+	for _, t := range titles {
+		if t.IndexURL != "" {
+			known[t.IndexURL] = true
+		}
+	}
+
+	type ownedPage struct {
+		telegraph.PageInfo
+		token string
+	}
+	var pages []ownedPage
+	for _, token := range s.importTokens() {
+		list, err := s.tgClient.AllPages(ctx, token)
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("getPageList: %v", err))
+		}
+		// getPageList отдаёт новые страницы первыми, в историю пишем от старых к новым
+		for i := len(list) - 1; i >= 0; i-- {
+			pages = append(pages, ownedPage{list[i], token})
+		}
+	}
+
+	for i, p := range pages {
+		if progress != nil {
+			progress(i+1, len(pages))
+		}
+		if known[p.URL] {
+			res.Skipped++
+			continue
+		}
+		known[p.URL] = true
+
+		imgCount := 0
+		if page, err := s.tgClient.GetPageContent(ctx, p.Path); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", p.Path, err))
+		} else {
+			imgCount = len(telegraph.Images(page.Content))
+		}
+
+		titleID := matchTitle(p.Title, titles)
+		if titleID != nil {
+			res.Matched++
+		}
+		if _, err := s.historyRepo.Add(p.Title, p.URL, imgCount, p.token, titleID); err != nil {
+			return res, err
+		}
+		res.Imported++
+	}
+	return res, nil
+}
+
+// importTokens — токены для импорта без повторов
+func (s *PublicationService) importTokens() []string {
+	seen := map[string]bool{}
+	var tokens []string
+	add := func(t string) {
+		if t != "" && !seen[t] {
+			seen[t] = true
+			tokens = append(tokens, t)
+		}
+	}
+	add(s.tgClient.AccessToken())
+	if s.accountRepo != nil {
+		if accounts, err := s.accountRepo.GetAll(); err == nil {
+			for _, a := range accounts {
+				add(a.AccessToken)
+			}
+		}
+	}
+	return tokens
+}
+
+// matchTitle ищет тайтл, название которого открывает заголовок страницы
+// («Ван Пис Глава 1050» → «Ван Пис»); при нескольких совпадениях берётся самое длинное название
+func matchTitle(pageTitle string, titles []database.Title) *uint {
+	page := strings.ToLower(strings.TrimSpace(pageTitle))
+	var best *database.Title
+	for i := range titles {
+		name := strings.ToLower(strings.TrimSpace(titles[i].Name))
+		if name == "" || !strings.HasPrefix(page, name) {
+			continue
+		}
+		// Название должно заканчиваться на границе слова: «Ван Пис» не совпадает с «Ван Писатель»
+		if rest := page[len(name):]; rest != "" {
+			if r, _ := utf8.DecodeRuneInString(rest); !strings.ContainsRune(" .,:;—-–|()[]#", r) {
+				continue
+			}
+		}
+		if best == nil || len(name) > len(best.Name) {
+			best = &titles[i]
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	id := best.ID
+	return &id
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+func TestMatchTitle(t *testing.T) {
+	titles := []database.Title{{ID: 1, Name: "Ван Пис"}, {ID: 2, Name: "Ван Пис: Омакэ"}, {ID: 3, Name: "Берсерк"}}
+	tests := []struct {
+		page string
+		want uint
+	}{
+		{"Ван Пис Глава 1050", 1},
+		{"ван пис — глава 3", 1},
+		{"Ван Пис: Омакэ 2", 2},
+		{"Берсерк", 3},
+		{"Ван Писатель 1", 0},
+		{"Наруто 700", 0},
+	}
+	for _, tt := range tests {
+		got := matchTitle(tt.page, titles)
+		if (got == nil && tt.want != 0) || (got != nil && *got != tt.want) {
+			t.Errorf("matchTitle(%q) = %v, want %d", tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestImportPages(t *testing.T) {
+	_, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	titles := repository.NewTitleRepository(db)
+	client := &telegraph.Client{Token: "main", BaseURL: ts.URL}
+	s := NewPublicationService(client, nil, history, titles, nil, nil, nil)
+	titles.Create("Берсерк", "")
+
+	// Страницы, созданные «вне приложения»
+	other := &telegraph.Client{Token: "main", BaseURL: ts.URL}
+	other.CreatePage(context.Background(), "Берсерк Глава 1", telegraph.ImageNodes([]string{"http://img/1", "http://img/2"}))
+	other.CreatePage(context.Background(), "Заметки", []telegraph.Node{telegraph.Paragraph(telegraph.Text("текст"))})
+	// И одна уже есть в истории
+	if _, err := s.CreatePage(context.Background(), "Берсерк Глава 2", []string{"http://img/3"}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	res, err := s.ImportPages(context.Background(), func(done, total int) { calls++ })
+	if err != nil || len(res.Errors) > 0 {
+		t.Fatalf("import: %v %v", err, res.Errors)
+	}
+	if res.Imported != 2 || res.Skipped != 1 || res.Matched != 1 || calls != 3 {
+		t.Errorf("unexpected result %+v (progress calls %d)", res, calls)
+	}
+
+	items, _ := history.Get(10, 0)
+	byTitle := map[string]database.HistoryItem{}
+	for _, it := range items {
+		byTitle[it.Title] = it
+	}
+	ch1 := byTitle["Берсерк Глава 1"]
+	if ch1.ImgCount != 2 || ch1.TgphToken != "main" || ch1.TitleID == nil {
+		t.Errorf("unexpected imported chapter: %+v", ch1)
+	}
+	if byTitle["Заметки"].TitleID != nil {
+		t.Error("unmatched page must have no title")
+	}
+
+	// Повторный импорт ничего не дублирует
+	if res, _ := s.ImportPages(context.Background(), nil); res.Imported != 0 || res.Skipped != 3 {
+		t.Errorf("second import must skip everything, got %+v", res)
+	}
+}
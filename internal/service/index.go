@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+// indexChaptersHeading — заголовок списка глав на странице-оглавлении
+const indexChaptersHeading = "Главы"
+
+// UpdateTitleIndex пересобирает страницу-оглавление тайтла: при первом вызове создаёт её,
+// дальше правит через editPage тем же токеном. Возвращает адрес страницы.
+func (s *PublicationService) UpdateTitleIndex(ctx context.Context, titleID uint) (string, error) {
+	title, err := s.titleRepo.GetByID(titleID)
+	if err != nil {
+		return "", err
+	}
+	chapters, err := s.historyRepo.GetByTitle(titleID)
+	if err != nil {
+		return "", err
+	}
+
+	content := indexContent(title, chapters)
+	if title.IndexPath != "" {
+		token := title.IndexToken
+		if token == "" {
+			token = s.tgClient.AccessToken()
+		}
+		url, err := s.tgClient.EditPage(ctx, title.IndexPath, title.Name, content, token)
+		if err != nil {
+			return "", err
+		}
+		if url != title.IndexURL {
+			err = s.titleRepo.SetIndexPage(titleID, title.IndexPath, url, token)
+		}
+		return url, err
+	}
+
+	author := s.authorFor(int(titleID))
+	url, err := s.tgClient.CreatePageAs(ctx, title.Name, content, author)
+	if err != nil {
+		return "", err
+	}
+	return url, s.titleRepo.SetIndexPage(titleID, pagePath(url), url, s.tokenOf(author))
+}
+
+// refreshIndex обновляет оглавление, если оно включено у тайтла.
+// Ошибка не критична для вызывающей операции и возвращается как предупреждение.
+func (s *PublicationService) refreshIndex(ctx context.Context, titleID *uint) []string {
+	if titleID == nil || *titleID == 0 || s.titleRepo == nil {
+		return nil
+	}
+	title, err := s.titleRepo.GetByID(*titleID)
+	if err != nil || !title.IndexEnabled {
+		return nil
+	}
+	if _, err := s.UpdateTitleIndex(ctx, *titleID); err != nil {
+		return []string{fmt.Sprintf("Не удалось обновить оглавление «%s»: %v", title.Name, err)}
+	}
+	return nil
+}
+
+// indexContent — обложка, описание и список глав со ссылками
+func indexContent(title database.Title, chapters []database.HistoryItem) []telegraph.Node {
+	b := telegraph.NewBuilder()
+	if title.CoverURL != "" {
+		b.Image(title.CoverURL, "")
+	}
+	if title.Description != "" {
+		b.Paragraph(telegraph.Text(title.Description))
+	}
+	b.Heading(indexChaptersHeading)
+
+	if len(chapters) == 0 {
+		return b.Paragraph(telegraph.Italic(telegraph.Text("Пока нет опубликованных глав"))).Nodes()
+	}
+	items := make([]telegraph.Node, 0, len(chapters))
+	for _, ch := range sortChapters(chapters) {
+		items = append(items, telegraph.Element("li", nil, telegraph.Link(ch.Url, telegraph.Text(ch.Title))))
+	}
+	return b.Add(telegraph.Element("ul", nil, items...)).Nodes()
+}
+
+// sortChapters упорядочивает главы по номеру из заголовка; главы без номера
+// остаются в порядке публикации после пронумерованных
+func sortChapters(chapters []database.HistoryItem) []database.HistoryItem {
+	sorted := append([]database.HistoryItem(nil), chapters...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ni, okI := chapterNumber(sorted[i].Title)
+		nj, okJ := chapterNumber(sorted[j].Title)
+		if okI && okJ {
+			return ni < nj
+		}
+		return okI && !okJ
+	})
+	return sorted
+}
+
+// DeleteHistory удаляет главу из истории и пересобирает оглавление её тайтла
+func (s *PublicationService) DeleteHistory(ctx context.Context, id uint) ([]string, error) {
+	item, err := s.historyRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.historyRepo.Delete(id); err != nil {
+		return nil, err
+	}
+	return s.refreshIndex(ctx, item.TitleID), nil
+}
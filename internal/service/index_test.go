@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+// indexLinks возвращает ссылки списка глав со страницы-оглавления
+func indexLinks(content []telegraph.Node) []string {
+	var urls []string
+	for _, n := range content {
+		if n.Tag != "ul" {
+			continue
+		}
+		for _, li := range n.Children {
+			urls = append(urls, li.Children[0].Attrs["href"])
+		}
+	}
+	return urls
+}
+
+func TestSortChapters(t *testing.T) {
+	items := []database.HistoryItem{{Title: "Глава 10"}, {Title: "Пролог"}, {Title: "Глава 2"}, {Title: "Глава 1.5"}}
+	sorted := sortChapters(items)
+	want := []string{"Глава 1.5", "Глава 2", "Глава 10", "Пролог"}
+	for i, w := range want {
+		if sorted[i].Title != w {
+			t.Errorf("position %d: got %q, want %q", i, sorted[i].Title, w)
+		}
+	}
+	if items[0].Title != "Глава 10" {
+		t.Error("input slice must not be reordered")
+	}
+}
+
+func TestTitleIndex_Lifecycle(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	titles := repository.NewTitleRepository(db)
+	client := &telegraph.Client{Token: "current", BaseURL: ts.URL}
+	s := NewPublicationService(client, nil, history, titles, nil, nil, nil)
+
+	if err := titles.Create("Manga", ""); err != nil {
+		t.Fatal(err)
+	}
+	all, _ := titles.GetAll()
+	titleID := all[0].ID
+
+	// Пока оглавление не включено, публикация глав его не создаёт
+	ch2, err := s.CreatePage(context.Background(), "Глава 2", []string{"http://img/2"}, int(titleID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title, _ := titles.GetByID(titleID); title.IndexPath != "" {
+		t.Fatal("index must not be created while disabled")
+	}
+
+	if err := titles.UpdateIndexSettings(titleID, true, "http://img/cover", "Описание"); err != nil {
+		t.Fatal(err)
+	}
+	indexURL, err := s.UpdateTitleIndex(context.Background(), titleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := fake.page(indexURL)
+	if page.Title != "Manga" || page.Content[0].Attrs["src"] != "http://img/cover" {
+		t.Errorf("unexpected index page: %+v", page)
+	}
+
+	// Новая глава попадает в оглавление по номеру, а не по времени публикации
+	ch1, err := s.CreatePage(context.Background(), "Глава 1", []string{"http://img/1"}, int(titleID))
+	if err != nil || len(ch1.Warnings) > 0 {
+		t.Fatalf("chapter 1: %v %v", err, ch1.Warnings)
+	}
+	links := indexLinks(fake.page(indexURL).Content)
+	if len(links) != 2 || links[0] != ch1.URL || links[1] != ch2.URL {
+		t.Errorf("unexpected index links: %v", links)
+	}
+
+	// Правка главы обновляет заголовок в истории и в оглавлении
+	if res, err := s.EditPage(context.Background(), pagePath(ch2.URL), "Глава 2: Встреча", []string{"http://img/2b"}, "current"); err != nil || res != ch2.URL {
+		t.Fatalf("edit failed: %s %v", res, err)
+	}
+	if item, _ := history.GetByID(ch2.HistoryID); item.Title != "Глава 2: Встреча" {
+		t.Errorf("history title not updated: %q", item.Title)
+	}
+	last := fake.page(indexURL).Content
+	if got := last[len(last)-1].Children[1].Children[0].Children[0].Text; got != "Глава 2: Встреча" {
+		t.Errorf("index must show edited title, got %q", got)
+	}
+
+	// Правка одних картинок оглавление не трогает
+	indexPage := fake.page(indexURL)
+	if _, err := s.EditPage(context.Background(), pagePath(ch2.URL), "", []string{"http://img/2c"}, "current"); err != nil {
+		t.Fatal(err)
+	}
+	if fake.page(indexURL) != indexPage {
+		t.Error("index must not be re-published when the chapter title is unchanged")
+	}
+
+	// Удаление из истории убирает главу из оглавления, сама страница остаётся той же
+	if _, err := s.DeleteHistory(context.Background(), ch1.HistoryID); err != nil {
+		t.Fatal(err)
+	}
+	links = indexLinks(fake.page(indexURL).Content)
+	if len(links) != 1 || links[0] != ch2.URL {
+		t.Errorf("unexpected index links after delete: %v", links)
+	}
+	if title, _ := titles.GetByID(titleID); title.IndexURL != indexURL {
+		t.Errorf("index must be edited in place, got %q", title.IndexURL)
+	}
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+	"telegraph_uploader_v2/internal/uploader"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultLinkConcurrency — сколько картинок проверяется одновременно
+	DefaultLinkConcurrency = 8
+	// DefaultSlowLink — ответ дольше этого считается медленным
+	DefaultSlowLink = 3 * time.Second
+)
+
+// Reuploader перезаливает потерянную картинку из исходного файла (см. uploader.R2Uploader.Reupload)
+type Reuploader interface {
+	Reupload(ctx context.Context, brokenURL string, settings uploader.ResizeSettings) (string, error)
+}
+
+// LinkChecker проверяет картинки на опубликованных страницах истории и чинит битые
+type LinkChecker struct {
+	pub         *PublicationService
+	historyRepo repository.HistoryRepository
+	linkRepo    repository.LinkRepository
+	reuploader  Reuploader
+	httpClient  *http.Client
+	concurrency int
+	slowAfter   time.Duration
+}
+
+func NewLinkChecker(pub *PublicationService, history repository.HistoryRepository, links repository.LinkRepository, reuploader Reuploader) *LinkChecker {
+	return &LinkChecker{
+		pub:         pub,
+		historyRepo: history,
+		linkRepo:    links,
+		reuploader:  reuploader,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		concurrency: DefaultLinkConcurrency,
+		slowAfter:   DefaultSlowLink,
+	}
+}
+
+// LinkReport — итог проверки
+type LinkReport struct {
+	Pages  int      `json:"pages"`
+	Images int      `json:"images"`
+	Broken int      `json:"broken"`
+	Slow   int      `json:"slow"`
+	Errors []string `json:"errors"`
+}
+
+// RepairResult — итог починки главы
+type RepairResult struct {
+	Fixed  int      `json:"fixed"`
+	Failed []string `json:"failed"`
+}
+
+// Check проверяет все главы истории. Ошибка по отдельной главе попадает в отчёт и не прерывает проход.
+func (c *LinkChecker) Check(ctx context.Context, progress func(done, total int)) (LinkReport, error) {
+	var report LinkReport
+	items, err := c.historyRepo.All()
+	if err != nil {
+		return report, err
+	}
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		pages, images, issues, err := c.CheckHistory(ctx, item.ID)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return report, err
+			}
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", item.Title, err))
+		}
+		report.Pages += pages
+		report.Images += images
+		for _, issue := range issues {
+			if issue.Broken {
+				report.Broken++
+			} else {
+				report.Slow++
+			}
+		}
+		if progress != nil {
+			progress(i+1, len(items))
+		}
+	}
+	return report, nil
+}
+
+// CheckHistory проверяет все страницы главы (включая части) и сохраняет найденные проблемы.
+// Возвращает число страниц, картинок и список проблем.
+func (c *LinkChecker) CheckHistory(ctx context.Context, historyID uint) (int, int, []database.LinkIssue, error) {
+	item, err := c.historyRepo.GetByID(historyID)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	urls := []string{item.Url}
+	if len(item.Parts) > 0 {
+		urls = urls[:0]
+		for _, p := range item.Parts {
+			urls = append(urls, p.Url)
+		}
+	}
+
+	var issues []database.LinkIssue
+	images := 0
+	for _, pageURL := range urls {
+		_, srcs, err := c.pub.GetPage(ctx, pageURL)
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("get %s: %w", pagePath(pageURL), err)
+		}
+		images += len(srcs)
+		pageIssues, err := c.checkImages(ctx, pagePath(pageURL), srcs)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		issues = append(issues, pageIssues...)
+	}
+
+	if err := c.linkRepo.ReplaceForHistory(historyID, issues); err != nil {
+		return len(urls), images, issues, err
+	}
+	return len(urls), images, issues, nil
+}
+
+// checkImages опрашивает картинки страницы не более чем в concurrency потоков
+func (c *LinkChecker) checkImages(ctx context.Context, path string, srcs []string) ([]database.LinkIssue, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(c.concurrency)
+
+	var mu sync.Mutex
+	var issues []database.LinkIssue
+	for _, src := range srcs {
+		src := src
+		g.Go(func() error {
+			issue, ok := c.probe(ctx, src)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !ok {
+				issue.Path = path
+				mu.Lock()
+				issues = append(issues, issue)
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// probe делает HEAD-запрос к картинке (GET первого байта, если сервер не поддерживает HEAD).
+// ok — картинка доступна и ответила быстрее slowAfter.
+func (c *LinkChecker) probe(ctx context.Context, src string) (database.LinkIssue, bool) {
+	issue := database.LinkIssue{URL: src, CheckedAt: time.Now()}
+	start := time.Now()
+	resp, err := c.request(ctx, http.MethodHead, src)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = c.request(ctx, http.MethodGet, src)
+	}
+	issue.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		issue.Broken = true
+		issue.Error = err.Error()
+		return issue, false
+	}
+	issue.Status = resp.StatusCode
+	if resp.StatusCode >= 400 {
+		issue.Broken = true
+		issue.Error = resp.Status
+		return issue, false
+	}
+	return issue, time.Duration(issue.LatencyMs)*time.Millisecond < c.slowAfter
+}
+
+func (c *LinkChecker) request(ctx context.Context, method, src string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp, nil
+}
+
+// Issues — результаты последней проверки всех глав
+func (c *LinkChecker) Issues() ([]database.LinkIssue, error) {
+	return c.linkRepo.All()
+}
+
+// Repair перезаливает битые картинки главы из исходных файлов и подменяет адреса на страницах.
+// Картинки без известного исходника попадают в Failed. После починки глава проверяется заново.
+func (c *LinkChecker) Repair(ctx context.Context, historyID uint, settings uploader.ResizeSettings) (RepairResult, error) {
+	var res RepairResult
+	if c.reuploader == nil {
+		return res, errors.New("uploader is not available")
+	}
+	item, err := c.historyRepo.GetByID(historyID)
+	if err != nil {
+		return res, err
+	}
+	issues, err := c.linkRepo.ForHistory(historyID)
+	if err != nil {
+		return res, err
+	}
+
+	// Новые адреса по страницам: path -> старый адрес -> новый
+	replacements := map[string]map[string]string{}
+	var paths []string
+	for _, issue := range issues {
+		if !issue.Broken {
+			continue
+		}
+		link, err := c.reuploader.Reupload(ctx, issue.URL, settings)
+		if err != nil {
+			res.Failed = append(res.Failed, fmt.Sprintf("%s: %v", issue.URL, err))
+			continue
+		}
+		if replacements[issue.Path] == nil {
+			replacements[issue.Path] = map[string]string{}
+			paths = append(paths, issue.Path)
+		}
+		replacements[issue.Path][issue.URL] = link
+	}
+
+	token := item.TgphToken
+	if token == "" {
+		token = c.pub.tgClient.AccessToken()
+	}
+	for _, path := range paths {
+		repl := replacements[path]
+		fixed := 0
+		_, err := c.pub.EditPageImages(ctx, path, "", token, func(e *telegraph.PageEditor) error {
+			for i, src := range e.Images() {
+				if link, ok := repl[src]; ok {
+					if err := e.Replace(i, link); err != nil {
+						return err
+					}
+					fixed++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return res, fmt.Errorf("edit %s: %w", path, err)
+		}
+		res.Fixed += fixed
+	}
+
+	if res.Fixed > 0 {
+		if _, _, _, err := c.CheckHistory(ctx, historyID); err != nil {
+			log.Printf("[Links] Recheck of history %d failed: %v", historyID, err)
+		}
+	}
+	return res, nil
+}
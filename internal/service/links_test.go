@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+	"telegraph_uploader_v2/internal/uploader"
+)
+
+// fakeReuploader «перезаливает» картинку, для которой известен исходник
+type fakeReuploader struct {
+	known map[string]string
+}
+
+func (f *fakeReuploader) Reupload(ctx context.Context, brokenURL string, settings uploader.ResizeSettings) (string, error) {
+	if link, ok := f.known[brokenURL]; ok {
+		return link, nil
+	}
+	return "", uploader.ErrSourceUnknown
+}
+
+func TestLinkChecker_CheckAndRepair(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	// Картинки: /ok — есть, /slow — медленная, /gone* — удалены, HEAD не поддерживается на /nohead
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/gone"):
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/slow":
+			time.Sleep(50 * time.Millisecond)
+		case r.URL.Path == "/nohead" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer images.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	links := repository.NewLinkRepository(db)
+	pub := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+	reuploader := &fakeReuploader{known: map[string]string{images.URL + "/gone1": images.URL + "/ok?v=2"}}
+	checker := NewLinkChecker(pub, history, links, reuploader)
+	checker.slowAfter = 30 * time.Millisecond
+
+	srcs := []string{images.URL + "/ok", images.URL + "/gone1", images.URL + "/slow", images.URL + "/nohead", images.URL + "/gone2"}
+	ch, err := pub.CreatePage(context.Background(), "Глава 1", srcs, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := checker.Check(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Pages != 1 || report.Images != 5 || report.Broken != 2 || report.Slow != 1 || len(report.Errors) != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	res, err := checker.Repair(context.Background(), ch.HistoryID, uploader.ResizeSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Fixed != 1 || len(res.Failed) != 1 || !strings.Contains(res.Failed[0], "/gone2") {
+		t.Errorf("unexpected repair result %+v", res)
+	}
+	want := []string{images.URL + "/ok", images.URL + "/ok?v=2", images.URL + "/slow", images.URL + "/nohead", images.URL + "/gone2"}
+	if got := telegraph.PageImages(fake.page(ch.URL).Content); !reflect.DeepEqual(got, want) {
+		t.Errorf("page must be patched in place, got %v", got)
+	}
+
+	// После починки глава перепроверена: осталась одна битая и одна медленная
+	issues, _ := links.ForHistory(ch.HistoryID)
+	broken := 0
+	for _, issue := range issues {
+		if issue.Broken {
+			broken++
+		}
+	}
+	if len(issues) != 2 || broken != 1 {
+		t.Errorf("unexpected issues after repair: %+v", issues)
+	}
+}
@@ -21,3 +21,17 @@ func (s *MangaService) UploadChapter(ctx context.Context, filePaths []string, se
 	// Вызов R2
 	return s.uploader.UploadChapter(ctx, filePaths, settings, onProgress)
 }
+
+// EstimateChapter выполняет пробный прогон без загрузки
+func (s *MangaService) EstimateChapter(ctx context.Context, filePaths []string, settings uploader.ResizeSettings, sampleSize int, onProgress func(int, int)) uploader.EstimateResult {
+	if s.uploader == nil {
+		return uploader.EstimateResult{Success: false, Error: "Загрузчик не инициализирован"}
+	}
+
+	return s.uploader.EstimateChapter(ctx, filePaths, settings, sampleSize, onProgress)
+}
+
+// LintChapter проверяет файлы главы перед загрузкой. Загрузчик для этого не нужен.
+func (s *MangaService) LintChapter(ctx context.Context, filePaths []string) uploader.LintReport {
+	return uploader.LintChapter(ctx, filePaths, uploader.DefaultLintOptions())
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+
+	"gorm.io/gorm"
+)
+
+// DefaultMigrationDelay — пауза между правками страниц, чтобы не упираться в FLOOD_WAIT
+const DefaultMigrationDelay = 500 * time.Millisecond
+
+// errNoChanges — на странице нечего менять, editPage не нужен
+var errNoChanges = errors.New("no changes")
+
+// MigrationService переносит адреса картинок на всех страницах истории на новый домен
+type MigrationService struct {
+	pub           *PublicationService
+	historyRepo   repository.HistoryRepository
+	migrationRepo repository.MigrationRepository
+	cacheRepo     repository.ImageCacheRepository
+	delay         time.Duration
+}
+
+func NewMigrationService(pub *PublicationService, history repository.HistoryRepository, migrations repository.MigrationRepository, cache repository.ImageCacheRepository) *MigrationService {
+	return &MigrationService{
+		pub:           pub,
+		historyRepo:   history,
+		migrationRepo: migrations,
+		cacheRepo:     cache,
+		delay:         DefaultMigrationDelay,
+	}
+}
+
+// PageChange — страница, на которой меняются адреса картинок
+type PageChange struct {
+	HistoryID uint   `json:"history_id"`
+	Title     string `json:"title"`
+	Path      string `json:"path"`
+	Images    int    `json:"images"`
+}
+
+// MigrationReport — итог переноса или пробного прогона
+type MigrationReport struct {
+	MigrationID uint         `json:"migration_id"`
+	DryRun      bool         `json:"dry_run"`
+	Resumed     bool         `json:"resumed"`
+	Scanned     int          `json:"scanned"` // просмотрено страниц
+	Pages       int          `json:"pages"`   // страниц с заменами
+	Images      int          `json:"images"`  // заменённых адресов
+	Changes     []PageChange `json:"changes"`
+	Errors      []string     `json:"errors"`
+}
+
+// validatePrefixes отсекает переносы, которые нельзя безопасно продолжить:
+// если новый префикс начинается со старого, уже перенесённые адреса совпадут снова.
+func validatePrefixes(oldPrefix, newPrefix string) error {
+	if oldPrefix == "" || newPrefix == "" {
+		return errors.New("both prefixes are required")
+	}
+	if strings.HasPrefix(newPrefix, oldPrefix) {
+		return fmt.Errorf("new prefix %q must not start with old prefix %q", newPrefix, oldPrefix)
+	}
+	return nil
+}
+
+// rewriteImages заменяет префикс у картинок страницы, возвращает число замен
+func rewriteImages(e *telegraph.PageEditor, oldPrefix, newPrefix string) (int, error) {
+	n := 0
+	for i, src := range e.Images() {
+		if strings.HasPrefix(src, oldPrefix) {
+			if err := e.Replace(i, newPrefix+strings.TrimPrefix(src, oldPrefix)); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// historyPages — адреса всех страниц главы (у разбитой — частей)
+func historyPages(item database.HistoryItem) []string {
+	if len(item.Parts) == 0 {
+		return []string{item.Url}
+	}
+	urls := make([]string, len(item.Parts))
+	for i, p := range item.Parts {
+		urls[i] = p.Url
+	}
+	return urls
+}
+
+// Preview — пробный прогон: какие страницы и сколько картинок будут изменены. Ничего не правит.
+func (s *MigrationService) Preview(ctx context.Context, oldPrefix, newPrefix string, progress func(done, total int)) (MigrationReport, error) {
+	report := MigrationReport{DryRun: true}
+	if err := validatePrefixes(oldPrefix, newPrefix); err != nil {
+		return report, err
+	}
+	items, err := s.historyRepo.All()
+	if err != nil {
+		return report, err
+	}
+	for i, entry := range items {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		item, err := s.historyRepo.GetByID(entry.ID)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", entry.Title, err))
+			continue
+		}
+		for _, pageURL := range historyPages(item) {
+			_, images, err := s.pub.GetPage(ctx, pageURL)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", item.Title, err))
+				continue
+			}
+			report.Scanned++
+			n := 0
+			for _, src := range images {
+				if strings.HasPrefix(src, oldPrefix) {
+					n++
+				}
+			}
+			if n > 0 {
+				report.Pages++
+				report.Images += n
+				report.Changes = append(report.Changes, PageChange{HistoryID: item.ID, Title: item.Title, Path: pagePath(pageURL), Images: n})
+			}
+		}
+		if progress != nil {
+			progress(i+1, len(items))
+		}
+	}
+	return report, nil
+}
+
+// Migrate переписывает адреса картинок на всех страницах истории через editPage токеном главы.
+// Между правками выдерживается пауза delay. Ошибки отдельных страниц (удалена, чужой токен)
+// попадают в report.Errors; на временных ошибках (сеть, FLOOD_WAIT, отмена) перенос останавливается
+// и запоминает последнюю обработанную главу; повторный вызов с теми же префиксами продолжит с места остановки.
+// После завершения переносятся и адреса в кэше загрузок.
+func (s *MigrationService) Migrate(ctx context.Context, oldPrefix, newPrefix string, progress func(done, total int)) (MigrationReport, error) {
+	var report MigrationReport
+	if err := validatePrefixes(oldPrefix, newPrefix); err != nil {
+		return report, err
+	}
+
+	m, err := s.migrationRepo.FindUnfinished(oldPrefix, newPrefix)
+	switch {
+	case err == nil:
+		report.Resumed = true
+		log.Printf("[Migration] Resuming migration %d after history %d", m.ID, m.LastHistoryID)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		m = database.DomainMigration{OldPrefix: oldPrefix, NewPrefix: newPrefix}
+		if err := s.migrationRepo.Create(&m); err != nil {
+			return report, err
+		}
+	default:
+		return report, err
+	}
+	report.MigrationID = m.ID
+	m.Status, m.LastError = database.MigrationRunning, ""
+
+	fail := func(err error) (MigrationReport, error) {
+		m.Status, m.LastError = database.MigrationFailed, err.Error()
+		if uerr := s.migrationRepo.Update(&m); uerr != nil {
+			log.Printf("[Migration] Failed to save state: %v", uerr)
+		}
+		return report, err
+	}
+
+	items, err := s.historyRepo.All()
+	if err != nil {
+		return fail(err)
+	}
+	for i, entry := range items {
+		if entry.ID <= m.LastHistoryID {
+			continue
+		}
+		item, err := s.historyRepo.GetByID(entry.ID)
+		if err != nil {
+			return fail(err)
+		}
+		token := item.TgphToken
+		if token == "" {
+			token = s.pub.tgClient.AccessToken()
+		}
+
+		for _, pageURL := range historyPages(item) {
+			if err := ctx.Err(); err != nil {
+				return fail(err)
+			}
+			path := pagePath(pageURL)
+			replaced := 0
+			_, err := s.pub.EditPageImages(ctx, path, "", token, func(e *telegraph.PageEditor) error {
+				n, err := rewriteImages(e, oldPrefix, newPrefix)
+				if err != nil {
+					return err
+				}
+				if n == 0 {
+					return errNoChanges
+				}
+				replaced = n
+				return nil
+			})
+			report.Scanned++
+			if errors.Is(err, errNoChanges) {
+				continue
+			}
+			if err != nil {
+				err = fmt.Errorf("%s (%s): %w", item.Title, path, err)
+				if isTransient(err) {
+					return fail(err)
+				}
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+			report.Pages++
+			report.Images += replaced
+			report.Changes = append(report.Changes, PageChange{HistoryID: item.ID, Title: item.Title, Path: path, Images: replaced})
+			m.Pages++
+			m.Images += replaced
+			if err := s.wait(ctx); err != nil {
+				return fail(err)
+			}
+		}
+
+		m.LastHistoryID = item.ID
+		if err := s.migrationRepo.Update(&m); err != nil {
+			return fail(err)
+		}
+		if progress != nil {
+			progress(i+1, len(items))
+		}
+	}
+
+	if s.cacheRepo != nil {
+		if n, err := s.cacheRepo.ReplacePrefix(oldPrefix, newPrefix); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("Не удалось обновить кэш загрузок: %v", err))
+		} else {
+			log.Printf("[Migration] Updated %d cached upload URLs", n)
+		}
+	}
+	m.Status = database.MigrationDone
+	return report, s.migrationRepo.Update(&m)
+}
+
+// isTransient — ошибка, после которой перенос стоит повторить позже, а не пропускать страницу
+func isTransient(err error) bool {
+	var netErr *telegraph.NetworkError
+	var flood *telegraph.FloodWaitError
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &netErr) || errors.As(err, &flood)
+}
+
+// wait выдерживает паузу между правками страниц
+func (s *MigrationService) wait(ctx context.Context) error {
+	if s.delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(s.delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// History — все переносы, новые первыми
+func (s *MigrationService) History() ([]database.DomainMigration, error) {
+	return s.migrationRepo.GetAll()
+}
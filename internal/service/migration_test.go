@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+func TestMigration_PreviewFailResume(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	migrations := repository.NewMigrationRepository(db)
+	pub := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+	s := NewMigrationService(pub, history, migrations, nil)
+	s.delay = 0
+
+	const oldPrefix, newPrefix = "https://old.example.com/", "https://cdn.example.org/"
+	var chapters []PageResult
+	for _, images := range [][]string{
+		{oldPrefix + "1.webp", "https://other.com/keep.webp"},
+		{oldPrefix + "2.webp", oldPrefix + "3.webp"},
+		{"https://other.com/4.webp"},
+	} {
+		ch, err := pub.CreatePage(context.Background(), "Глава", images, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chapters = append(chapters, ch)
+	}
+
+	if _, err := s.Preview(context.Background(), oldPrefix, oldPrefix+"img/", nil); err == nil {
+		t.Error("new prefix starting with old one must be rejected")
+	}
+
+	preview, err := s.Preview(context.Background(), oldPrefix, newPrefix, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Scanned != 3 || preview.Pages != 2 || preview.Images != 3 {
+		t.Errorf("unexpected preview %+v", preview)
+	}
+	if got := telegraph.PageImages(fake.page(chapters[0].URL).Content); got[0] != oldPrefix+"1.webp" {
+		t.Fatalf("dry run must not edit pages, got %v", got)
+	}
+
+	// На второй главе flood control дольше допустимого — перенос останавливается после первой
+	second := pagePath(chapters[1].URL)
+	fake.mu.Lock()
+	fake.fail[second] = "FLOOD_WAIT_600"
+	fake.mu.Unlock()
+	if _, err := s.Migrate(context.Background(), oldPrefix, newPrefix, nil); err == nil {
+		t.Fatal("expected migration to fail")
+	}
+	all, _ := migrations.GetAll()
+	if len(all) != 1 || all[0].Status != database.MigrationFailed || all[0].LastHistoryID != chapters[0].HistoryID || all[0].Images != 1 {
+		t.Fatalf("unexpected migration state %+v", all)
+	}
+
+	fake.mu.Lock()
+	delete(fake.fail, second)
+	fake.mu.Unlock()
+	report, err := s.Migrate(context.Background(), oldPrefix, newPrefix, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Resumed || report.Pages != 1 || report.Images != 2 || report.Scanned != 2 {
+		t.Errorf("unexpected resumed report %+v", report)
+	}
+
+	want := [][]string{
+		{newPrefix + "1.webp", "https://other.com/keep.webp"},
+		{newPrefix + "2.webp", newPrefix + "3.webp"},
+		{"https://other.com/4.webp"},
+	}
+	for i, ch := range chapters {
+		if got := telegraph.PageImages(fake.page(ch.URL).Content); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("chapter %d: got %v", i+1, got)
+		}
+	}
+	all, _ = migrations.GetAll()
+	if len(all) != 1 || all[0].Status != database.MigrationDone || all[0].Images != 3 || all[0].Pages != 2 {
+		t.Errorf("unexpected final state %+v", all)
+	}
+}
+
+func TestMigration_SkipsMissingPages(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	migrations := repository.NewMigrationRepository(db)
+	pub := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+	s := NewMigrationService(pub, history, migrations, nil)
+	s.delay = 0
+
+	const oldPrefix, newPrefix = "https://old.example.com/", "https://cdn.example.org/"
+	var chapters []PageResult
+	for _, name := range []string{"1.webp", "2.webp", "3.webp"} {
+		ch, err := pub.CreatePage(context.Background(), "Глава", []string{oldPrefix + name}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chapters = append(chapters, ch)
+	}
+
+	// Вторую страницу удалили в Telegraph: она попадает в ошибки, перенос идёт дальше
+	fake.mu.Lock()
+	delete(fake.pages, pagePath(chapters[1].URL))
+	fake.mu.Unlock()
+
+	report, err := s.Migrate(context.Background(), oldPrefix, newPrefix, nil)
+	if err != nil {
+		t.Fatalf("missing page must not stop migration: %v", err)
+	}
+	if report.Pages != 2 || report.Images != 2 || len(report.Errors) != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if got := telegraph.PageImages(fake.page(chapters[2].URL).Content); got[0] != newPrefix+"3.webp" {
+		t.Errorf("page after the missing one not migrated: %v", got)
+	}
+	all, _ := migrations.GetAll()
+	if len(all) != 1 || all[0].Status != database.MigrationDone || all[0].LastHistoryID != chapters[2].HistoryID {
+		t.Errorf("unexpected final state %+v", all)
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+// ImageNote — подпись под картинкой (figcaption) и заметка переводчика после неё (Markdown)
+type ImageNote struct {
+	Caption string `json:"caption"`
+	Note    string `json:"note"`
+}
+
+// ChapterNotes — подписи и заметки по номерам страниц главы (с 1).
+// Заметка с номером 0 ставится перед первой картинкой, подпись у неё не используется.
+type ChapterNotes map[int]ImageNote
+
+// Файлы с подписями и заметками в папке главы (см. LoadChapterNotes)
+const (
+	NotesJSONFile = "notes.json"
+	NotesTextFile = "notes.txt"
+)
+
+// chapterBlock — картинка главы вместе с подписью и заметкой после неё; при делении
+// главы на части блок не разрывается
+type chapterBlock struct {
+	nodes []telegraph.Node
+}
+
+// chapterBlocks собирает блоки картинок. Подписи и заметки к несуществующим страницам
+// пропускаются с предупреждением.
+func chapterBlocks(images []string, notes ChapterNotes) ([]chapterBlock, []string) {
+	blocks := make([]chapterBlock, len(images))
+	for i, src := range images {
+		note := notes[i+1]
+		var nodes []telegraph.Node
+		if i == 0 {
+			nodes = append(nodes, telegraph.FromMarkdown(notes[0].Note)...)
+		}
+		if caption := strings.TrimSpace(note.Caption); caption != "" {
+			nodes = append(nodes, telegraph.Figure(src, telegraph.Text(caption)))
+		} else {
+			nodes = append(nodes, telegraph.Image(src))
+		}
+		nodes = append(nodes, telegraph.FromMarkdown(note.Note)...)
+		blocks[i] = chapterBlock{nodes: nodes}
+	}
+
+	var warnings []string
+	for _, page := range sortedPages(notes) {
+		if page < 0 || page > len(images) || page == 0 && len(images) == 0 {
+			warnings = append(warnings, fmt.Sprintf("Заметка к странице %d пропущена: в главе %d страниц", page, len(images)))
+		}
+	}
+	return blocks, warnings
+}
+
+// blockNodes — контент страницы из блоков по порядку
+func blockNodes(blocks []chapterBlock) []telegraph.Node {
+	var content []telegraph.Node
+	for _, b := range blocks {
+		content = append(content, b.nodes...)
+	}
+	return content
+}
+
+func sortedPages(notes ChapterNotes) []int {
+	pages := make([]int, 0, len(notes))
+	for page := range notes {
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+	return pages
+}
+
+// LoadChapterNotes читает подписи и заметки из папки главы: notes.json, а если его нет — notes.txt.
+// Ключи — имена файлов картинок («003.jpg») или номера страниц («3», «0» — перед первой).
+// Сопоставлять их с картинками должен вызывающий: после резки разворотов номера файлов
+// и страниц расходятся. Без файлов заметок возвращается nil.
+//
+// notes.json: {"003.jpg": {"caption": "Вывеска: «Кафе»", "note": "Примечание"}}
+//
+// notes.txt — секции с ключом в квадратных скобках; строка «подпись:» (или «caption:»)
+// задаёт подпись, остальные строки — заметку в Markdown. Текст до первой секции идёт
+// перед первой страницей:
+//
+//	[003.jpg]
+//	подпись: Вывеска: «Кафе»
+//	*Примечание переводчика*: игра слов в оригинале.
+func LoadChapterNotes(dir string) (map[string]ImageNote, error) {
+	data, err := os.ReadFile(filepath.Join(dir, NotesJSONFile))
+	if err == nil {
+		var notes map[string]ImageNote
+		if err := json.Unmarshal(data, &notes); err != nil {
+			return nil, fmt.Errorf("%s: %w", NotesJSONFile, err)
+		}
+		return notes, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, NotesTextFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseNotesText(string(data)), nil
+}
+
+// parseNotesText разбирает формат notes.txt (см. LoadChapterNotes)
+func parseNotesText(text string) map[string]ImageNote {
+	notes := map[string]ImageNote{}
+	key := "0"
+	var lines []string
+	var caption string
+	flush := func() {
+		note := strings.TrimSpace(strings.Join(lines, "\n"))
+		if note != "" || caption != "" {
+			notes[key] = ImageNote{Caption: caption, Note: note}
+		}
+		lines, caption = nil, ""
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") && len(trimmed) > 2 {
+			flush()
+			key = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			continue
+		}
+		if caption == "" {
+			lower := strings.ToLower(trimmed)
+			if rest, ok := cutAnyPrefix(lower, trimmed, "подпись:", "caption:"); ok {
+				caption = rest
+				continue
+			}
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return notes
+}
+
+// cutAnyPrefix срезает первый подходящий префикс (сравнение по lower, срез — по исходной строке)
+func cutAnyPrefix(lower, s string, prefixes ...string) (string, bool) {
+	for _, p := range prefixes {
+		if strings.HasPrefix(lower, p) {
+			return strings.TrimSpace(s[len(p):]), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+func TestCreatePageWithNotes(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	history := repository.NewHistoryRepository(setupHistoryDB(t))
+	s := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+
+	notes := ChapterNotes{
+		0: {Note: "Перед главой"},
+		2: {Caption: "Вывеска: «Кафе»", Note: "*Игра слов* в оригинале"},
+		9: {Note: "Лишняя"},
+	}
+	res, err := s.CreatePageWithNotes(context.Background(), "Глава 1", []string{"http://img/1", "http://img/2", "http://img/3"}, notes, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[{"tag":"p","children":["Перед главой"]},{"tag":"img","attrs":{"src":"http://img/1"}},` +
+		`{"tag":"figure","children":[{"tag":"img","attrs":{"src":"http://img/2"}},{"tag":"figcaption","children":["Вывеска: «Кафе»"]}]},` +
+		`{"tag":"p","children":[{"tag":"i","children":["Игра слов"]}," в оригинале"]},` +
+		`{"tag":"img","attrs":{"src":"http://img/3"}}]`
+	if got, _ := json.Marshal(fake.page(res.URL).Content); string(got) != want {
+		t.Errorf("unexpected content:\n got %s\nwant %s", got, want)
+	}
+	if len(res.Warnings) != 1 {
+		t.Errorf("expected warning for note to missing page, got %v", res.Warnings)
+	}
+	if item, _ := history.GetByID(res.HistoryID); item.ImgCount != 3 {
+		t.Errorf("notes must not change image count, got %d", item.ImgCount)
+	}
+
+	// Правка картинок в режиме редактирования не стирает подписи и заметки
+	if _, err := s.EditPage(context.Background(), pagePath(res.URL), "", []string{"http://img/1", "http://img/2", "http://img/4"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	edited, _ := json.Marshal(fake.page(res.URL).Content)
+	for _, text := range []string{"Перед главой", "Вывеска: «Кафе»", "Игра слов", "http://img/4"} {
+		if !strings.Contains(string(edited), text) {
+			t.Errorf("edited page lost %q: %s", text, edited)
+		}
+	}
+
+	// Картинка с заметкой не отрывается от неё при делении на части
+	images := testImages(20)
+	notes = ChapterNotes{}
+	for i := 1; i <= len(images); i++ {
+		notes[i] = ImageNote{Note: "Заметка к странице"}
+	}
+	s.contentLimit = telegraph.ContentSize(telegraph.ImageNodes(images)) / 2
+	res, err = s.CreatePageWithNotes(context.Background(), "Глава 2", images, notes, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Parts) < 2 {
+		t.Fatalf("expected split chapter, got %v", res.Parts)
+	}
+	for _, part := range res.Parts {
+		content := fake.page(part).Content
+		for i, n := range content {
+			if n.Tag == "img" && (i+1 >= len(content) || content[i+1].Children[0].Text != "Заметка к странице") {
+				t.Errorf("note separated from its image in %s", part)
+			}
+		}
+	}
+}
+
+func TestLoadChapterNotes(t *testing.T) {
+	dir := t.TempDir()
+	if notes, err := LoadChapterNotes(dir); err != nil || notes != nil {
+		t.Fatalf("expected no notes without sidecar, got %v %v", notes, err)
+	}
+
+	text := "Перед главой\n\n" +
+		"[003.jpg]\n" +
+		"Подпись: Вывеска: «Кафе»\n" +
+		"*Примечание*: игра слов.\n" +
+		"Вторая строка\n\n" +
+		"[5]\n" +
+		"caption: Только подпись\n"
+	if err := os.WriteFile(filepath.Join(dir, NotesTextFile), []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := LoadChapterNotes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]ImageNote{
+		"0":       {Note: "Перед главой"},
+		"003.jpg": {Caption: "Вывеска: «Кафе»", Note: "*Примечание*: игра слов.\nВторая строка"},
+		"5":       {Caption: "Только подпись"},
+	}
+	if !reflect.DeepEqual(notes, want) {
+		t.Errorf("unexpected notes.txt:\n got %+v\nwant %+v", notes, want)
+	}
+
+	// notes.json важнее notes.txt
+	if err := os.WriteFile(filepath.Join(dir, NotesJSONFile), []byte(`{"001.png": {"caption": "Обложка"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	notes, err = LoadChapterNotes(dir)
+	if err != nil || !reflect.DeepEqual(notes, map[string]ImageNote{"001.png": {Caption: "Обложка"}}) {
+		t.Errorf("unexpected notes.json: %+v %v", notes, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, NotesJSONFile), []byte(`{`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadChapterNotes(dir); err == nil {
+		t.Error("expected error for broken notes.json")
+	}
+}
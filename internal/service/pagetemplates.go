@@ -0,0 +1,76 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+// renderPageTemplate подставляет переменные главы и тайтла в шаблон страницы и переводит его
+// в контент Telegraph. Кроме переменных тайтла доступны {{Title}} (заголовок главы),
+// {{Chapter}} (номер главы из заголовка), {{Name}} (название тайтла) и {{Index}} (оглавление).
+// Заголовки и название экранируются под формат шаблона, чтобы «*» или «<» в них не стали разметкой.
+// Адрес оглавления в Markdown вставляется как есть (его ставят в ссылку), переменные тайтла —
+// тоже: их пишет автор шаблона, и разметка в них допустима.
+func renderPageTemplate(tmpl database.Template, title database.Title, chapterTitle string) ([]telegraph.Node, error) {
+	chapter := ""
+	if n, ok := chapterNumber(chapterTitle); ok {
+		chapter = strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	escape := markdownEscaper.Replace
+	index := title.IndexURL
+	if strings.EqualFold(strings.TrimSpace(tmpl.Format), "html") {
+		escape = html.EscapeString
+		index = html.EscapeString(index)
+	}
+	text := strings.NewReplacer(
+		"{{Title}}", escape(chapterTitle),
+		"{{Chapter}}", escape(chapter),
+		"{{Name}}", escape(title.Name),
+		"{{Index}}", index,
+	).Replace(tmpl.Content)
+	return telegraph.ConvertText(tmpl.Format, applyVariables(text, title.Variables))
+}
+
+// markdownEscaper экранирует символы, которые FromMarkdown понимает как разметку
+var markdownEscaper = func() *strings.Replacer {
+	var pairs []string
+	for _, c := range "\\`*_~[]()<>#!-+.|{}" {
+		pairs = append(pairs, string(c), `\`+string(c))
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// pageTemplates — шапка и подвал главы по шаблонам тайтла. Картинки из шаблонов на странице
+// неотличимы от картинок главы, поэтому в шаблонах лучше обходиться текстом и ссылками.
+// Ошибки шаблонов не мешают публикации: блок пропускается, ошибка уходит в предупреждения.
+func (s *PublicationService) pageTemplates(titleID int, chapterTitle string) (header, footer []telegraph.Node, warnings []string) {
+	if titleID <= 0 || s.titleRepo == nil || s.templateRepo == nil {
+		return nil, nil, nil
+	}
+	title, err := s.titleRepo.GetByID(uint(titleID))
+	if err != nil {
+		return nil, nil, nil
+	}
+	render := func(id *uint, block string) []telegraph.Node {
+		if id == nil {
+			return nil
+		}
+		tmpl, err := s.templateRepo.GetByID(*id)
+		if err == nil {
+			var content []telegraph.Node
+			if content, err = renderPageTemplate(tmpl, title, chapterTitle); err == nil {
+				return content
+			}
+		}
+		warnings = append(warnings, fmt.Sprintf("Не удалось подставить %s: %v", block, err))
+		return nil
+	}
+	header = render(title.HeaderTemplateID, "шапку")
+	footer = render(title.FooterTemplateID, "подвал")
+	return header, footer, warnings
+}
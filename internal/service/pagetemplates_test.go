@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+func TestCreatePage_PageTemplates(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	titles := repository.NewTitleRepository(db)
+	templates := repository.NewTemplateRepository(db)
+	s := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, titles, nil, nil, templates)
+
+	if err := titles.Create("Манга", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	all, _ := titles.GetAll()
+	titleID := all[0].ID
+	if err := titles.AddVariable(titleID, "Team", "Команда"); err != nil {
+		t.Fatal(err)
+	}
+	header := database.Template{Name: "Шапка", Kind: database.TemplatePage, Format: "markdown", Content: "**{{Name}}**, глава {{Chapter}}. Перевод: {{Team}}"}
+	footer := database.Template{Name: "Подвал", Kind: database.TemplatePage, Format: "html", Content: `<p><a href="https://t.me/chan">Канал</a></p>`}
+	if err := templates.Add(&header); err != nil {
+		t.Fatal(err)
+	}
+	if err := templates.Add(&footer); err != nil {
+		t.Fatal(err)
+	}
+	if err := titles.SetPageTemplates(titleID, &header.ID, &footer.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := s.CreatePage(context.Background(), "Глава 12.5", []string{"http://img/1", "http://img/2"}, int(titleID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := fake.page(res.URL).Content
+	if len(content) != 4 {
+		t.Fatalf("expected header, 2 images and footer, got %+v", content)
+	}
+	want := `[{"tag":"b","children":["Манга"]},", глава 12.5. Перевод: Команда"]`
+	if got, _ := json.Marshal(content[0].Children); string(got) != want {
+		t.Errorf("unexpected header: %s", got)
+	}
+	if content[3].Children[0].Attrs["href"] != "https://t.me/chan" {
+		t.Errorf("unexpected footer: %+v", content[3])
+	}
+	if item, _ := history.GetByID(res.HistoryID); item.ImgCount != 2 {
+		t.Errorf("template blocks must not count as chapter images, got %d", item.ImgCount)
+	}
+
+	// Разбитая глава: шапка только в первой части, подвал — в последней
+	s.contentLimit = telegraph.ContentSize(content) + chapterNavigationReserve() + telegraph.ContentSize(telegraph.ImageNodes(testImages(5)))
+	res, err = s.CreatePage(context.Background(), "Глава 13", testImages(20), int(titleID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Parts) < 2 {
+		t.Fatalf("expected split chapter, got %v", res.Parts)
+	}
+	first := fake.page(res.Parts[0]).Content
+	last := fake.page(res.Parts[len(res.Parts)-1]).Content
+	if first[0].Tag != "p" || first[0].Children[0].Tag != "b" || last[0].Tag != "img" {
+		t.Errorf("header must open only the first part: %+v / %+v", first[0], last[0])
+	}
+	// В последней части после подвала идёт только навигация по частям (следующей главы нет)
+	if footer := last[len(last)-2]; footer.Children[0].Attrs["href"] != "https://t.me/chan" {
+		t.Errorf("footer must precede part navigation in the last part: %+v", last)
+	}
+	for _, part := range res.Parts[:len(res.Parts)-1] {
+		for _, n := range fake.page(part).Content {
+			if n.Tag == "p" && len(n.Children) > 0 && n.Children[0].Attrs["href"] == "https://t.me/chan" {
+				t.Errorf("footer must be only in the last part, found in %s", part)
+			}
+		}
+	}
+
+	// Сломанный шаблон не мешает публикации
+	if err := templates.Update(database.Template{ID: footer.ID, Name: footer.Name, Kind: footer.Kind, Format: "rst", Content: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	s.contentLimit = telegraph.MaxContentSize
+	res, err = s.CreatePage(context.Background(), "Глава 14", []string{"http://img/3"}, int(titleID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Шапка, картинка и ссылка на предыдущую главу — без подвала
+	if len(res.Warnings) != 1 || len(fake.page(res.URL).Content) != 3 {
+		t.Errorf("expected warning and page without footer, got %v / %+v", res.Warnings, fake.page(res.URL).Content)
+	}
+}
+
+func TestRenderPageTemplate_EscapesValues(t *testing.T) {
+	title := database.Title{Name: "*Манга* <b>", IndexURL: "https://telegra.ph/Index_01-01"}
+	tests := []struct {
+		format, content, want string
+	}{
+		{"markdown", "{{Name}}: {{Title}} [оглавление]({{Index}})",
+			`[{"tag":"p","children":["*Манга* \u003cb\u003e: Глава_1 [бонус] ",{"tag":"a","attrs":{"href":"https://telegra.ph/Index_01-01"},"children":["оглавление"]}]}]`},
+		{"html", `<p>{{Name}}: {{Title}} <a href="{{Index}}">оглавление</a></p>`,
+			`[{"tag":"p","children":["*Манга* \u003cb\u003e: Глава_1 [бонус] ",{"tag":"a","attrs":{"href":"https://telegra.ph/Index_01-01"},"children":["оглавление"]}]}]`},
+	}
+	for _, tt := range tests {
+		content, err := renderPageTemplate(database.Template{Format: tt.format, Content: tt.content}, title, "Глава_1 [бонус]")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if got, _ := json.Marshal(content); string(got) != tt.want {
+			t.Errorf("%s: values must stay plain text, got %s", tt.format, got)
+		}
+	}
+}
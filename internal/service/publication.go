@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -17,27 +18,97 @@ type PublicationService struct {
 	telegram    *telegram.Client
 	historyRepo repository.HistoryRepository
 	titleRepo   repository.TitleRepository
+	accountRepo repository.AccountRepository
+	// revisionRepo — снимки страниц глав для отката (nil — ревизии не ведутся)
+	revisionRepo repository.RevisionRepository
+	// templateRepo — шаблоны шапки и подвала глав (nil — страницы публикуются без них)
+	templateRepo repository.TemplateRepository
+	// contentLimit — предел размера content одной страницы; длинная глава режется на части
+	contentLimit int
 }
 
-func NewPublicationService(tg *telegraph.Client, telegram *telegram.Client, history repository.HistoryRepository, titles repository.TitleRepository) *PublicationService {
+func NewPublicationService(tg *telegraph.Client, telegram *telegram.Client, history repository.HistoryRepository, titles repository.TitleRepository, accounts repository.AccountRepository, revisions repository.RevisionRepository, templates repository.TemplateRepository) *PublicationService {
 	return &PublicationService{
-		tgClient:    tg,
-		telegram:    telegram,
-		historyRepo: history,
-		titleRepo:   titles,
+		tgClient:     tg,
+		telegram:     telegram,
+		historyRepo:  history,
+		titleRepo:    titles,
+		accountRepo:  accounts,
+		revisionRepo: revisions,
+		templateRepo: templates,
+		contentLimit: telegraph.MaxContentSize,
 	}
 }
 
 type PageResult struct {
 	URL       string
 	HistoryID uint
+	// Parts — ссылки на все части, если глава не поместилась на одну страницу
+	Parts []string
+	// Warnings — некритичные ошибки (например, не удалось обновить ссылки у соседних глав)
+	Warnings []string
 }
 
-func (s *PublicationService) CreatePage(title string, images []string, titleID int) (PageResult, error) {
-	url := s.tgClient.CreatePage(title, images)
+// CreatePage публикует главу. Если контент не помещается в лимит Telegraph,
+// глава режется на части «(часть i/N)» со ссылками назад/вперёд, а в историю
+// пишется одна запись со ссылкой на первую часть и списком всех частей.
+// Для глав тайтла добавляются ссылки на соседние главы (по номеру из заголовка),
+// а у соседей — ссылка на новую главу. Шапка и подвал из шаблонов тайтла ставятся
+// перед картинками и после них. Оглавление тайтла (если включено) пересобирается.
+func (s *PublicationService) CreatePage(ctx context.Context, title string, images []string, titleID int) (PageResult, error) {
+	return s.CreatePageWithNotes(ctx, title, images, nil, titleID)
+}
+
+// CreatePageWithNotes — CreatePage с подписями под картинками и заметками переводчика между ними
+// (см. ChapterNotes). Картинка с подписью и заметкой не разрываются между частями.
+func (s *PublicationService) CreatePageWithNotes(ctx context.Context, title string, images []string, notes ChapterNotes, titleID int) (PageResult, error) {
+	prev, next := s.chapterNeighbors(titleID, title)
+	frame := pageFrame{prevURL: itemURL(prev), nextURL: itemURL(next)}
+	var warnings []string
+	frame.header, frame.footer, warnings = s.pageTemplates(titleID, title)
+	blocks, noteWarnings := chapterBlocks(images, notes)
+	warnings = append(warnings, noteWarnings...)
+
+	limit := s.contentLimit - telegraph.ContentSize(frame.header) - telegraph.ContentSize(frame.footer)
+	if prev != nil || next != nil {
+		limit -= chapterNavigationReserve()
+	}
+
+	author := s.authorFor(titleID)
+	var res PageResult
+	var err error
+	if parts := splitBlocks(blocks, limit); len(parts) > 1 {
+		res, err = s.createParts(ctx, title, parts, titleID, author, frame)
+	} else {
+		res, err = s.createSingle(ctx, title, blocks, titleID, author, frame)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	res.Warnings = append(warnings, s.linkNeighbors(ctx, res.URL, prev, next)...)
+	if titleID > 0 {
+		u := uint(titleID)
+		res.Warnings = append(res.Warnings, s.refreshIndex(ctx, &u)...)
+	}
+	return res, nil
+}
+
+// pageFrame — то, что окружает картинки главы: блоки из шаблонов тайтла и ссылки на соседние главы
+type pageFrame struct {
+	header, footer   []telegraph.Node
+	prevURL, nextURL string
+}
 
-	if len(url) < 4 || url[:4] != "http" {
-		return PageResult{}, fmt.Errorf("telegraph error: %s", url)
+// createSingle публикует главу одной страницей
+func (s *PublicationService) createSingle(ctx context.Context, title string, blocks []chapterBlock, titleID int, author telegraph.Author, frame pageFrame) (PageResult, error) {
+	content := append([]telegraph.Node{}, frame.header...)
+	content = append(content, blockNodes(blocks)...)
+	content = append(content, frame.footer...)
+	content = append(content, chapterNavigation(frame.prevURL, frame.nextURL)...)
+	url, err := s.tgClient.CreatePageAs(ctx, title, content, author)
+	if err != nil {
+		return PageResult{}, err
 	}
 
 	var tID *uint
@@ -45,23 +116,235 @@ func (s *PublicationService) CreatePage(title string, images []string, titleID i
 		u := uint(titleID)
 		tID = &u
 	}
-	// TODO: Pass tgphToken if needed, or get from somewhere.
-	// In App it was `a.config.TelegraphToken`.
-	// But `tgClient` has the token. `s.tgClient.Token`.
 
-	id, err := s.historyRepo.Add(title, url, len(images), s.tgClient.Token, tID)
+	id, err := s.historyRepo.Add(title, url, len(blocks), s.tokenOf(author), tID)
+	if err == nil {
+		s.recordRevision(id, pagePath(url), title, content, RevisionCreate)
+	}
 
 	return PageResult{URL: url, HistoryID: id}, err
 }
 
-func (s *PublicationService) EditPage(path string, title string, images []string, token string) string {
-	return s.tgClient.EditPage(path, title, images, token)
+// createParts создаёт страницы частей, затем дописывает в каждую навигацию.
+// Шапка и ссылка на предыдущую главу ставятся в первую часть, подвал и ссылка на следующую — в последнюю.
+func (s *PublicationService) createParts(ctx context.Context, title string, parts [][]chapterBlock, titleID int, author telegraph.Author, frame pageFrame) (PageResult, error) {
+	body := func(i int) []telegraph.Node {
+		var content []telegraph.Node
+		if i == 0 {
+			content = append(content, frame.header...)
+		}
+		content = append(content, blockNodes(parts[i])...)
+		if i == len(parts)-1 {
+			content = append(content, frame.footer...)
+		}
+		return content
+	}
+
+	urls := make([]string, len(parts))
+	for i := range parts {
+		url, err := s.tgClient.CreatePageAs(ctx, partTitle(title, i+1, len(parts)), body(i), author)
+		if err != nil {
+			return PageResult{}, fmt.Errorf("part %d/%d: %w", i+1, len(parts), err)
+		}
+		urls[i] = url
+	}
+
+	// Ссылки на соседние части известны только после создания всех страниц
+	contents := make([][]telegraph.Node, len(parts))
+	for i := range parts {
+		content := append(body(i), partNavigation(urls, i)...)
+		switch i {
+		case 0:
+			content = append(content, chapterNavigation(frame.prevURL, "")...)
+		case len(parts) - 1:
+			content = append(content, chapterNavigation("", frame.nextURL)...)
+		}
+		if _, err := s.tgClient.EditPage(ctx, pagePath(urls[i]), partTitle(title, i+1, len(parts)), content, s.tokenOf(author)); err != nil {
+			return PageResult{}, fmt.Errorf("navigation %d/%d: %w", i+1, len(parts), err)
+		}
+		contents[i] = content
+	}
+
+	var tID *uint
+	if titleID > 0 {
+		u := uint(titleID)
+		tID = &u
+	}
+	images := 0
+	for _, part := range parts {
+		images += len(part)
+	}
+	id, err := s.historyRepo.Add(title, urls[0], images, s.tokenOf(author), tID)
+	if err != nil {
+		return PageResult{URL: urls[0], Parts: urls}, err
+	}
+
+	historyParts := make([]database.HistoryPart, len(parts))
+	for i, part := range parts {
+		historyParts[i] = database.HistoryPart{Part: i + 1, Url: urls[i], ImgCount: len(part)}
+	}
+	err = s.historyRepo.AddParts(id, historyParts)
+	for i := range parts {
+		s.recordRevision(id, pagePath(urls[i]), partTitle(title, i+1, len(parts)), contents[i], RevisionCreate)
+	}
+
+	return PageResult{URL: urls[0], HistoryID: id, Parts: urls}, err
 }
 
-func (s *PublicationService) GetPage(pageUrl string) (string, []string, error) {
+// authorFor — аккаунт и автор, от имени которых публикуются главы тайтла.
+// Без привязки к тайтлу используется аккаунт по умолчанию (токен клиента).
+func (s *PublicationService) authorFor(titleID int) telegraph.Author {
+	if s.accountRepo == nil {
+		return telegraph.Author{}
+	}
+	if titleID > 0 && s.titleRepo != nil {
+		if t, err := s.titleRepo.GetByID(uint(titleID)); err == nil && t.AccountID != nil {
+			if acc, err := s.accountRepo.GetByID(*t.AccountID); err == nil {
+				return telegraph.Author{AccessToken: acc.AccessToken, Name: acc.AuthorName, URL: acc.AuthorURL}
+			}
+		}
+	}
+	if acc, err := s.accountRepo.GetDefault(); err == nil && acc.AccessToken == s.tgClient.AccessToken() {
+		return telegraph.Author{Name: acc.AuthorName, URL: acc.AuthorURL}
+	}
+	return telegraph.Author{}
+}
+
+// tokenOf — токен, которым фактически создана страница (клиент мог создать аккаунт сам)
+func (s *PublicationService) tokenOf(author telegraph.Author) string {
+	if author.AccessToken != "" {
+		return author.AccessToken
+	}
+	return s.tgClient.AccessToken()
+}
+
+// partTitle — заголовок страницы-части
+func partTitle(title string, part, total int) string {
+	return fmt.Sprintf("%s (часть %d/%d)", title, part, total)
+}
+
+// partNavigation — абзац со ссылками на предыдущую и следующую части
+func partNavigation(urls []string, i int) []telegraph.Node {
+	var links []telegraph.Node
+	if i > 0 {
+		links = append(links, telegraph.Link(urls[i-1], telegraph.Text(fmt.Sprintf("← Часть %d", i))))
+	}
+	if i < len(urls)-1 {
+		if len(links) > 0 {
+			links = append(links, telegraph.Text(" | "))
+		}
+		links = append(links, telegraph.Link(urls[i+1], telegraph.Text(fmt.Sprintf("Часть %d →", i+2))))
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return []telegraph.Node{telegraph.Paragraph(links...)}
+}
+
+// navigationReserve — сколько места оставить под навигацию (с запасом на длинные адреса)
+func navigationReserve() int {
+	placeholder := "https://telegra.ph/" + strings.Repeat("x", 200)
+	return telegraph.ContentSize(partNavigation([]string{placeholder, placeholder, placeholder}, 1))
+}
+
+// splitBlocks делит картинки с их подписями и заметками на части, каждая из которых помещается
+// в limit вместе с навигацией. Если всё помещается на одну страницу — возвращается одна часть.
+func splitBlocks(blocks []chapterBlock, limit int) [][]chapterBlock {
+	if limit <= 0 || telegraph.ContentSize(blockNodes(blocks)) <= limit {
+		return [][]chapterBlock{blocks}
+	}
+
+	budget := limit - navigationReserve()
+	var parts [][]chapterBlock
+	var current []chapterBlock
+	size := 2 // []
+	for _, b := range blocks {
+		blockSize := telegraph.ContentSize(b.nodes) - 2 + len(b.nodes) // без [] и с запятыми
+		if len(current) > 0 && size+blockSize > budget {
+			parts = append(parts, current)
+			current, size = nil, 2
+		}
+		current = append(current, b)
+		size += blockSize
+	}
+	if len(current) > 0 {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+// pagePath достаёт путь страницы из её URL
+func pagePath(pageUrl string) string {
 	parts := strings.Split(pageUrl, "/")
-	path := parts[len(parts)-1]
-	return s.tgClient.GetPage(path)
+	return parts[len(parts)-1]
+}
+
+// EditPage приводит картинки страницы к списку images, сохраняя текст, ссылки,
+// подписи и навигацию (см. telegraph.PageEditor).
+func (s *PublicationService) EditPage(ctx context.Context, path string, title string, images []string, token string) (string, error) {
+	return s.EditPageImages(ctx, path, title, token, func(e *telegraph.PageEditor) error {
+		e.SetImages(images)
+		return nil
+	})
+}
+
+// EditPageImages загружает текущий контент страницы, применяет к нему edit и сохраняет.
+// Пустой title оставляет заголовок страницы как есть, пустой token — токен, которым глава создана.
+// Если глава есть в истории, сохраняется ревизия, а при явной смене заголовка неразбитой главы
+// обновляются история и оглавление тайтла; ошибки оглавления только логируются.
+func (s *PublicationService) EditPageImages(ctx context.Context, path string, title string, token string, edit func(e *telegraph.PageEditor) error) (string, error) {
+	page, err := s.tgClient.GetPageContent(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("load %s: %w", path, err)
+	}
+	editor := telegraph.NewPageEditor(page.Content)
+	if err := edit(editor); err != nil {
+		return "", err
+	}
+	// Без явного заголовка (миграция, перезалив) страница сохраняет свой, и главу не переименовываем
+	rename := title != ""
+	if title == "" {
+		title = page.Title
+	}
+
+	var item database.HistoryItem
+	tracked := false
+	if s.historyRepo != nil {
+		if found, err := s.historyRepo.FindByPath(path); err == nil {
+			item, tracked = found, true
+		}
+	}
+	// Основной аккаунт мог смениться после публикации, а править страницу может только её автор
+	if token == "" && tracked {
+		token = item.TgphToken
+	}
+
+	url, err := s.tgClient.EditPage(ctx, path, title, editor.Content(), token)
+	if err != nil || !tracked {
+		return url, err
+	}
+	s.recordBaseline(item.ID, path, page)
+	s.recordRevision(item.ID, path, title, editor.Content(), RevisionEdit)
+	// Оглавление показывает только заголовки глав, правка картинок его не меняет.
+	// У разбитой главы заголовок страницы — заголовок части, он не переносится на главу.
+	if rename && len(item.Parts) == 0 && item.Title != title {
+		if err := s.historyRepo.UpdateTitle(item.ID, title); err != nil {
+			log.Printf("[Publication] Failed to update history title: %v", err)
+		}
+		for _, w := range s.refreshIndex(ctx, item.TitleID) {
+			log.Printf("[Publication] %s", w)
+		}
+	}
+	return url, nil
+}
+
+// GetPage возвращает заголовок и редактируемые картинки страницы
+func (s *PublicationService) GetPage(ctx context.Context, pageUrl string) (string, []string, error) {
+	page, err := s.tgClient.GetPageContent(ctx, pagePath(pageUrl))
+	if err != nil {
+		return "", nil, err
+	}
+	return page.Title, telegraph.PageImages(page.Content), nil
 }
 
 func applyVariables(content string, variables []database.TitleVariable) string {
@@ -92,6 +375,7 @@ func (s *PublicationService) PublishPost(ctx context.Context, historyID uint, ch
 	if item.TitleID != nil && *item.TitleID > 0 {
 		title, err := s.titleRepo.GetByID(*item.TitleID)
 		if err == nil {
+			content = strings.ReplaceAll(content, "{{Index}}", title.IndexURL)
 			content = applyVariables(content, title.Variables)
 		}
 	}
@@ -1,9 +1,20 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
 	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
 )
 
 func TestApplyVariables(t *testing.T) {
@@ -56,3 +67,212 @@ func BenchmarkApplyVariables(b *testing.B) {
 		applyVariables(content, variables)
 	}
 }
+
+func testImages(n int) []string {
+	images := make([]string, n)
+	for i := range images {
+		images[i] = fmt.Sprintf("https://cdn.example.com/%019d_page_%03d.webp", i, i)
+	}
+	return images
+}
+
+func TestSplitImages(t *testing.T) {
+	images := testImages(40)
+	blocks, _ := chapterBlocks(images, nil)
+
+	// Всё помещается — одна часть
+	parts := splitBlocks(blocks, telegraph.MaxContentSize)
+	if len(parts) != 1 || len(parts[0]) != 40 {
+		t.Fatalf("expected single part, got %d", len(parts))
+	}
+
+	limit := telegraph.ContentSize(telegraph.ImageNodes(images)) / 2
+	parts = splitBlocks(blocks, limit)
+	if len(parts) < 3 {
+		t.Fatalf("expected at least 3 parts with navigation reserve, got %d", len(parts))
+	}
+	var total []string
+	for i, p := range parts {
+		content := append(blockNodes(p), partNavigation(make([]string, len(parts)), i)...)
+		if size := telegraph.ContentSize(content); size > limit {
+			t.Errorf("part %d is %d bytes, limit %d", i+1, size, limit)
+		}
+		total = append(total, telegraph.Images(blockNodes(p))...)
+	}
+	if strings.Join(total, ",") != strings.Join(images, ",") {
+		t.Error("parts must keep every image in order")
+	}
+}
+
+func TestPartNavigation(t *testing.T) {
+	urls := []string{"https://telegra.ph/a", "https://telegra.ph/b", "https://telegra.ph/c"}
+
+	nav := partNavigation(urls, 0)
+	if len(nav) != 1 || len(nav[0].Children) != 1 || nav[0].Children[0].Attrs["href"] != urls[1] {
+		t.Errorf("first part must link only to the next one: %+v", nav)
+	}
+	nav = partNavigation(urls, 1)
+	if len(nav[0].Children) != 3 || nav[0].Children[0].Attrs["href"] != urls[0] || nav[0].Children[2].Attrs["href"] != urls[2] {
+		t.Errorf("middle part must link both ways: %+v", nav)
+	}
+	if partNavigation(urls[:1], 0) != nil {
+		t.Error("single page needs no navigation")
+	}
+}
+
+func TestCreatePage_SplitsIntoParts(t *testing.T) {
+	var created, edited int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/createPage":
+			created++
+			fmt.Fprintf(w, `{"ok": true, "result": {"url": "https://telegra.ph/part-%d"}}`, created)
+		case "/editPage":
+			edited++
+			var content []telegraph.Node
+			json.Unmarshal([]byte(r.FormValue("content")), &content)
+			if last := content[len(content)-1]; last.Tag != "p" {
+				t.Errorf("expected navigation paragraph at the end, got %+v", last)
+			}
+			if !strings.Contains(r.FormValue("title"), "(часть ") {
+				t.Errorf("unexpected part title %q", r.FormValue("title"))
+			}
+			fmt.Fprintf(w, `{"ok": true, "result": {"url": "https://telegra.ph/%s"}}`, r.FormValue("path"))
+		}
+	}))
+	defer ts.Close()
+
+	db, err := gorm.Open(sqlite.Open("file:split_parts?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&database.HistoryEntry{}, &database.HistoryPart{}); err != nil {
+		t.Fatal(err)
+	}
+	history := repository.NewHistoryRepository(db)
+
+	s := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+	images := testImages(30)
+	s.contentLimit = telegraph.ContentSize(telegraph.ImageNodes(images)) / 2
+
+	res, err := s.CreatePage(context.Background(), "Глава 1", images, 0)
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if len(res.Parts) < 2 || created != len(res.Parts) || edited != len(res.Parts) {
+		t.Fatalf("expected every part created and edited, got parts=%d created=%d edited=%d", len(res.Parts), created, edited)
+	}
+	if res.URL != "https://telegra.ph/part-1" {
+		t.Errorf("history must point to first part, got %s", res.URL)
+	}
+
+	item, err := history.GetByID(res.HistoryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.ImgCount != 30 || len(item.Parts) != len(res.Parts) || item.Parts[1].Part != 2 {
+		t.Errorf("unexpected history entry: %+v", item)
+	}
+	sum := 0
+	for _, p := range item.Parts {
+		sum += p.ImgCount
+	}
+	if sum != 30 {
+		t.Errorf("parts must cover all images, got %d", sum)
+	}
+}
+
+func TestEditPage_KeepsNonImageContent(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	history := repository.NewHistoryRepository(setupHistoryDB(t))
+	s := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+
+	first, err := s.CreatePage(context.Background(), "Глава 1", []string{"http://img/1"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Страница, отредактированная вручную: подпись и примечание переводчика
+	path := pagePath(first.URL)
+	fake.pages[path].Content = []telegraph.Node{
+		telegraph.Figure("http://img/1", telegraph.Text("обложка")),
+		telegraph.Paragraph(telegraph.Text("Примечание переводчика")),
+		telegraph.Image("http://img/2"),
+	}
+
+	if _, err := s.EditPage(context.Background(), path, "Глава 1", []string{"http://img/2", "http://img/1", "http://img/3"}, "t"); err != nil {
+		t.Fatal(err)
+	}
+	want := []telegraph.Node{
+		telegraph.Image("http://img/2"),
+		telegraph.Paragraph(telegraph.Text("Примечание переводчика")),
+		telegraph.Figure("http://img/1", telegraph.Text("обложка")),
+		telegraph.Image("http://img/3"),
+	}
+	if got := fake.page(first.URL).Content; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected content:\n got %+v\nwant %+v", got, want)
+	}
+
+	title, images, err := s.GetPage(context.Background(), first.URL)
+	if err != nil || title != "Глава 1" || !reflect.DeepEqual(images, []string{"http://img/2", "http://img/1", "http://img/3"}) {
+		t.Errorf("GetPage: %v %q %v", err, title, images)
+	}
+}
+
+func TestEditPage_UsesChapterToken(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	history := repository.NewHistoryRepository(setupHistoryDB(t))
+	client := &telegraph.Client{Token: "first", BaseURL: ts.URL}
+	s := NewPublicationService(client, nil, history, nil, nil, nil, nil)
+
+	ch, err := s.CreatePage(context.Background(), "Глава 1", []string{"http://img/1"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Основной аккаунт сменили после публикации
+	client.SetToken("second")
+
+	if _, err := s.EditPage(context.Background(), pagePath(ch.URL), "", []string{"http://img/2"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.tokens[pagePath(ch.URL)]; got != "first" {
+		t.Errorf("page must be edited with its creator token, got %q", got)
+	}
+}
+
+func TestEditPage_KeepsSplitChapterTitle(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	history := repository.NewHistoryRepository(setupHistoryDB(t))
+	s := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+	images := testImages(30)
+	s.contentLimit = telegraph.ContentSize(telegraph.ImageNodes(images)) / 2
+
+	ch, err := s.CreatePage(context.Background(), "Глава 1", images, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ch.Parts) < 2 {
+		t.Fatalf("expected a split chapter, got %d parts", len(ch.Parts))
+	}
+
+	// Перезалив картинок без заголовка, как при миграции домена
+	if _, err := s.EditPage(context.Background(), pagePath(ch.URL), "", images[:3], ""); err != nil {
+		t.Fatal(err)
+	}
+	item, err := history.GetByID(ch.HistoryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Title != "Глава 1" {
+		t.Errorf("chapter must keep its title, got %q", item.Title)
+	}
+	if got := fake.page(ch.URL).Title; got != partTitle("Глава 1", 1, len(ch.Parts)) {
+		t.Errorf("part must keep its title, got %q", got)
+	}
+}
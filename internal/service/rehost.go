@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"telegraph_uploader_v2/internal/telegraph"
+	"telegraph_uploader_v2/internal/uploader"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// rehostConcurrency — сколько сторонних картинок скачивается одновременно
+const rehostConcurrency = 4
+
+// telegraphOrigin — адрес, относительно которого Telegraph отдаёт свои файлы (/file/...)
+const telegraphOrigin = "https://telegra.ph"
+
+// Rehoster загружает сторонние картинки в наш бакет (см. uploader.R2Uploader.UploadRemote)
+type Rehoster interface {
+	IsHosted(imageURL string) bool
+	UploadRemote(ctx context.Context, imageURL string, settings uploader.ResizeSettings) (string, error)
+}
+
+// RehostFailure — картинка, которую не удалось перенести
+type RehostFailure struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// RehostResult — итог перезаливки страницы
+type RehostResult struct {
+	URL      string          `json:"url"`
+	External int             `json:"external"` // сторонних картинок на странице
+	Rehosted int             `json:"rehosted"`
+	Failed   []RehostFailure `json:"failed"`
+}
+
+// absoluteImageURL дополняет относительные адреса Telegraph (/file/...) до полных
+func absoluteImageURL(src string) string {
+	if strings.HasPrefix(src, "/") && !strings.HasPrefix(src, "//") {
+		return telegraphOrigin + src
+	}
+	return src
+}
+
+// RehostPage скачивает все сторонние картинки страницы, загружает их в бакет
+// и правит страницу на новые адреса. Картинки, которые не удалось скачать или обработать,
+// остаются как есть и попадают в Failed. Страница правится токеном главы из истории.
+func (s *PublicationService) RehostPage(ctx context.Context, pageURL string, rehoster Rehoster, settings uploader.ResizeSettings) (RehostResult, error) {
+	res := RehostResult{URL: pageURL}
+	if rehoster == nil {
+		return res, errors.New("uploader is not available")
+	}
+	path := pagePath(pageURL)
+	_, images, err := s.GetPage(ctx, pageURL)
+	if err != nil {
+		return res, err
+	}
+
+	var external []string
+	seen := map[string]bool{}
+	for _, src := range images {
+		if !rehoster.IsHosted(src) && !seen[src] {
+			seen[src] = true
+			external = append(external, src)
+		}
+	}
+	res.External = len(external)
+	if len(external) == 0 {
+		return res, nil
+	}
+
+	// Скачиваем и загружаем параллельно, ошибки по отдельным картинкам не прерывают остальные
+	var mu sync.Mutex
+	replacements := map[string]string{}
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(rehostConcurrency)
+	for _, src := range external {
+		src := src
+		g.Go(func() error {
+			link, err := rehoster.UploadRemote(gctx, absoluteImageURL(src), settings)
+			if gctx.Err() != nil {
+				return gctx.Err()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				res.Failed = append(res.Failed, RehostFailure{URL: src, Error: err.Error()})
+			} else {
+				replacements[src] = link
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return res, err
+	}
+	if len(replacements) == 0 {
+		return res, nil
+	}
+
+	token := s.tgClient.AccessToken()
+	if s.historyRepo != nil {
+		if item, err := s.historyRepo.FindByPath(path); err == nil && item.TgphToken != "" {
+			token = item.TgphToken
+		}
+	}
+	res.URL, err = s.EditPageImages(ctx, path, "", token, func(e *telegraph.PageEditor) error {
+		for i, src := range e.Images() {
+			if link, ok := replacements[src]; ok {
+				if err := e.Replace(i, link); err != nil {
+					return err
+				}
+				res.Rehosted++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("edit %s: %w", path, err)
+	}
+	return res, nil
+}
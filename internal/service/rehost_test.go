@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+	"telegraph_uploader_v2/internal/uploader"
+)
+
+// fakeRehoster «загружает» в cdn.example.com всё, кроме адресов с dead в имени
+type fakeRehoster struct {
+	mu      sync.Mutex
+	fetched []string
+}
+
+func (f *fakeRehoster) IsHosted(imageURL string) bool {
+	return strings.HasPrefix(imageURL, "https://cdn.example.com/")
+}
+
+func (f *fakeRehoster) UploadRemote(ctx context.Context, imageURL string, settings uploader.ResizeSettings) (string, error) {
+	f.mu.Lock()
+	f.fetched = append(f.fetched, imageURL)
+	f.mu.Unlock()
+	if strings.Contains(imageURL, "dead") {
+		return "", errors.New("download: 404 Not Found")
+	}
+	return "https://cdn.example.com/" + imageURL[strings.LastIndex(imageURL, "/")+1:], nil
+}
+
+func TestRehostPage(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	history := repository.NewHistoryRepository(setupHistoryDB(t))
+	s := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+	ch, err := s.CreatePage(context.Background(), "Глава 1", []string{
+		"/file/abc.jpg",
+		"https://cdn.example.com/ours.webp",
+		"https://i.imgur.com/dead.png",
+		"https://i.imgur.com/x.png",
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rehoster := &fakeRehoster{}
+	res, err := s.RehostPage(context.Background(), ch.URL, rehoster, uploader.ResizeSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.External != 3 || res.Rehosted != 2 || len(res.Failed) != 1 || res.Failed[0].URL != "https://i.imgur.com/dead.png" {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if !slices.Contains(rehoster.fetched, "https://telegra.ph/file/abc.jpg") {
+		t.Errorf("relative Telegraph files must be fetched from telegra.ph, got %v", rehoster.fetched)
+	}
+
+	want := []string{
+		"https://cdn.example.com/abc.jpg",
+		"https://cdn.example.com/ours.webp",
+		"https://i.imgur.com/dead.png",
+		"https://cdn.example.com/x.png",
+	}
+	if got := telegraph.PageImages(fake.page(ch.URL).Content); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected page images %v", got)
+	}
+}
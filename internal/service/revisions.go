@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+// Причины появления ревизии
+const (
+	RevisionCreate     = "create"
+	RevisionEdit       = "edit"
+	RevisionNavigation = "navigation"
+	RevisionRollback   = "rollback"
+	// RevisionBaseline — контент до первой правки страницы, опубликованной без ревизий
+	RevisionBaseline = "baseline"
+)
+
+// RevisionDiff — разница между двумя ревизиями одной страницы
+type RevisionDiff struct {
+	From     uint   `json:"from"`
+	To       uint   `json:"to"`
+	OldTitle string `json:"old_title"`
+	NewTitle string `json:"new_title"`
+	// Added и Removed — картинки, которых нет в старой / новой ревизии
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	// Moved — общие картинки, сменившие порядок (в порядке новой ревизии)
+	Moved []string `json:"moved"`
+}
+
+// recordRevision сохраняет снимок страницы главы. Ошибка только логируется:
+// страница в Telegraph уже изменена, откатывать её из-за истории ревизий не нужно.
+func (s *PublicationService) recordRevision(historyID uint, path, title string, content []telegraph.Node, reason string) {
+	if s.revisionRepo == nil || historyID == 0 {
+		return
+	}
+	data, err := json.Marshal(content)
+	if err == nil {
+		err = s.revisionRepo.Add(&database.PageRevision{
+			HistoryID: historyID,
+			Path:      path,
+			Title:     title,
+			Content:   string(data),
+			ImgCount:  len(telegraph.PageImages(content)),
+			Reason:    reason,
+		})
+	}
+	if err != nil {
+		log.Printf("[Publication] Failed to save revision of %s: %v", path, err)
+	}
+}
+
+// recordBaseline сохраняет контент страницы перед правкой, если ревизий у неё ещё нет
+// (глава опубликована до появления истории ревизий): иначе первую правку нечем откатить.
+func (s *PublicationService) recordBaseline(historyID uint, path string, page *telegraph.Page) {
+	if s.revisionRepo == nil || historyID == 0 {
+		return
+	}
+	revs, err := s.revisionRepo.ForHistory(historyID)
+	if err != nil {
+		log.Printf("[Publication] Failed to load revisions of %s: %v", path, err)
+		return
+	}
+	for _, rev := range revs {
+		if rev.Path == path {
+			return
+		}
+	}
+	s.recordRevision(historyID, path, page.Title, page.Content, RevisionBaseline)
+}
+
+// Revisions — ревизии страниц главы, новые первыми
+func (s *PublicationService) Revisions(historyID uint) ([]database.PageRevision, error) {
+	if s.revisionRepo == nil {
+		return nil, nil
+	}
+	return s.revisionRepo.ForHistory(historyID)
+}
+
+// revisionContent разбирает сохранённый контент ревизии
+func revisionContent(rev database.PageRevision) ([]telegraph.Node, error) {
+	var content []telegraph.Node
+	if err := json.Unmarshal([]byte(rev.Content), &content); err != nil {
+		return nil, fmt.Errorf("revision %d: %w", rev.ID, err)
+	}
+	return content, nil
+}
+
+// DiffRevisions сравнивает картинки и заголовок двух ревизий одной страницы
+func (s *PublicationService) DiffRevisions(fromID, toID uint) (RevisionDiff, error) {
+	if s.revisionRepo == nil {
+		return RevisionDiff{}, errors.New("revisions are not available")
+	}
+	from, err := s.revisionRepo.GetByID(fromID)
+	if err != nil {
+		return RevisionDiff{}, err
+	}
+	to, err := s.revisionRepo.GetByID(toID)
+	if err != nil {
+		return RevisionDiff{}, err
+	}
+	if from.HistoryID != to.HistoryID || from.Path != to.Path {
+		return RevisionDiff{}, fmt.Errorf("revisions %d and %d belong to different pages", fromID, toID)
+	}
+
+	fromContent, err := revisionContent(from)
+	if err != nil {
+		return RevisionDiff{}, err
+	}
+	toContent, err := revisionContent(to)
+	if err != nil {
+		return RevisionDiff{}, err
+	}
+
+	diff := RevisionDiff{From: fromID, To: toID, OldTitle: from.Title, NewTitle: to.Title}
+	diff.Added, diff.Removed, diff.Moved = diffImages(telegraph.PageImages(fromContent), telegraph.PageImages(toContent))
+	return diff, nil
+}
+
+// diffImages находит добавленные, удалённые и переставленные картинки.
+// Переставленными считаются общие картинки вне наибольшей общей подпоследовательности.
+// Повторы одного адреса учитываются поштучно.
+func diffImages(before, after []string) (added, removed, moved []string) {
+	oldLeft := counts(before)
+	newLeft := counts(after)
+
+	var oldCommon, newCommon []string
+	for _, u := range before {
+		if newLeft[u] > 0 {
+			newLeft[u]--
+			oldCommon = append(oldCommon, u)
+		} else {
+			removed = append(removed, u)
+		}
+	}
+	for _, u := range after {
+		if oldLeft[u] > 0 {
+			oldLeft[u]--
+			newCommon = append(newCommon, u)
+		} else {
+			added = append(added, u)
+		}
+	}
+
+	// LCS по общим картинкам: lcs[i][j] — длина для oldCommon[i:] и newCommon[j:]
+	lcs := make([][]int, len(oldCommon)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newCommon)+1)
+	}
+	for i := len(oldCommon) - 1; i >= 0; i-- {
+		for j := len(newCommon) - 1; j >= 0; j-- {
+			if oldCommon[i] == newCommon[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; j < len(newCommon); {
+		switch {
+		case i < len(oldCommon) && oldCommon[i] == newCommon[j]:
+			i++
+			j++
+		case i < len(oldCommon) && lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			moved = append(moved, newCommon[j])
+			j++
+		}
+	}
+	return added, removed, moved
+}
+
+func counts(urls []string) map[string]int {
+	m := make(map[string]int, len(urls))
+	for _, u := range urls {
+		m[u]++
+	}
+	return m
+}
+
+// RollbackRevision возвращает страницу к сохранённой ревизии через editPage.
+// Навигация по главам остаётся текущей. Откат сам записывается новой ревизией, так что его тоже можно отменить.
+func (s *PublicationService) RollbackRevision(ctx context.Context, revisionID uint) (string, error) {
+	if s.revisionRepo == nil {
+		return "", errors.New("revisions are not available")
+	}
+	rev, err := s.revisionRepo.GetByID(revisionID)
+	if err != nil {
+		return "", err
+	}
+	item, err := s.historyRepo.GetByID(rev.HistoryID)
+	if err != nil {
+		return "", fmt.Errorf("history %d: %w", rev.HistoryID, err)
+	}
+	content, err := revisionContent(rev)
+	if err != nil {
+		return "", err
+	}
+	// Соседние главы могли появиться или пропасть после ревизии: навигация берётся с живой страницы
+	page, err := s.tgClient.GetPageContent(ctx, rev.Path)
+	if err != nil {
+		return "", fmt.Errorf("load %s: %w", rev.Path, err)
+	}
+	_, prevURL, nextURL := findChapterNavigation(page.Content)
+	content = withChapterNavigation(content, prevURL, nextURL)
+
+	token := item.TgphToken
+	if token == "" {
+		token = s.tgClient.AccessToken()
+	}
+	url, err := s.tgClient.EditPage(ctx, rev.Path, rev.Title, content, token)
+	if err != nil {
+		return "", err
+	}
+	s.recordRevision(item.ID, rev.Path, rev.Title, content, RevisionRollback)
+
+	// У разбитой главы заголовки частей с суффиксом «(часть i/N)», историю не трогаем
+	if len(item.Parts) == 0 && item.Title != rev.Title {
+		if err := s.historyRepo.UpdateTitle(item.ID, rev.Title); err != nil {
+			log.Printf("[Publication] Failed to update history title: %v", err)
+		}
+		for _, w := range s.refreshIndex(ctx, item.TitleID) {
+			log.Printf("[Publication] %s", w)
+		}
+	}
+	return url, nil
+}
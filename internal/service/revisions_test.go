@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+func TestDiffImages(t *testing.T) {
+	added, removed, moved := diffImages(
+		[]string{"a", "b", "c", "d", "x"},
+		[]string{"b", "a", "c", "new", "d"},
+	)
+	if !reflect.DeepEqual(added, []string{"new"}) || !reflect.DeepEqual(removed, []string{"x"}) {
+		t.Errorf("unexpected added %v / removed %v", added, removed)
+	}
+	if len(moved) != 1 || (moved[0] != "a" && moved[0] != "b") {
+		t.Errorf("expected one of swapped images moved, got %v", moved)
+	}
+
+	// Повторы считаются поштучно
+	added, removed, moved = diffImages([]string{"a", "a"}, []string{"a"})
+	if added != nil || !reflect.DeepEqual(removed, []string{"a"}) || moved != nil {
+		t.Errorf("duplicates: %v %v %v", added, removed, moved)
+	}
+}
+
+func TestRevisions_EditDiffRollback(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	s := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, repository.NewRevisionRepository(db), nil)
+
+	ch, err := s.CreatePage(context.Background(), "Глава 1", []string{"http://img/1", "http://img/2"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := pagePath(ch.URL)
+	if _, err := s.EditPage(context.Background(), path, "Глава 1: сломано", []string{"http://img/2", "http://img/3"}, "t"); err != nil {
+		t.Fatal(err)
+	}
+
+	revs, err := s.Revisions(ch.HistoryID)
+	if err != nil || len(revs) != 2 || revs[0].Reason != RevisionEdit || revs[1].Reason != RevisionCreate {
+		t.Fatalf("expected create and edit revisions: %v %+v", err, revs)
+	}
+
+	diff, err := s.DiffRevisions(revs[1].ID, revs[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(diff.Added, []string{"http://img/3"}) || !reflect.DeepEqual(diff.Removed, []string{"http://img/1"}) || diff.NewTitle != "Глава 1: сломано" {
+		t.Errorf("unexpected diff %+v", diff)
+	}
+
+	if _, err := s.RollbackRevision(context.Background(), revs[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	page := fake.page(ch.URL)
+	if page.Title != "Глава 1" || !reflect.DeepEqual(telegraph.PageImages(page.Content), []string{"http://img/1", "http://img/2"}) {
+		t.Errorf("page must be restored, got %q %v", page.Title, telegraph.PageImages(page.Content))
+	}
+	if item, _ := history.GetByID(ch.HistoryID); item.Title != "Глава 1" {
+		t.Errorf("history title must be restored, got %q", item.Title)
+	}
+	if revs, _ := s.Revisions(ch.HistoryID); len(revs) != 3 || revs[0].Reason != RevisionRollback {
+		t.Errorf("rollback must be recorded as a revision, got %+v", revs)
+	}
+}
+
+func TestRevisions_BaselineBeforeFirstEdit(t *testing.T) {
+	_, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	client := &telegraph.Client{Token: "t", BaseURL: ts.URL}
+
+	// Глава опубликована, когда ревизии ещё не сохранялись
+	old := NewPublicationService(client, nil, history, nil, nil, nil, nil)
+	ch, err := old.CreatePage(context.Background(), "Глава 1", []string{"http://img/1"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewPublicationService(client, nil, history, nil, nil, repository.NewRevisionRepository(db), nil)
+	for _, images := range [][]string{{"http://img/2"}, {"http://img/3"}} {
+		if _, err := s.EditPage(context.Background(), pagePath(ch.URL), "", images, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revs, err := s.Revisions(ch.HistoryID)
+	if err != nil || len(revs) != 3 || revs[2].Reason != RevisionBaseline {
+		t.Fatalf("expected baseline and two edits: %v %+v", err, revs)
+	}
+	if _, err := s.RollbackRevision(context.Background(), revs[2].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, images, _ := s.GetPage(context.Background(), ch.URL); !reflect.DeepEqual(images, []string{"http://img/1"}) {
+		t.Errorf("rollback to baseline must restore original images, got %v", images)
+	}
+}
+
+func TestRevisions_SplitChapterPart(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	client := &telegraph.Client{Token: "first", BaseURL: ts.URL}
+	s := NewPublicationService(client, nil, history, nil, nil, repository.NewRevisionRepository(db), nil)
+	images := testImages(30)
+	s.contentLimit = telegraph.ContentSize(telegraph.ImageNodes(images)) / 2
+
+	ch, err := s.CreatePage(context.Background(), "Глава 1", images, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ch.Parts) < 2 {
+		t.Fatalf("expected a split chapter, got %d parts", len(ch.Parts))
+	}
+	second := pagePath(ch.Parts[1])
+	client.SetToken("second")
+
+	if _, err := s.EditPage(context.Background(), second, "", []string{"http://img/1"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.tokens[second]; got != "first" {
+		t.Errorf("part must be edited with the chapter token, got %q", got)
+	}
+
+	revs, err := s.Revisions(ch.HistoryID)
+	if err != nil || revs[0].Path != second || revs[0].Reason != RevisionEdit {
+		t.Fatalf("part edit must be saved as a chapter revision: %v %+v", err, revs)
+	}
+	var created uint
+	for _, rev := range revs {
+		if rev.Path == second && rev.Reason == RevisionCreate {
+			created = rev.ID
+		}
+	}
+	if _, err := s.RollbackRevision(context.Background(), created); err != nil {
+		t.Fatal(err)
+	}
+	if _, got, _ := s.GetPage(context.Background(), ch.Parts[1]); reflect.DeepEqual(got, []string{"http://img/1"}) {
+		t.Error("rollback must restore the part images")
+	}
+	if item, _ := history.GetByID(ch.HistoryID); item.Title != "Глава 1" {
+		t.Errorf("chapter must keep its title, got %q", item.Title)
+	}
+}
+
+func TestRevisions_RollbackKeepsNavigation(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	client := &telegraph.Client{Token: "t", BaseURL: ts.URL}
+	s := NewPublicationService(client, nil, history, repository.NewTitleRepository(db), nil, repository.NewRevisionRepository(db), nil)
+
+	ch1, err := s.CreatePage(context.Background(), "Глава 1", []string{"http://img/1"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revs, err := s.Revisions(ch1.HistoryID)
+	if err != nil || len(revs) != 1 {
+		t.Fatalf("expected the create revision: %v %+v", err, revs)
+	}
+	// Глава 2 дописывает в главу 1 ссылку «вперёд», которой нет в ревизии создания
+	ch2, err := s.CreatePage(context.Background(), "Глава 2", []string{"http://img/2"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.RollbackRevision(context.Background(), revs[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, nextURL := findChapterNavigation(fake.page(ch1.URL).Content); nextURL != ch2.URL {
+		t.Errorf("rollback must keep the link to the next chapter, got %q", nextURL)
+	}
+}
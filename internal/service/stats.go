@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"telegraph_uploader_v2/internal/database"
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+// DefaultViewsInterval — как часто сборщик опрашивает getViews
+const DefaultViewsInterval = 6 * time.Hour
+
+// StatsService периодически собирает просмотры страниц из истории и хранит их снимки
+type StatsService struct {
+	tgClient    *telegraph.Client
+	historyRepo repository.HistoryRepository
+	viewsRepo   repository.ViewsRepository
+	interval    time.Duration
+	now         func() time.Time
+}
+
+func NewStatsService(tg *telegraph.Client, history repository.HistoryRepository, views repository.ViewsRepository) *StatsService {
+	return &StatsService{
+		tgClient:    tg,
+		historyRepo: history,
+		viewsRepo:   views,
+		interval:    DefaultViewsInterval,
+		now:         time.Now,
+	}
+}
+
+// Start запускает сбор сразу и затем каждые interval, пока не отменён ctx
+func (s *StatsService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			if n, err := s.Collect(ctx); err != nil {
+				log.Printf("[Stats] Collect failed after %d pages: %v", n, err)
+			} else {
+				log.Printf("[Stats] Collected views for %d pages", n)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Collect делает один проход по истории: для каждой главы берёт просмотры за всё время
+// и за текущие год, месяц, день и час (у разбитой — сумму по частям). Возвращает число сохранённых снимков.
+// Ошибка по отдельной странице не прерывает проход.
+func (s *StatsService) Collect(ctx context.Context) (int, error) {
+	items, err := s.historyRepo.All()
+	if err != nil {
+		return 0, err
+	}
+
+	takenAt := s.now().Truncate(time.Minute)
+	year, month, day, hour := telegraph.PeriodsAt(takenAt)
+	periods := []telegraph.ViewsPeriod{{}, year, month, day, hour}
+
+	snaps := make([]database.ViewSnapshot, 0, len(items))
+	var failed int
+	for _, entry := range items {
+		if err := ctx.Err(); err != nil {
+			return len(snaps), s.viewsRepo.AddSnapshots(snaps)
+		}
+
+		// All не загружает части, а просмотры разбитой главы — сумма по всем её страницам
+		item, err := s.historyRepo.GetByID(entry.ID)
+		if err != nil {
+			failed++
+			continue
+		}
+		views, err := s.chapterViews(ctx, item, periods)
+		if err != nil {
+			failed++
+			continue
+		}
+		snaps = append(snaps, database.ViewSnapshot{
+			HistoryID: item.ID,
+			TitleID:   item.TitleID,
+			TakenAt:   takenAt,
+			Total:     views[0],
+			Year:      views[1],
+			Month:     views[2],
+			Day:       views[3],
+			Hour:      views[4],
+		})
+	}
+
+	if err := s.viewsRepo.AddSnapshots(snaps); err != nil {
+		return 0, err
+	}
+	if failed > 0 && len(snaps) == 0 {
+		return 0, fmt.Errorf("getViews failed for all %d pages", failed)
+	}
+	return len(snaps), nil
+}
+
+// chapterViews суммирует просмотры страниц главы по каждому из периодов
+func (s *StatsService) chapterViews(ctx context.Context, item database.HistoryItem, periods []telegraph.ViewsPeriod) ([5]int, error) {
+	var views [5]int
+	for _, pageURL := range historyPages(item) {
+		for i, p := range periods {
+			n, err := s.tgClient.GetViews(ctx, pagePath(pageURL), p)
+			if err != nil {
+				return views, err
+			}
+			views[i] += n
+		}
+	}
+	return views, nil
+}
+
+// PageViews — ряд просмотров главы за последние days дней
+func (s *StatsService) PageViews(historyID uint, days int) ([]database.ViewPoint, error) {
+	return s.viewsRepo.ForHistory(historyID, s.since(days))
+}
+
+// TitleViews — суммарные просмотры глав тайтла за последние days дней
+func (s *StatsService) TitleViews(titleID uint, days int) ([]database.ViewPoint, error) {
+	return s.viewsRepo.ForTitle(titleID, s.since(days))
+}
+
+// LatestViews — последние собранные просмотры по каждой главе
+func (s *StatsService) LatestViews() (map[uint]int, error) {
+	return s.viewsRepo.Latest()
+}
+
+func (s *StatsService) since(days int) time.Time {
+	if days <= 0 {
+		return time.Time{}
+	}
+	return s.now().AddDate(0, 0, -days)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+func TestStatsService_Collect(t *testing.T) {
+	_, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	titles := repository.NewTitleRepository(db)
+	client := &telegraph.Client{Token: "main", BaseURL: ts.URL}
+	pub := NewPublicationService(client, nil, history, titles, nil, nil, nil)
+
+	titles.Create("Manga", "")
+	all, _ := titles.GetAll()
+	ch1, _ := pub.CreatePage(context.Background(), "Глава 1", []string{"http://img/1"}, int(all[0].ID))
+	ch2, _ := pub.CreatePage(context.Background(), "Глава 2", []string{"http://img/2"}, int(all[0].ID))
+	// Страница, которой уже нет, не должна ломать проход
+	history.Add("Удалённая", "https://telegra.ph/missing", 1, "main", nil)
+
+	stats := NewStatsService(client, history, repository.NewViewsRepository(db))
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	stats.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		n, err := stats.Collect(context.Background())
+		if err != nil || n != 2 {
+			t.Fatalf("collect %d: %d %v", i, n, err)
+		}
+		now = now.Add(time.Hour)
+	}
+
+	points, err := stats.PageViews(ch1.HistoryID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 || points[0].Total != 100 || points[0].Day != 12 || points[0].Hour != 6 {
+		t.Errorf("unexpected page series: %+v", points)
+	}
+
+	titlePoints, err := stats.TitleViews(all[0].ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(titlePoints) != 2 || titlePoints[1].Total != 200 || !titlePoints[1].TakenAt.After(titlePoints[0].TakenAt) {
+		t.Errorf("unexpected title series: %+v", titlePoints)
+	}
+
+	latest, err := stats.LatestViews()
+	if err != nil || latest[ch2.HistoryID] != 100 {
+		t.Errorf("unexpected latest views: %v %v", latest, err)
+	}
+}
+
+func TestStatsService_CollectSplitChapter(t *testing.T) {
+	_, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	db := setupHistoryDB(t)
+	history := repository.NewHistoryRepository(db)
+	client := &telegraph.Client{Token: "main", BaseURL: ts.URL}
+	pub := NewPublicationService(client, nil, history, nil, nil, nil, nil)
+	images := testImages(30)
+	pub.contentLimit = telegraph.ContentSize(telegraph.ImageNodes(images)) / 2
+
+	ch, err := pub.CreatePage(context.Background(), "Глава 1", images, 0)
+	if err != nil || len(ch.Parts) < 2 {
+		t.Fatalf("expected a split chapter: %v %d", err, len(ch.Parts))
+	}
+
+	stats := NewStatsService(client, history, repository.NewViewsRepository(db))
+	if n, err := stats.Collect(context.Background()); err != nil || n != 1 {
+		t.Fatalf("collect: %d %v", n, err)
+	}
+	latest, err := stats.LatestViews()
+	if err != nil || latest[ch.HistoryID] != 100*len(ch.Parts) {
+		t.Errorf("views of a split chapter must sum all parts, got %v %v", latest, err)
+	}
+}
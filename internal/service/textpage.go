@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+// CreateTextPage публикует текстовую страницу (анонс, правила, список глав…) из Markdown или HTML.
+// Разметка переводится в контент Telegraph (неподдерживаемые теги понижаются), страница пишется
+// в историю с числом картинок в тексте. К тайтлу она не привязывается, поэтому не попадает
+// в навигацию между главами и оглавление.
+func (s *PublicationService) CreateTextPage(ctx context.Context, title, format, source string) (PageResult, error) {
+	if strings.TrimSpace(title) == "" {
+		return PageResult{}, errors.New("title is required")
+	}
+	content, err := telegraph.ConvertText(format, source)
+	if err != nil {
+		return PageResult{}, err
+	}
+	if len(content) == 0 {
+		return PageResult{}, errors.New("page text is empty")
+	}
+	if size := telegraph.ContentSize(content); size > s.contentLimit {
+		return PageResult{}, fmt.Errorf("page text is too large: %d bytes, limit %d", size, s.contentLimit)
+	}
+
+	author := s.authorFor(0)
+	url, err := s.tgClient.CreatePageAs(ctx, title, content, author)
+	if err != nil {
+		return PageResult{}, err
+	}
+	id, err := s.historyRepo.Add(title, url, len(telegraph.Images(content)), s.tokenOf(author), nil)
+	if err == nil {
+		s.recordRevision(id, pagePath(url), title, content, RevisionCreate)
+	}
+	return PageResult{URL: url, HistoryID: id}, err
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"telegraph_uploader_v2/internal/repository"
+	"telegraph_uploader_v2/internal/telegraph"
+)
+
+func TestCreateTextPage(t *testing.T) {
+	fake, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	history := repository.NewHistoryRepository(setupHistoryDB(t))
+	s := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+
+	src := "# Анонс\n\nНовая глава **уже** на сайте.\n\n![](https://img/cover.jpg)\n\n<script>x</script>"
+	res, err := s.CreateTextPage(context.Background(), "Анонс", "markdown", src)
+	if err != nil {
+		t.Fatalf("CreateTextPage failed: %v", err)
+	}
+
+	page := fake.page(res.URL)
+	if page.Title != "Анонс" || len(page.Content) != 4 || page.Content[0].Tag != "h3" || page.Content[2].Tag != "img" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+	if err := telegraph.Validate(page.Content); err != nil {
+		t.Errorf("invalid content: %v", err)
+	}
+
+	item, err := history.GetByID(res.HistoryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Url != res.URL || item.ImgCount != 1 || item.TitleID != nil || item.TgphToken != "t" {
+		t.Errorf("unexpected history entry: %+v", item)
+	}
+
+	res, err = s.CreateTextPage(context.Background(), "HTML", "html", `<h1>Правила</h1><div onclick="x()">Не <font>выкладывать</font> сканы</div>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.page(res.URL).Content; len(got) != 2 || got[0].Tag != "h3" || got[1].Tag != "p" {
+		t.Errorf("unexpected html content: %+v", got)
+	}
+}
+
+func TestCreateTextPage_Errors(t *testing.T) {
+	_, ts := newFakeTelegraph(t)
+	defer ts.Close()
+
+	history := repository.NewHistoryRepository(setupHistoryDB(t))
+	s := NewPublicationService(&telegraph.Client{Token: "t", BaseURL: ts.URL}, nil, history, nil, nil, nil, nil)
+	ctx := context.Background()
+
+	if _, err := s.CreateTextPage(ctx, "", "markdown", "text"); err == nil {
+		t.Error("expected error for empty title")
+	}
+	if _, err := s.CreateTextPage(ctx, "Пусто", "markdown", "  \n\n"); err == nil {
+		t.Error("expected error for empty text")
+	}
+	if _, err := s.CreateTextPage(ctx, "Формат", "rst", "text"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	s.contentLimit = 100
+	if _, err := s.CreateTextPage(ctx, "Большой", "markdown", strings.Repeat("слово ", 100)); err == nil {
+		t.Error("expected error for oversized text")
+	}
+	if items, _ := history.All(); len(items) != 0 {
+		t.Errorf("failed pages must not reach history, got %d", len(items))
+	}
+}
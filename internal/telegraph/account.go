@@ -0,0 +1,177 @@
+package telegraph
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultShortName и DefaultAuthorName — данные аккаунта, который создаётся автоматически при отсутствии токена
+const (
+	DefaultShortName  = "MangaUploader"
+	DefaultAuthorName = "MangaBot"
+)
+
+// Account — аккаунт Telegraph (ответ createAccount/getAccountInfo/editAccountInfo/revokeAccessToken)
+type Account struct {
+	ShortName   string `json:"short_name"`
+	AuthorName  string `json:"author_name"`
+	AuthorURL   string `json:"author_url"`
+	AccessToken string `json:"access_token,omitempty"`
+	AuthURL     string `json:"auth_url,omitempty"`
+	PageCount   int    `json:"page_count,omitempty"`
+}
+
+// Author — от чьего имени создаётся страница. Пустой AccessToken — токен клиента.
+type Author struct {
+	AccessToken string
+	Name        string
+	URL         string
+}
+
+// CreateAccount создаёт новый аккаунт Telegraph
+func (c *Client) CreateAccount(ctx context.Context, shortName, authorName, authorURL string) (*Account, error) {
+	data := url.Values{}
+	data.Set("short_name", shortName)
+	data.Set("author_name", authorName)
+	data.Set("author_url", authorURL)
+
+	var acc Account
+	if err := c.call(ctx, "createAccount", data, &acc); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+// GetAccountInfo возвращает данные аккаунта вместе с числом страниц
+func (c *Client) GetAccountInfo(ctx context.Context, token string) (*Account, error) {
+	data := url.Values{}
+	data.Set("access_token", token)
+	data.Set("fields", `["short_name","author_name","author_url","auth_url","page_count"]`)
+
+	var acc Account
+	if err := c.call(ctx, "getAccountInfo", data, &acc); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+// EditAccountInfo меняет имя и автора аккаунта
+func (c *Client) EditAccountInfo(ctx context.Context, token, shortName, authorName, authorURL string) (*Account, error) {
+	data := url.Values{}
+	data.Set("access_token", token)
+	data.Set("short_name", shortName)
+	data.Set("author_name", authorName)
+	data.Set("author_url", authorURL)
+
+	var acc Account
+	if err := c.call(ctx, "editAccountInfo", data, &acc); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+// RevokeAccessToken отзывает токен и выдаёт новый. Старый токен после этого не работает,
+// поэтому все сохранённые копии нужно заменить на acc.AccessToken.
+func (c *Client) RevokeAccessToken(ctx context.Context, token string) (*Account, error) {
+	data := url.Values{}
+	data.Set("access_token", token)
+
+	var acc Account
+	if err := c.call(ctx, "revokeAccessToken", data, &acc); err != nil {
+		return nil, err
+	}
+	if acc.AccessToken == "" {
+		return nil, fmt.Errorf("revokeAccessToken: пустой токен в ответе")
+	}
+	return &acc, nil
+}
+
+// PageInfo — страница из getPageList (без контента)
+type PageInfo struct {
+	Path        string `json:"path"`
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Views       int    `json:"views"`
+	ImageURL    string `json:"image_url"`
+}
+
+// PageListLimit — максимум страниц за один вызов getPageList
+const PageListLimit = 200
+
+// GetPageList возвращает страницу списка страниц аккаунта (от новых к старым) и их общее число
+func (c *Client) GetPageList(ctx context.Context, token string, offset, limit int) ([]PageInfo, int, error) {
+	data := url.Values{}
+	data.Set("access_token", token)
+	data.Set("offset", strconv.Itoa(offset))
+	data.Set("limit", strconv.Itoa(limit))
+
+	var list struct {
+		TotalCount int        `json:"total_count"`
+		Pages      []PageInfo `json:"pages"`
+	}
+	if err := c.call(ctx, "getPageList", data, &list); err != nil {
+		return nil, 0, err
+	}
+	return list.Pages, list.TotalCount, nil
+}
+
+// AllPages выгружает все страницы аккаунта, проходя getPageList постранично
+func (c *Client) AllPages(ctx context.Context, token string) ([]PageInfo, error) {
+	var pages []PageInfo
+	for {
+		batch, total, err := c.GetPageList(ctx, token, len(pages), PageListLimit)
+		if err != nil {
+			return pages, err
+		}
+		pages = append(pages, batch...)
+		if len(batch) == 0 || len(pages) >= total {
+			return pages, nil
+		}
+	}
+}
+
+// ViewsPeriod — период для getViews. Нулевые поля не передаются: Day требует Month, Month — Year.
+// Hour учитывается только при Hourly (час 0 — валидное значение).
+type ViewsPeriod struct {
+	Year, Month, Day, Hour int
+	Hourly                 bool
+}
+
+// PeriodsAt — год, месяц, день и час, в которые попадает t (для разбивки просмотров)
+func PeriodsAt(t time.Time) (year, month, day, hour ViewsPeriod) {
+	year = ViewsPeriod{Year: t.Year()}
+	month = ViewsPeriod{Year: t.Year(), Month: int(t.Month())}
+	day = ViewsPeriod{Year: t.Year(), Month: int(t.Month()), Day: t.Day()}
+	hour = ViewsPeriod{Year: t.Year(), Month: int(t.Month()), Day: t.Day(), Hour: t.Hour(), Hourly: true}
+	return
+}
+
+// GetViews возвращает число просмотров страницы за всё время или за период
+func (c *Client) GetViews(ctx context.Context, path string, period ViewsPeriod) (int, error) {
+	data := url.Values{}
+	data.Set("path", path)
+	if period.Year > 0 {
+		data.Set("year", strconv.Itoa(period.Year))
+		if period.Month > 0 {
+			data.Set("month", strconv.Itoa(period.Month))
+			if period.Day > 0 {
+				data.Set("day", strconv.Itoa(period.Day))
+				if period.Hourly {
+					data.Set("hour", strconv.Itoa(period.Hour))
+				}
+			}
+		}
+	}
+
+	var res struct {
+		Views int `json:"views"`
+	}
+	if err := c.call(ctx, "getViews", data, &res); err != nil {
+		return 0, err
+	}
+	return res.Views, nil
+}
@@ -0,0 +1,75 @@
+package telegraph
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccountMethods(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("access_token") == "bad" {
+			w.Write([]byte(`{"ok": false, "error": "ACCESS_TOKEN_INVALID"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/getAccountInfo":
+			w.Write([]byte(`{"ok": true, "result": {"short_name": "Team", "author_name": "Author", "page_count": 12}}`))
+		case "/editAccountInfo":
+			w.Write([]byte(`{"ok": true, "result": {"short_name": "` + r.FormValue("short_name") + `", "author_name": "` + r.FormValue("author_name") + `"}}`))
+		case "/revokeAccessToken":
+			w.Write([]byte(`{"ok": true, "result": {"access_token": "new", "auth_url": "https://edit.telegra.ph/auth/1"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	client := &Client{BaseURL: ts.URL}
+
+	info, err := client.GetAccountInfo(context.Background(), "tok")
+	if err != nil || info.ShortName != "Team" || info.PageCount != 12 {
+		t.Errorf("GetAccountInfo: %v %+v", err, info)
+	}
+
+	edited, err := client.EditAccountInfo(context.Background(), "tok", "Renamed", "New Author", "")
+	if err != nil || edited.ShortName != "Renamed" || edited.AuthorName != "New Author" {
+		t.Errorf("EditAccountInfo: %v %+v", err, edited)
+	}
+
+	revoked, err := client.RevokeAccessToken(context.Background(), "tok")
+	if err != nil || revoked.AccessToken != "new" {
+		t.Errorf("RevokeAccessToken: %v %+v", err, revoked)
+	}
+
+	if _, err := client.GetAccountInfo(context.Background(), "bad"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected API error, got %v", err)
+	}
+}
+
+func TestCreatePage_OnAccountCreated(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/createAccount":
+			w.Write([]byte(`{"ok": true, "result": {"short_name": "MangaUploader", "access_token": "generated"}}`))
+		case "/createPage":
+			if r.FormValue("author_name") != "Team" {
+				t.Errorf("expected author_name Team, got %q", r.FormValue("author_name"))
+			}
+			w.Write([]byte(`{"ok": true, "result": {"url": "http://telegra.ph/p"}}`))
+		}
+	}))
+	defer ts.Close()
+
+	var saved *Account
+	client := &Client{BaseURL: ts.URL, OnAccountCreated: func(acc *Account) { saved = acc }}
+	if url, err := client.CreatePageAs(context.Background(), "Title", nil, Author{Name: "Team"}); err != nil || url != "http://telegra.ph/p" {
+		t.Fatalf("unexpected result %s", url)
+	}
+	if saved == nil || saved.AccessToken != "generated" {
+		t.Errorf("OnAccountCreated must receive the new account, got %+v", saved)
+	}
+}
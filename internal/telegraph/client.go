@@ -1,140 +1,165 @@
 package telegraph
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"telegraph_uploader_v2/internal/config"
 )
 
+// Значения по умолчанию для таймаута запроса и ожидания при flood control
+const (
+	DefaultTimeout      = 30 * time.Second
+	DefaultMaxFloodWait = 2 * time.Minute
+	defaultMaxRetries   = 3
+)
+
 // Client хранит настройки для работы с Telegraph
 type Client struct {
+	// Token — токен по умолчанию. Задаётся при создании клиента, дальше его меняет
+	// сервис аккаунтов из других горутин, поэтому после старта — только AccessToken/SetToken.
 	Token   string
 	BaseURL string
+	// HTTPClient — через него идут все запросы (nil — http.Client с DefaultTimeout)
+	HTTPClient *http.Client
+	// MaxFloodWait — сколько максимум ждать при FLOOD_WAIT_x перед повтором;
+	// если API просит больше, возвращается FloodWaitError (0 — DefaultMaxFloodWait)
+	MaxFloodWait time.Duration
+	// MaxRetries — сколько раз повторять запрос после flood control (0 — по умолчанию)
+	MaxRetries int
+	// OnAccountCreated вызывается, когда клиент сам создал аккаунт из-за пустого токена
+	OnAccountCreated func(acc *Account)
+
+	tokenMu sync.RWMutex
+}
+
+// AccessToken возвращает текущий токен по умолчанию
+func (c *Client) AccessToken() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.Token
+}
+
+// SetToken меняет токен по умолчанию
+func (c *Client) SetToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.Token = token
 }
 
 // New создает нового клиента. Мы передаем ему конфиг целиком.
 func New(cfg *config.Config) *Client {
+	timeout := DefaultTimeout
+	if cfg.TelegraphTimeout > 0 {
+		timeout = time.Duration(cfg.TelegraphTimeout) * time.Second
+	}
 	return &Client{
-		Token:   cfg.TelegraphToken,
-		BaseURL: "https://api.telegra.ph",
+		Token:        cfg.TelegraphToken,
+		BaseURL:      "https://api.telegra.ph",
+		HTTPClient:   &http.Client{Timeout: timeout},
+		MaxFloodWait: time.Duration(cfg.TelegraphMaxFloodWait) * time.Second,
 	}
 }
 
-// Структуры для парсинга JSON
-type TelegraphResponse struct {
-	Ok     bool `json:"ok"`
-	Result struct {
-		Url string `json:"url"`
-	} `json:"result"`
-	Error string `json:"error"`
+var defaultHTTPClient = &http.Client{Timeout: DefaultTimeout}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return defaultHTTPClient
 }
 
-type TelegraphNode struct {
-	Tag   string            `json:"tag"`
-	Attrs map[string]string `json:"attrs"`
+// pageResult — result для createPage/editPage/getPage
+type pageResult struct {
+	Path    string `json:"path"`
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Content []Node `json:"content"`
 }
 
 // CreatePage теперь метод структуры Client (c *Client)
-// Мы переименовали CreateTelegraphPage -> CreatePage, так как пакет уже называется telegraph
-func (c *Client) CreatePage(title string, imageUrls []string) string {
-	// Используем токен из структуры
-	token := c.Token
+// Мы переименовали CreateTelegraphPage -> CreatePage, так как пакет уже называется telegraph.
+// Контент собирается из узлов (см. node.go) и проверяется до отправки.
+func (c *Client) CreatePage(ctx context.Context, title string, content []Node) (string, error) {
+	return c.CreatePageAs(ctx, title, content, Author{})
+}
+
+// CreatePageAs создаёт страницу от имени указанного аккаунта и автора
+func (c *Client) CreatePageAs(ctx context.Context, title string, content []Node, author Author) (string, error) {
+	if err := Validate(content); err != nil {
+		return "", &ContentError{Err: err}
+	}
 
-	// Если токена нет в конфиге, создаем временный аккаунт
+	token := author.AccessToken
 	if token == "" {
-		var err error
-		token, err = c.createAccount("MangaUploader")
+		token = c.AccessToken()
+	}
+
+	// Если токена нет нигде, создаем аккаунт и отдаём его на сохранение через OnAccountCreated
+	if token == "" {
+		acc, err := c.CreateAccount(ctx, DefaultShortName, DefaultAuthorName, "")
 		if err != nil {
-			return "Ошибка создания аккаунта Telegraph: " + err.Error()
+			return "", fmt.Errorf("создание аккаунта Telegraph: %w", err)
 		}
+		token = acc.AccessToken
 		// Запоминаем токен в памяти клиента, чтобы не создавать аккаунт каждый раз
-		c.Token = token
-		fmt.Println("ВНИМАНИЕ: Создан новый временный аккаунт Telegraph")
+		c.SetToken(token)
+		if c.OnAccountCreated != nil {
+			c.OnAccountCreated(acc)
+		} else {
+			fmt.Println("ВНИМАНИЕ: Создан новый временный аккаунт Telegraph")
+		}
 	}
 
-	content := c.imagesToNodes(imageUrls)
-
 	contentJson, err := json.Marshal(content)
 	if err != nil {
-		return "Ошибка JSON: " + err.Error()
+		return "", &ContentError{Err: err}
 	}
 
-	apiURL := c.BaseURL + "/createPage"
 	data := url.Values{}
 	data.Set("access_token", token)
 	data.Set("title", title)
 	data.Set("content", string(contentJson))
 	data.Set("return_content", "false")
-
-	resp, err := http.PostForm(apiURL, data)
-	if err != nil {
-		return "Ошибка сети Telegraph: " + err.Error()
+	if author.Name != "" {
+		data.Set("author_name", author.Name)
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	var tgResp TelegraphResponse
-	if err := json.Unmarshal(body, &tgResp); err != nil {
-		return "Ошибка ответа API: " + string(body)
+	if author.URL != "" {
+		data.Set("author_url", author.URL)
 	}
 
-	if !tgResp.Ok {
-		return "Telegraph API Error: " + tgResp.Error
-	}
-
-	return tgResp.Result.Url
-}
-
-func (c *Client) createAccount(shortName string) (string, error) {
-	apiURL := c.BaseURL + "/createAccount"
-	data := url.Values{}
-	data.Set("short_name", shortName)
-	data.Set("author_name", "MangaBot")
-
-	resp, err := http.PostForm(apiURL, data)
-	if err != nil {
+	var page pageResult
+	if err := c.call(ctx, "createPage", data, &page); err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	type AccountResp struct {
-		Ok     bool `json:"ok"`
-		Result struct {
-			AccessToken string `json:"access_token"`
-		} `json:"result"`
-	}
-	var acc AccountResp
-	json.Unmarshal(body, &acc)
-
-	if !acc.Ok {
-		return "", fmt.Errorf("не удалось создать аккаунт")
-	}
-	return acc.Result.AccessToken, nil
+	return page.URL, nil
 }
 
 // EditPage редактирует существующую страницу
-func (c *Client) EditPage(path string, title string, imageUrls []string, accessToken string) string {
+func (c *Client) EditPage(ctx context.Context, path string, title string, content []Node, accessToken string) (string, error) {
+	if err := Validate(content); err != nil {
+		return "", &ContentError{Err: err}
+	}
+
 	// Если токен не передан, берем из конфига (но лучше передавать тот, которым создавали)
 	token := accessToken
 	if token == "" {
-		token = c.Token
+		token = c.AccessToken()
 	}
 
-	content := c.imagesToNodes(imageUrls)
-
 	contentJson, err := json.Marshal(content)
 	if err != nil {
-		return "Ошибка JSON: " + err.Error()
+		return "", &ContentError{Err: err}
 	}
 
-	apiURL := c.BaseURL + "/editPage"
 	data := url.Values{}
 	data.Set("access_token", token)
 	data.Set("path", path)
@@ -142,86 +167,102 @@ func (c *Client) EditPage(path string, title string, imageUrls []string, accessT
 	data.Set("content", string(contentJson))
 	data.Set("return_content", "false")
 
-	resp, err := http.PostForm(apiURL, data)
-	if err != nil {
-		return "Ошибка сети Telegraph: " + err.Error()
+	var page pageResult
+	if err := c.call(ctx, "editPage", data, &page); err != nil {
+		return "", err
 	}
-	defer resp.Body.Close()
+	// Успех, возвращаем URL (хотя он не меняется при редактировании)
+	return page.URL, nil
+}
+
+// Page — страница Telegraph с полным деревом контента
+type Page struct {
+	Title   string
+	Content []Node
+}
 
-	body, _ := io.ReadAll(resp.Body)
+// GetPageContent получает заголовок и контент страницы в виде дерева узлов
+func (c *Client) GetPageContent(ctx context.Context, path string) (*Page, error) {
+	data := url.Values{}
+	data.Set("return_content", "true")
 
-	var tgResp TelegraphResponse
-	if err := json.Unmarshal(body, &tgResp); err != nil {
-		return "Ошибка ответа API: " + string(body)
+	var page pageResult
+	if err := c.call(ctx, "getPage/"+path, data, &page); err != nil {
+		return nil, err
 	}
+	return &Page{Title: page.Title, Content: page.Content}, nil
+}
 
-	if !tgResp.Ok {
-		return "Telegraph API Error: " + tgResp.Error
+// GetPage получает заголовок и список изображений со страницы
+func (c *Client) GetPage(ctx context.Context, path string) (string, []string, error) {
+	page, err := c.GetPageContent(ctx, path)
+	if err != nil {
+		return "", nil, err
 	}
-
-	// Успех, возвращаем URL (хотя он не меняется при редактировании)
-	return tgResp.Result.Url
+	return page.Title, Images(page.Content), nil
 }
 
-// imagesToNodes преобразует список URL-адресов изображений в узлы TelegraphNode
-func (c *Client) imagesToNodes(imageUrls []string) []TelegraphNode {
-	var content []TelegraphNode
-	for _, link := range imageUrls {
-		node := TelegraphNode{
-			Tag: "img",
-			Attrs: map[string]string{
-				"src": link,
-			},
+// call выполняет метод API и разбирает result в out.
+// При FLOOD_WAIT_x ждёт указанное время (не дольше MaxFloodWait) и повторяет запрос.
+func (c *Client) call(ctx context.Context, method string, data url.Values, out interface{}) error {
+	maxWait := c.MaxFloodWait
+	if maxWait <= 0 {
+		maxWait = DefaultMaxFloodWait
+	}
+	retries := c.MaxRetries
+	if retries <= 0 {
+		retries = defaultMaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.do(ctx, method, data, out)
+		flood, ok := err.(*FloodWaitError)
+		if !ok || attempt >= retries || flood.RetryAfter > maxWait {
+			return err
+		}
+
+		timer := time.NewTimer(flood.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
 		}
-		content = append(content, node)
 	}
-	return content
 }
 
-type PageResponse struct {
-	Ok     bool `json:"ok"`
-	Result struct {
-		Title   string        `json:"title"`
-		Content []interface{} `json:"content"` // Content может быть строками или объектами
-	} `json:"result"`
-	Error string `json:"error"`
-}
+// do — один запрос к API без повторов
+func (c *Client) do(ctx context.Context, method string, data url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/"+method, strings.NewReader(data.Encode()))
+	if err != nil {
+		return &NetworkError{Method: method, Err: err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-// GetPage получает заголовок и список изображений со страницы
-func (c *Client) GetPage(path string) (string, []string, error) {
-	apiURL := fmt.Sprintf("%s/getPage/%s?return_content=true", c.BaseURL, path)
-	
-	resp, err := http.Get(apiURL)
+	resp, err := c.httpClient().Do(req)
 	if err != nil {
-		return "", nil, err
+		return &NetworkError{Method: method, Err: err}
 	}
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-
-	var pageResp PageResponse
-	if err := json.Unmarshal(body, &pageResp); err != nil {
-		return "", nil, err
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &NetworkError{Method: method, Err: err}
 	}
 
-	if !pageResp.Ok {
-		// Fix vet error: non-constant format string
-		return "", nil, fmt.Errorf("%s", pageResp.Error)
+	var apiResp struct {
+		Ok     bool            `json:"ok"`
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
 	}
-
-	var imageUrls []string
-	for _, node := range pageResp.Result.Content {
-		// Node может быть map[string]interface{} (если это тег)
-		if nodeMap, ok := node.(map[string]interface{}); ok {
-			if tag, ok := nodeMap["tag"].(string); ok && tag == "img" {
-				if attrs, ok := nodeMap["attrs"].(map[string]interface{}); ok {
-					if src, ok := attrs["src"].(string); ok {
-						imageUrls = append(imageUrls, src)
-					}
-				}
-			}
-		}
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return &NetworkError{Method: method, Err: fmt.Errorf("bad response (HTTP %d): %.200s", resp.StatusCode, body)}
 	}
-
-	return pageResp.Result.Title, imageUrls, nil
+	if !apiResp.Ok {
+		return apiError(method, apiResp.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(apiResp.Result, out)
 }
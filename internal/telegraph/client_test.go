@@ -1,10 +1,14 @@
 package telegraph
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestCreatePage(t *testing.T) {
@@ -27,7 +31,10 @@ func TestCreatePage(t *testing.T) {
 		BaseURL: ts.URL,
 	}
 
-	url := client.CreatePage("Test Title", []string{"http://img1.jpg"})
+	url, err := client.CreatePage(context.Background(), "Test Title", ImageNodes([]string{"http://img1.jpg"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 	if url != "http://telegra.ph/test-123" {
 		t.Errorf("expected url http://telegra.ph/test-123, got %s", url)
 	}
@@ -60,13 +67,13 @@ func TestCreatePage_NoToken(t *testing.T) {
 		BaseURL: ts.URL,
 	}
 
-	url := client.CreatePage("Title", []string{})
+	url, _ := client.CreatePage(context.Background(), "Title", []Node{})
 	if url != "http://telegra.ph/created-with-new-token" {
 		t.Errorf("expected url with new token, got %s", url)
 	}
 	// Verify token was stored
-	if client.Token != "new_generated_token" {
-		t.Errorf("expected client token to be updated, got %s", client.Token)
+	if client.AccessToken() != "new_generated_token" {
+		t.Errorf("expected client token to be updated, got %s", client.AccessToken())
 	}
 }
 
@@ -85,10 +92,11 @@ func TestCreatePage_CreateAccountFail(t *testing.T) {
 		BaseURL: ts.URL,
 	}
 
-	url := client.CreatePage("Title", nil)
-	// Expect error string
-	if !strings.HasPrefix(url, "Ошибка создания аккаунта") {
-		t.Errorf("expected account creation error, got %s", url)
+	_, err := client.CreatePage(context.Background(), "Title", nil)
+	// Expect typed API error from createAccount
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Method != "createAccount" || apiErr.Code != "FAIL" {
+		t.Errorf("expected account creation error, got %v", err)
 	}
 }
 
@@ -108,7 +116,7 @@ func TestEditPage(t *testing.T) {
 		BaseURL: ts.URL,
 	}
 
-	url := client.EditPage("test-path", "New Title", []string{}, "custom_token")
+	url, _ := client.EditPage(context.Background(), "test-path", "New Title", []Node{}, "custom_token")
 	if url != "http://telegra.ph/test-path" {
 		t.Errorf("expected success url, got %s", url)
 	}
@@ -125,7 +133,7 @@ func TestEditPage_UseConfigToken(t *testing.T) {
 	defer ts.Close()
 
 	client := &Client{Token: "default", BaseURL: ts.URL}
-	client.EditPage("path", "title", nil, "") // Empty access token
+	client.EditPage(context.Background(), "path", "title", nil, "") // Empty access token
 }
 
 func TestGetPage(t *testing.T) {
@@ -147,7 +155,7 @@ func TestGetPage(t *testing.T) {
 	defer ts.Close()
 
 	client := &Client{Token: "t", BaseURL: ts.URL}
-	title, images, err := client.GetPage("test-page")
+	title, images, err := client.GetPage(context.Background(), "test-page")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -167,11 +175,12 @@ func TestGetPage_Error(t *testing.T) {
 	defer ts.Close()
 
 	client := &Client{BaseURL: ts.URL}
-	_, _, err := client.GetPage("invalid")
+	_, _, err := client.GetPage(context.Background(), "invalid")
 	if err == nil {
 		t.Error("expected error for page not found")
 	}
-	if err.Error() != "PAGE_NOT_FOUND" {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "PAGE_NOT_FOUND" || !errors.Is(err, ErrPageNotFound) {
 		t.Errorf("expected PAGE_NOT_FOUND, got %v", err)
 	}
 }
@@ -184,20 +193,21 @@ func TestNetworkErrors(t *testing.T) {
 	client := &Client{Token: "t", BaseURL: ts.URL}
 
 	// CreatePage network error
-	res := client.CreatePage("T", nil)
-	if !strings.HasPrefix(res, "Ошибка") {
-		t.Errorf("expected network error message, got %s", res)
+	var netErr *NetworkError
+	_, err := client.CreatePage(context.Background(), "T", nil)
+	if !errors.As(err, &netErr) {
+		t.Errorf("expected network error, got %v", err)
 	}
 
 	// EditPage network error
-	res = client.EditPage("p", "t", nil, "")
-	if !strings.HasPrefix(res, "Ошибка") {
-		t.Errorf("expected network error message, got %s", res)
+	_, err = client.EditPage(context.Background(), "p", "t", nil, "")
+	if !errors.As(err, &netErr) {
+		t.Errorf("expected network error, got %v", err)
 	}
 
 	// GetPage network error
-	_, _, err := client.GetPage("p")
-	if err == nil {
+	_, _, err = client.GetPage(context.Background(), "p")
+	if !errors.As(err, &netErr) {
 		t.Error("expected error for GetPage network failure")
 	}
 }
@@ -211,14 +221,93 @@ func TestJsonErrors(t *testing.T) {
 	client := &Client{Token: "t", BaseURL: ts.URL}
 
 	// CreatePage JSON error from API
-	res := client.CreatePage("T", nil)
-	if !strings.HasPrefix(res, "Ошибка") {
-		t.Errorf("expected JSON error message, got %s", res)
+	_, err := client.CreatePage(context.Background(), "T", nil)
+	if err == nil {
+		t.Error("expected error for CreatePage bad JSON")
 	}
 	
 	// GetPage JSON error from API
-	_, _, err := client.GetPage("p")
+	_, _, err = client.GetPage(context.Background(), "p")
 	if err == nil {
 		t.Error("expected error for GetPage bad JSON")
 	}
 }
+
+func TestCall_FloodWaitRetry(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if calls == 1 {
+			w.Write([]byte(`{"ok": false, "error": "FLOOD_WAIT_1"}`))
+			return
+		}
+		w.Write([]byte(`{"ok": true, "result": {"url": "http://telegra.ph/after-wait"}}`))
+	}))
+	defer ts.Close()
+
+	client := &Client{Token: "t", BaseURL: ts.URL}
+	start := time.Now()
+	url, err := client.CreatePage(context.Background(), "T", nil)
+	if err != nil || url != "http://telegra.ph/after-wait" {
+		t.Fatalf("expected retry to succeed, got %q %v", url, err)
+	}
+	if calls != 2 || time.Since(start) < time.Second {
+		t.Errorf("expected one wait of 1s and a retry, got %d calls in %s", calls, time.Since(start))
+	}
+}
+
+func TestCall_FloodWaitTooLong(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": false, "error": "FLOOD_WAIT_600"}`))
+	}))
+	defer ts.Close()
+
+	client := &Client{Token: "t", BaseURL: ts.URL, MaxFloodWait: time.Minute}
+	_, err := client.EditPage(context.Background(), "p", "T", nil, "")
+	var flood *FloodWaitError
+	if !errors.As(err, &flood) || flood.RetryAfter != 600*time.Second {
+		t.Errorf("expected FloodWaitError with 600s, got %v", err)
+	}
+}
+
+func TestCall_ContextCancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": false, "error": "FLOOD_WAIT_30"}`))
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	client := &Client{Token: "t", BaseURL: ts.URL}
+	if _, err := client.CreatePage(ctx, "T", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context deadline while waiting for flood control, got %v", err)
+	}
+}
+
+type recordingTransport struct {
+	requests int
+}
+
+func (rt *recordingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rt.requests++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"ok": false, "error": "CONTENT_TOO_BIG"}`)),
+		Request:    r,
+	}, nil
+}
+
+func TestClient_UsesInjectedHTTPClient(t *testing.T) {
+	rt := &recordingTransport{}
+	client := &Client{Token: "t", BaseURL: "http://telegraph.invalid", HTTPClient: &http.Client{Transport: rt}}
+
+	_, err := client.CreatePage(context.Background(), "T", nil)
+	if rt.requests != 1 {
+		t.Errorf("expected request through injected client, got %d", rt.requests)
+	}
+	if !errors.Is(err, ErrContentTooBig) {
+		t.Errorf("expected ErrContentTooBig, got %v", err)
+	}
+}
@@ -0,0 +1,627 @@
+package telegraph
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// === Преобразование HTML и Markdown в контент Telegraph ===
+//
+// Telegraph понимает только узкий набор тегов (allowedTags), поэтому всё остальное
+// понижается до ближайшего допустимого: h1/h2 → h3, h5/h6 → h4, del → s,
+// контейнеры (div, span, section…) разворачиваются в детей, строки таблиц
+// становятся абзацами, а script/style и формы выбрасываются целиком.
+// Из атрибутов остаются только href и src, причём лишь с безопасными схемами.
+
+// renamedTags — неподдерживаемые теги, у которых есть близкий допустимый аналог
+var renamedTags = map[string]string{
+	"h1": "h3", "h2": "h3", "h5": "h4", "h6": "h4",
+	"del": "s", "strike": "s", "ins": "u",
+	"tt": "code", "kbd": "code", "samp": "code", "var": "code",
+	"cite": "i", "dfn": "i", "mark": "b",
+}
+
+// droppedTags выбрасываются вместе с содержимым
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "head": true, "title": true, "noscript": true,
+	"template": true, "svg": true, "math": true, "object": true, "embed": true,
+	"input": true, "button": true, "select": true, "textarea": true, "source": true,
+	"track": true, "audio": true, "canvas": true, "map": true,
+}
+
+// containerTags — блочные обёртки: становятся абзацем, если внутри только строчное содержимое
+var containerTags = map[string]bool{
+	"div": true, "section": true, "article": true, "header": true, "footer": true,
+	"main": true, "nav": true, "center": true, "address": true, "details": true,
+	"summary": true, "form": true, "fieldset": true, "body": true, "html": true,
+}
+
+// blockTags — теги, которые не нужно заворачивать в абзац на верхнем уровне
+var blockTags = map[string]bool{
+	"p": true, "h3": true, "h4": true, "ul": true, "ol": true, "blockquote": true,
+	"aside": true, "figure": true, "pre": true, "hr": true, "img": true,
+	"iframe": true, "video": true,
+}
+
+// FromHTML преобразует HTML-фрагмент в контент Telegraph, понижая неподдерживаемые теги.
+// Результат всегда проходит Validate.
+func FromHTML(src string) ([]Node, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(src), body)
+	if err != nil {
+		return nil, err
+	}
+	var out []Node
+	for _, n := range nodes {
+		out = append(out, convertHTML(n, false)...)
+	}
+	return wrapBlocks(out), nil
+}
+
+// convertHTML преобразует узел HTML; внутри pre пробелы сохраняются как есть
+func convertHTML(n *html.Node, pre bool) []Node {
+	switch n.Type {
+	case html.TextNode:
+		text := n.Data
+		if !pre {
+			text = collapseSpaces(text)
+		}
+		if text == "" {
+			return nil
+		}
+		return []Node{Text(text)}
+	case html.ElementNode:
+	default:
+		return nil
+	}
+
+	tag := strings.ToLower(n.Data)
+	if droppedTags[tag] {
+		return nil
+	}
+	if renamed, ok := renamedTags[tag]; ok {
+		tag = renamed
+	}
+	// Пункт вне списка — обычный абзац
+	if tag == "li" && (n.Parent == nil || (n.Parent.Data != "ul" && n.Parent.Data != "ol")) {
+		tag = "p"
+	}
+
+	switch tag {
+	case "br", "hr":
+		return []Node{Element(tag, nil)}
+	case "img":
+		if src, ok := safeURL(attr(n, "src"), true); ok {
+			return []Node{Image(src)}
+		}
+		return nil
+	case "iframe", "video":
+		if src, ok := safeURL(attr(n, "src"), true); ok {
+			return []Node{Element(tag, map[string]string{"src": src})}
+		}
+		return nil
+	case "tr":
+		return wrapBlocks(tableRow(n, pre))
+	case "dt":
+		return wrapBlocks(wrapInline(htmlChildren(n, pre), Bold))
+	case "dd":
+		return wrapBlocks(htmlChildren(n, pre))
+	}
+
+	children := htmlChildren(n, pre || tag == "pre")
+	switch {
+	case tag == "a":
+		if href, ok := safeURL(attr(n, "href"), false); ok && len(children) > 0 {
+			return wrapInline(children, func(nodes ...Node) Node { return Link(href, nodes...) })
+		}
+		return children
+	case (tag == "p" || tag == "figcaption") && !isInline(children):
+		// Абзац не может содержать блоки: строчные куски становятся абзацами рядом с ними
+		return wrapBlocks(children)
+	case (tag == "h3" || tag == "h4") && !isInline(children):
+		return splitAround(children, func(run []Node) []Node {
+			if run = trimSpaces(run); len(run) > 0 {
+				return []Node{Element(tag, nil, run...)}
+			}
+			return nil
+		})
+	case tag == "p" || tag == "h3" || tag == "h4" || tag == "li" || tag == "figcaption":
+		children = trimSpaces(children)
+		if len(children) == 0 {
+			return nil
+		}
+		return []Node{Element(tag, nil, children...)}
+	case allowedTags[tag] && !blockTags[tag]:
+		if len(children) == 0 {
+			return nil
+		}
+		return wrapInline(children, func(nodes ...Node) Node { return Element(tag, nil, nodes...) })
+	case allowedTags[tag]:
+		if len(children) == 0 {
+			return nil
+		}
+		return []Node{Element(tag, nil, children...)}
+	case containerTags[tag]:
+		if isInline(children) {
+			if children = trimSpaces(children); len(children) > 0 {
+				return []Node{Paragraph(children...)}
+			}
+			return nil
+		}
+		return wrapBlocks(children)
+	}
+	// span, font, table, tbody и прочее — просто содержимое
+	return children
+}
+
+// htmlChildren преобразует детей узла
+func htmlChildren(n *html.Node, pre bool) []Node {
+	var out []Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		out = append(out, convertHTML(c, pre)...)
+	}
+	return mergeText(out)
+}
+
+// tableRow склеивает ячейки строки таблицы через « | »
+func tableRow(n *html.Node, pre bool) []Node {
+	var out []Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || (c.Data != "td" && c.Data != "th") {
+			continue
+		}
+		cell := trimSpaces(htmlChildren(c, pre))
+		if c.Data == "th" && len(cell) > 0 {
+			cell = []Node{Bold(cell...)}
+		}
+		if len(out) > 0 {
+			out = append(out, Text(" | "))
+		}
+		out = append(out, cell...)
+	}
+	return mergeText(out)
+}
+
+// attr — значение атрибута HTML-узла
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// safeURL пропускает только http(s), mailto и tg, а также относительные адреса.
+// Для картинок и встраиваний (media) разрешены только http(s) и относительные.
+func safeURL(raw string, media bool) (string, bool) {
+	u := strings.TrimSpace(raw)
+	if u == "" {
+		return "", false
+	}
+	if strings.HasPrefix(u, "/") || (!media && strings.HasPrefix(u, "#")) {
+		return u, true
+	}
+	i := strings.Index(u, ":")
+	if i <= 0 {
+		return "", false
+	}
+	switch strings.ToLower(u[:i]) {
+	case "http", "https":
+		return u, true
+	case "mailto", "tg":
+		return u, !media
+	}
+	return "", false
+}
+
+var spaceRun = regexp.MustCompile(`\s+`)
+
+// collapseSpaces сворачивает пробельные последовательности в один пробел, как это делает браузер
+func collapseSpaces(s string) string {
+	return spaceRun.ReplaceAllString(s, " ")
+}
+
+// isInline — нет ли среди узлов блочных
+func isInline(nodes []Node) bool {
+	for _, n := range nodes {
+		if !n.IsText() && blockTags[n.Tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// trimSpaces убирает пробелы в начале и конце строчного содержимого
+func trimSpaces(nodes []Node) []Node {
+	for len(nodes) > 0 && nodes[0].IsText() {
+		if t := strings.TrimLeft(nodes[0].Text, " \t\n"); t != "" {
+			nodes[0].Text = t
+			break
+		}
+		nodes = nodes[1:]
+	}
+	for len(nodes) > 0 && nodes[len(nodes)-1].IsText() {
+		last := len(nodes) - 1
+		if t := strings.TrimRight(nodes[last].Text, " \t\n"); t != "" {
+			nodes[last].Text = t
+			break
+		}
+		nodes = nodes[:last]
+	}
+	return nodes
+}
+
+// wrapBlocks заворачивает идущие подряд строчные узлы в абзацы, пробелы между блоками отбрасывает
+func wrapBlocks(nodes []Node) []Node {
+	return splitAround(nodes, func(run []Node) []Node {
+		if run = trimSpaces(run); len(run) > 0 {
+			return []Node{Paragraph(run...)}
+		}
+		return nil
+	})
+}
+
+// splitAround выносит блочные узлы на верхний уровень, а идущие подряд строчные
+// узлы между ними отдаёт wrap
+func splitAround(nodes []Node, wrap func(run []Node) []Node) []Node {
+	var out, run []Node
+	flush := func() {
+		if len(run) > 0 {
+			out = append(out, wrap(run)...)
+		}
+		run = nil
+	}
+	for _, n := range nodes {
+		if !n.IsText() && blockTags[n.Tag] {
+			flush()
+			out = append(out, n)
+			continue
+		}
+		run = append(run, n)
+	}
+	flush()
+	return out
+}
+
+// wrapInline заворачивает содержимое в строчный тег (b, a…). Блок внутри строчного тега
+// недопустим, поэтому тег применяется к строчным кускам по отдельности, а у абзацев
+// и заголовков — к их содержимому; пробелы между блоками отбрасываются.
+func wrapInline(nodes []Node, wrap func(children ...Node) Node) []Node {
+	if isInline(nodes) {
+		return []Node{wrap(nodes...)}
+	}
+	out := splitAround(nodes, func(run []Node) []Node {
+		if len(trimSpaces(append([]Node(nil), run...))) == 0 {
+			return nil
+		}
+		return []Node{wrap(run...)}
+	})
+	for i, n := range out {
+		if n.Tag == "p" || n.Tag == "h3" || n.Tag == "h4" {
+			out[i].Children = []Node{wrap(n.Children...)}
+		}
+	}
+	return out
+}
+
+// === Markdown ===
+
+var (
+	mdHeading  = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdRule     = regexp.MustCompile(`^ {0,3}([-*_])( *[-*_]){2,} *$`)
+	mdFence    = regexp.MustCompile("^ {0,3}(```|~~~)")
+	mdBullet   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	mdOrdered  = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	mdQuote    = regexp.MustCompile(`^ {0,3}>\s?(.*)$`)
+	mdImageRow = regexp.MustCompile(`^\s*!\[([^\]]*)\]\(\s*([^\s)]+)(?:\s+"[^"]*")?\s*\)\s*$`)
+)
+
+// FromMarkdown преобразует подмножество Markdown в контент Telegraph:
+// заголовки (# и ## → h3, остальные → h4), абзацы, списки, цитаты, блоки кода,
+// горизонтальные линии, картинки, ссылки и выделение (**, *, ~~, `).
+// Вложенные списки выравниваются в один уровень, неизвестная разметка остаётся текстом.
+func FromMarkdown(src string) []Node {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var out []Node
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			out = append(out, Paragraph(mdLines(para)...))
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case mdFence.MatchString(line):
+			flush()
+			fence := mdFence.FindStringSubmatch(line)[1]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			out = append(out, Element("pre", nil, Text(strings.Join(code, "\n"))))
+		case mdHeading.MatchString(line):
+			flush()
+			m := mdHeading.FindStringSubmatch(line)
+			tag := "h4"
+			if len(m[1]) <= 2 {
+				tag = "h3"
+			}
+			if children := mdInline(m[2]); len(children) > 0 {
+				out = append(out, Element(tag, nil, children...))
+			}
+		case mdRule.MatchString(line):
+			flush()
+			out = append(out, Rule())
+		case mdQuote.MatchString(line):
+			flush()
+			var quote []string
+			for ; i < len(lines) && mdQuote.MatchString(lines[i]); i++ {
+				quote = append(quote, mdQuote.FindStringSubmatch(lines[i])[1])
+			}
+			i--
+			out = append(out, Blockquote(mdQuoteLines(quote)...))
+		case mdBullet.MatchString(line), mdOrdered.MatchString(line):
+			flush()
+			item, tag := mdBullet, "ul"
+			if !mdBullet.MatchString(line) {
+				item, tag = mdOrdered, "ol"
+			}
+			var items []Node
+			var cur []string
+			add := func() {
+				if len(cur) > 0 {
+					items = append(items, Element("li", nil, mdLines(cur)...))
+					cur = nil
+				}
+			}
+			for ; i < len(lines); i++ {
+				l := lines[i]
+				if m := item.FindStringSubmatch(l); m != nil {
+					add()
+					cur = []string{m[1]}
+					continue
+				}
+				// Продолжение пункта — непустая строка с отступом
+				if strings.TrimSpace(l) == "" || !strings.HasPrefix(l, " ") && !strings.HasPrefix(l, "\t") {
+					break
+				}
+				if m := mdBullet.FindStringSubmatch(l); m != nil {
+					add()
+					cur = []string{m[1]}
+					continue
+				}
+				if m := mdOrdered.FindStringSubmatch(l); m != nil {
+					add()
+					cur = []string{m[1]}
+					continue
+				}
+				cur = append(cur, l)
+			}
+			add()
+			i--
+			out = append(out, Element(tag, nil, items...))
+		case mdImageRow.MatchString(line) && len(para) == 0:
+			m := mdImageRow.FindStringSubmatch(line)
+			if src, ok := safeURL(m[2], true); ok {
+				if alt := strings.TrimSpace(m[1]); alt != "" {
+					out = append(out, Figure(src, Text(alt)))
+				} else {
+					out = append(out, Image(src))
+				}
+			}
+		default:
+			para = append(para, line)
+		}
+	}
+	flush()
+	return out
+}
+
+// mdLines склеивает строки абзаца: обычный перенос — пробел,
+// два пробела или «\» в конце строки — жёсткий перенос <br>
+func mdLines(lines []string) []Node {
+	var out []Node
+	for i, l := range lines {
+		hard := strings.HasSuffix(l, "  ") || strings.HasSuffix(strings.TrimRight(l, " "), `\`)
+		l = strings.TrimSpace(l)
+		if hard {
+			l = strings.TrimSuffix(l, `\`)
+		}
+		out = append(out, mdInline(l)...)
+		if i < len(lines)-1 {
+			if hard {
+				out = append(out, LineBreak())
+			} else {
+				out = append(out, Text(" "))
+			}
+		}
+	}
+	return mergeText(out)
+}
+
+// mdQuoteLines — содержимое цитаты: абзацы внутри неё разделяются переносами строк
+func mdQuoteLines(lines []string) []Node {
+	var out []Node
+	var para []string
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		if len(out) > 0 {
+			out = append(out, LineBreak(), LineBreak())
+		}
+		out = append(out, mdLines(para)...)
+		para = nil
+	}
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			flush()
+			continue
+		}
+		para = append(para, l)
+	}
+	flush()
+	return out
+}
+
+// mdInline разбирает строчную разметку: экранирование, `код`, **жирный**, *курсив*,
+// ~~зачёркнутый~~, [ссылки](url), ![картинки](url) и <автоссылки>
+func mdInline(s string) []Node {
+	var out []Node
+	var text strings.Builder
+	emit := func(n Node) {
+		if text.Len() > 0 {
+			out = append(out, Text(text.String()))
+			text.Reset()
+		}
+		out = append(out, n)
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		rest := s[i:]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_~[]()<>#!-+.|{}", s[i+1]) >= 0:
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				emit(Element("code", nil, Text(s[i+1:i+1+end])))
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(s[i+2:], rest[:2]); end > 0 {
+				emit(Bold(mdInline(s[i+2 : i+2+end])...))
+				i += end + 4
+				continue
+			}
+		case strings.HasPrefix(rest, "~~"):
+			if end := strings.Index(s[i+2:], "~~"); end > 0 {
+				emit(Element("s", nil, mdInline(s[i+2:i+2+end])...))
+				i += end + 4
+				continue
+			}
+		case c == '*' || c == '_':
+			// _ внутри слова (snake_case) — не разметка
+			if c == '_' && i > 0 && isWordByte(s[i-1]) {
+				break
+			}
+			if end := strings.IndexByte(s[i+1:], c); end > 0 && s[i+1] != ' ' {
+				closing := i + 1 + end
+				if c == '*' || closing+1 >= len(s) || !isWordByte(s[closing+1]) {
+					emit(Italic(mdInline(s[i+1 : closing])...))
+					i = closing + 1
+					continue
+				}
+			}
+		case strings.HasPrefix(rest, "!["):
+			if _, target, n, ok := mdLinkParts(s[i+1:]); ok {
+				if src, ok := safeURL(target, true); ok {
+					emit(Image(src))
+				}
+				i += n + 1
+				continue
+			}
+		case c == '[':
+			if label, target, n, ok := mdLinkParts(rest); ok {
+				children := mdInline(label)
+				if href, ok := safeURL(target, false); ok {
+					emit(Link(href, children...))
+				} else {
+					for _, ch := range children {
+						emit(ch)
+					}
+				}
+				i += n
+				continue
+			}
+		case c == '<':
+			if end := strings.IndexByte(rest, '>'); end > 0 {
+				if href, ok := safeURL(rest[1:end], false); ok && !strings.ContainsAny(href, " \t") && !strings.HasPrefix(href, "/") {
+					emit(Link(href))
+					i += end + 1
+					continue
+				}
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	if text.Len() > 0 {
+		out = append(out, Text(text.String()))
+	}
+	return mergeText(out)
+}
+
+// mdLinkParts разбирает «[текст](адрес "заголовок")», возвращает текст, адрес и длину разметки
+func mdLinkParts(s string) (label, target string, n int, ok bool) {
+	if !strings.HasPrefix(s, "[") {
+		return "", "", 0, false
+	}
+	depth := 0
+	closeLabel := -1
+	for i := 0; i < len(s) && closeLabel < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				closeLabel = i
+			}
+		}
+	}
+	if closeLabel < 0 || closeLabel+1 >= len(s) || s[closeLabel+1] != '(' {
+		return "", "", 0, false
+	}
+	end := strings.IndexByte(s[closeLabel+2:], ')')
+	if end < 0 {
+		return "", "", 0, false
+	}
+	inner := strings.TrimSpace(s[closeLabel+2 : closeLabel+2+end])
+	if fields := strings.Fields(inner); len(fields) > 0 {
+		target = strings.Trim(fields[0], "<>")
+	}
+	return s[1:closeLabel], target, closeLabel + 2 + end + 1, true
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
+
+// mergeText склеивает соседние текстовые узлы
+func mergeText(nodes []Node) []Node {
+	var out []Node
+	for _, n := range nodes {
+		if n.IsText() && len(out) > 0 && out[len(out)-1].IsText() {
+			out[len(out)-1].Text += n.Text
+			continue
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// ConvertText преобразует исходник в заданном формате: "markdown" (или "md") либо "html"
+func ConvertText(format, src string) ([]Node, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "markdown", "md", "":
+		return FromMarkdown(src), nil
+	case "html":
+		return FromHTML(src)
+	}
+	return nil, fmt.Errorf("unsupported text format %q", format)
+}
@@ -0,0 +1,137 @@
+package telegraph
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// asJSON — компактное представление контента для сравнения в тестах
+func asJSON(t *testing.T, content []Node) string {
+	t.Helper()
+	data, err := json.Marshal(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFromMarkdown(t *testing.T) {
+	src := "# Глава 1\n" +
+		"### Часть\n\n" +
+		"Первая строка\nпродолжение с **жирным**, *курсивом*, ~~зачёркнутым~~ и `кодом`.  \n" +
+		"После переноса [ссылка](https://t.me/chan) и snake_case_name.\n\n" +
+		"- один\n- два\n  продолжение\n\n" +
+		"1. первый\n2. второй\n\n" +
+		"> цитата\n> ещё\n\n" +
+		"---\n\n" +
+		"![Обложка](https://img/cover.jpg)\n\n" +
+		"```\nfunc main() {\n\t*x*\n}\n```\n"
+
+	content := FromMarkdown(src)
+	if err := Validate(content); err != nil {
+		t.Fatalf("invalid content: %v", err)
+	}
+	want := `[{"tag":"h3","children":["Глава 1"]},{"tag":"h4","children":["Часть"]},` +
+		`{"tag":"p","children":["Первая строка продолжение с ",{"tag":"b","children":["жирным"]},", ",{"tag":"i","children":["курсивом"]},", ",{"tag":"s","children":["зачёркнутым"]}," и ",{"tag":"code","children":["кодом"]},".",{"tag":"br"},"После переноса ",{"tag":"a","attrs":{"href":"https://t.me/chan"},"children":["ссылка"]}," и snake_case_name."]},` +
+		`{"tag":"ul","children":[{"tag":"li","children":["один"]},{"tag":"li","children":["два продолжение"]}]},` +
+		`{"tag":"ol","children":[{"tag":"li","children":["первый"]},{"tag":"li","children":["второй"]}]},` +
+		`{"tag":"blockquote","children":["цитата ещё"]},` +
+		`{"tag":"hr"},` +
+		`{"tag":"figure","children":[{"tag":"img","attrs":{"src":"https://img/cover.jpg"}},{"tag":"figcaption","children":["Обложка"]}]},` +
+		`{"tag":"pre","children":["func main() {\n\t*x*\n}"]}]`
+	if got := asJSON(t, content); got != want {
+		t.Errorf("unexpected content:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestFromMarkdown_UnsafeLinks(t *testing.T) {
+	content := FromMarkdown("[клик](javascript:alert(1)) и ![x](data:image/png;base64,AAAA) \\*не курсив\\*")
+	if err := Validate(content); err != nil {
+		t.Fatalf("invalid content: %v", err)
+	}
+	want := `[{"tag":"p","children":["клик) и  *не курсив*"]}]`
+	if got := asJSON(t, content); got != want {
+		t.Errorf("unexpected content:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestFromHTML(t *testing.T) {
+	src := `<h1>Заголовок</h1>
+<h5>Подзаголовок</h5>
+<div>Текст в <span style="color:red">div</span> с <strong class="x">жирным</strong>
+ и <del>старым</del></div>
+<script>alert(1)</script><style>p{}</style>
+<p><a href="javascript:alert(1)">плохая</a> <a href="https://ok" target="_blank" onclick="x()">хорошая</a><br></p>
+<ul><li> пункт </li></ul>
+<table><tr><th>Глава</th><td>1</td></tr></table>
+<img src="https://img/1.jpg" alt="x" width="10"><img src="javascript:x">
+<pre>  отступ
+ сохранён</pre>
+свободный <b>текст</b>`
+
+	content, err := FromHTML(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(content); err != nil {
+		t.Fatalf("invalid content: %v", err)
+	}
+	want := `[{"tag":"h3","children":["Заголовок"]},{"tag":"h4","children":["Подзаголовок"]},` +
+		`{"tag":"p","children":["Текст в div с ",{"tag":"strong","children":["жирным"]}," и ",{"tag":"s","children":["старым"]}]},` +
+		`{"tag":"p","children":["плохая ",{"tag":"a","attrs":{"href":"https://ok"},"children":["хорошая"]},{"tag":"br"}]},` +
+		`{"tag":"ul","children":[{"tag":"li","children":["пункт"]}]},` +
+		`{"tag":"p","children":[{"tag":"b","children":["Глава"]}," | 1"]},` +
+		`{"tag":"img","attrs":{"src":"https://img/1.jpg"}},` +
+		`{"tag":"pre","children":["  отступ\n сохранён"]},` +
+		`{"tag":"p","children":["свободный ",{"tag":"b","children":["текст"]}]}]`
+	if got := asJSON(t, content); got != want {
+		t.Errorf("unexpected content:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestFromHTML_BlocksInsideInline(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{
+			name: "bold around divs",
+			src:  `<b><div>x</div><div>y</div></b>`,
+			want: `[{"tag":"p","children":[{"tag":"b","children":["x"]}]},{"tag":"p","children":[{"tag":"b","children":["y"]}]}]`,
+		},
+		{
+			name: "link around a heading",
+			src:  `<a href="https://ok">до <h2>глава</h2></a>`,
+			want: `[{"tag":"p","children":[{"tag":"a","attrs":{"href":"https://ok"},"children":["до "]}]},{"tag":"h3","children":[{"tag":"a","attrs":{"href":"https://ok"},"children":["глава"]}]}]`,
+		},
+		{
+			name: "list in a table cell",
+			src:  `<table><tr><td>пункты</td><td><ul><li>a</li></ul></td></tr></table>`,
+			want: `[{"tag":"p","children":["пункты |"]},{"tag":"ul","children":[{"tag":"li","children":["a"]}]}]`,
+		},
+		{
+			name: "stray list item",
+			src:  `<li>пункт</li>`,
+			want: `[{"tag":"p","children":["пункт"]}]`,
+		},
+		{
+			name: "heading with a list",
+			src:  `<h4>до<ul><li>a</li></ul></h4>`,
+			want: `[{"tag":"h4","children":["до"]},{"tag":"ul","children":[{"tag":"li","children":["a"]}]}]`,
+		},
+	}
+	for _, tt := range tests {
+		content, err := FromHTML(tt.src)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := asJSON(t, content); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConvertText_UnknownFormat(t *testing.T) {
+	if _, err := ConvertText("rst", "text"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,188 @@
+package telegraph
+
+import "fmt"
+
+// PageEditor правит картинки страницы, не трогая остальной контент: текст, ссылки,
+// подписи и навигация остаются на своих местах.
+// Картинкой считается узел верхнего уровня <img> или <figure> с <img> внутри;
+// подпись figure переезжает вместе со своей картинкой.
+type PageEditor struct {
+	content []Node
+}
+
+// NewPageEditor создаёт редактор поверх копии контента страницы
+func NewPageEditor(content []Node) *PageEditor {
+	return &PageEditor{content: append([]Node(nil), content...)}
+}
+
+// Content возвращает итоговый контент
+func (e *PageEditor) Content() []Node {
+	return e.content
+}
+
+// imageSrc возвращает адрес картинки узла верхнего уровня (пусто — узел не картинка)
+func imageSrc(n Node) string {
+	switch n.Tag {
+	case "img":
+		return n.Attrs["src"]
+	case "figure":
+		for _, c := range n.Children {
+			if c.Tag == "img" {
+				return c.Attrs["src"]
+			}
+		}
+	}
+	return ""
+}
+
+// slots — индексы картинок в content
+func (e *PageEditor) slots() []int {
+	var idx []int
+	for i, n := range e.content {
+		if imageSrc(n) != "" {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// Images — адреса картинок по порядку
+func (e *PageEditor) Images() []string {
+	var urls []string
+	for _, i := range e.slots() {
+		urls = append(urls, imageSrc(e.content[i]))
+	}
+	return urls
+}
+
+// PageImages — картинки, которые можно править через PageEditor
+func PageImages(content []Node) []string {
+	return NewPageEditor(content).Images()
+}
+
+func (e *PageEditor) check(pos int) ([]int, error) {
+	slots := e.slots()
+	if pos < 0 || pos >= len(slots) {
+		return nil, fmt.Errorf("image %d out of range (%d images)", pos, len(slots))
+	}
+	return slots, nil
+}
+
+// insertNode вставляет узел-картинку так, чтобы он стал pos-й картинкой.
+// Новая последняя картинка встаёт сразу за прежней последней (до текста в конце страницы).
+func (e *PageEditor) insertNode(pos int, n Node) error {
+	slots := e.slots()
+	if pos < 0 || pos > len(slots) {
+		return fmt.Errorf("insert position %d out of range (%d images)", pos, len(slots))
+	}
+	at := len(e.content)
+	switch {
+	case pos < len(slots):
+		at = slots[pos]
+	case len(slots) > 0:
+		at = slots[len(slots)-1] + 1
+	}
+	e.content = append(e.content[:at], append([]Node{n}, e.content[at:]...)...)
+	return nil
+}
+
+// Insert добавляет картинку так, чтобы она стала pos-й
+func (e *PageEditor) Insert(pos int, src string) error {
+	return e.insertNode(pos, Image(src))
+}
+
+// Remove удаляет pos-ю картинку (вместе с подписью)
+func (e *PageEditor) Remove(pos int) error {
+	slots, err := e.check(pos)
+	if err != nil {
+		return err
+	}
+	at := slots[pos]
+	e.content = append(e.content[:at], e.content[at+1:]...)
+	return nil
+}
+
+// Replace меняет адрес pos-й картинки, сохраняя подпись
+func (e *PageEditor) Replace(pos int, src string) error {
+	slots, err := e.check(pos)
+	if err != nil {
+		return err
+	}
+	e.content[slots[pos]] = withImageSrc(e.content[slots[pos]], src)
+	return nil
+}
+
+// Move переставляет картинку с позиции from на позицию to
+func (e *PageEditor) Move(from, to int) error {
+	slots, err := e.check(from)
+	if err != nil {
+		return err
+	}
+	if to < 0 || to >= len(slots) {
+		return fmt.Errorf("move target %d out of range (%d images)", to, len(slots))
+	}
+	n := e.content[slots[from]]
+	e.content = append(e.content[:slots[from]], e.content[slots[from]+1:]...)
+	return e.insertNode(to, n)
+}
+
+// SetImages приводит картинки страницы к списку urls: картинки, которые остались,
+// сохраняют свои подписи, новые вставляются простыми <img>, лишние удаляются.
+// Порядок картинок — как в urls, остальные узлы не двигаются.
+func (e *PageEditor) SetImages(urls []string) {
+	// Исходные узлы по адресу (при повторах — по очереди)
+	existing := map[string][]Node{}
+	for _, i := range e.slots() {
+		src := imageSrc(e.content[i])
+		existing[src] = append(existing[src], e.content[i])
+	}
+	nodes := make([]Node, len(urls))
+	for i, u := range urls {
+		if prev := existing[u]; len(prev) > 0 {
+			nodes[i], existing[u] = prev[0], prev[1:]
+		} else {
+			nodes[i] = Image(u)
+		}
+	}
+
+	result := make([]Node, 0, len(e.content)+len(urls))
+	next := 0
+	lastSlot := -1
+	for _, n := range e.content {
+		if imageSrc(n) == "" {
+			result = append(result, n)
+			continue
+		}
+		if next < len(nodes) {
+			result = append(result, nodes[next])
+			next++
+			lastSlot = len(result) - 1
+		}
+	}
+	// Картинок стало больше — хвост встаёт за последней картинкой
+	if next < len(nodes) {
+		at := lastSlot + 1
+		if lastSlot < 0 {
+			at = len(result)
+		}
+		tail := append(append([]Node(nil), nodes[next:]...), result[at:]...)
+		result = append(result[:at], tail...)
+	}
+	e.content = result
+}
+
+// withImageSrc возвращает копию узла-картинки с новым адресом
+func withImageSrc(n Node, src string) Node {
+	if n.Tag == "img" {
+		return Image(src)
+	}
+	children := append([]Node(nil), n.Children...)
+	for i, c := range children {
+		if c.Tag == "img" {
+			children[i] = Image(src)
+			break
+		}
+	}
+	n.Children = children
+	return n
+}
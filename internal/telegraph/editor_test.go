@@ -0,0 +1,98 @@
+package telegraph
+
+import (
+	"reflect"
+	"testing"
+)
+
+// samplePage — страница с текстом вокруг картинок и подписью у второй
+func samplePage() []Node {
+	return []Node{
+		Heading("Глава 1"),
+		Image("http://a"),
+		Figure("http://b", Text("подпись b")),
+		Paragraph(Text("Перевод: команда")),
+		Image("http://c"),
+		Paragraph(Link("https://telegra.ph/next", Text("Дальше"))),
+	}
+}
+
+func TestPageEditor_Images(t *testing.T) {
+	e := NewPageEditor(samplePage())
+	if got := e.Images(); !reflect.DeepEqual(got, []string{"http://a", "http://b", "http://c"}) {
+		t.Errorf("unexpected images %v", got)
+	}
+}
+
+func TestPageEditor_Operations(t *testing.T) {
+	e := NewPageEditor(samplePage())
+
+	if err := e.Replace(1, "http://b2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Move(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Insert(3, "http://d"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Images(); !reflect.DeepEqual(got, []string{"http://b2", "http://c", "http://d"}) {
+		t.Fatalf("unexpected images %v", got)
+	}
+
+	content := e.Content()
+	if content[0].Tag != "h3" || content[len(content)-1].Children[0].Tag != "a" {
+		t.Error("heading and trailing link must stay in place")
+	}
+	// Подпись осталась у перемещённой и заменённой картинки
+	if content[1].Tag != "figure" || content[1].Children[1].Children[0].Text != "подпись b" {
+		t.Errorf("caption lost: %+v", content[1])
+	}
+	// Новая последняя картинка встала перед ссылкой в конце
+	if imageSrc(content[len(content)-2]) != "http://d" {
+		t.Errorf("appended image must precede trailing text, got %+v", content[len(content)-2])
+	}
+
+	if err := e.Remove(5); err == nil {
+		t.Error("expected out of range error")
+	}
+	if err := Validate(content); err != nil {
+		t.Errorf("edited content must stay valid: %v", err)
+	}
+}
+
+func TestPageEditor_SetImages(t *testing.T) {
+	e := NewPageEditor(samplePage())
+	e.SetImages([]string{"http://c", "http://b", "http://new1", "http://new2"})
+
+	want := []Node{
+		Heading("Глава 1"),
+		Image("http://c"),
+		Figure("http://b", Text("подпись b")),
+		Paragraph(Text("Перевод: команда")),
+		Image("http://new1"),
+		Image("http://new2"),
+		Paragraph(Link("https://telegra.ph/next", Text("Дальше"))),
+	}
+	if got := e.Content(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected content:\n got %+v\nwant %+v", got, want)
+	}
+
+	e.SetImages([]string{"http://b"})
+	if got := e.Content(); len(got) != 4 || imageSrc(got[1]) != "http://b" || got[2].Tag != "p" {
+		t.Errorf("removing images must keep text nodes, got %+v", got)
+	}
+}
+
+func TestPageEditor_DoesNotMutateInput(t *testing.T) {
+	page := samplePage()
+	e := NewPageEditor(page)
+	e.Replace(0, "http://x")
+	e.Remove(2)
+	if !reflect.DeepEqual(page, samplePage()) {
+		t.Error("editor must not modify the original content")
+	}
+}
@@ -0,0 +1,85 @@
+package telegraph
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ошибки, которые удобно проверять через errors.Is
+var (
+	ErrInvalidToken   = errors.New("telegraph: invalid access token")
+	ErrContentTooBig  = errors.New("telegraph: content too big")
+	ErrPageNotFound   = errors.New("telegraph: page not found")
+	ErrInvalidContent = errors.New("telegraph: invalid content")
+)
+
+// NetworkError — запрос не дошёл до API или ответ не удалось прочитать
+type NetworkError struct {
+	Method string
+	Err    error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("telegraph %s: network error: %v", e.Method, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// APIError — API ответил ok=false; Code — строка ошибки Telegraph (ACCESS_TOKEN_INVALID, PAGE_NOT_FOUND...)
+type APIError struct {
+	Method string
+	Code   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegraph %s: %s", e.Method, e.Code)
+}
+
+// Is сопоставляет коды API с общими ошибками пакета
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrInvalidToken:
+		return e.Code == "ACCESS_TOKEN_INVALID"
+	case ErrContentTooBig:
+		return e.Code == "CONTENT_TOO_BIG"
+	case ErrPageNotFound:
+		return e.Code == "PAGE_NOT_FOUND"
+	}
+	return false
+}
+
+// FloodWaitError — сработал flood control, повторить можно через RetryAfter
+type FloodWaitError struct {
+	Method     string
+	RetryAfter time.Duration
+}
+
+func (e *FloodWaitError) Error() string {
+	return fmt.Sprintf("telegraph %s: flood wait %s", e.Method, e.RetryAfter)
+}
+
+// ContentError — контент не прошёл локальную проверку и не отправлялся
+type ContentError struct {
+	Err error
+}
+
+func (e *ContentError) Error() string {
+	return "telegraph: invalid content: " + e.Err.Error()
+}
+
+func (e *ContentError) Unwrap() error { return e.Err }
+
+func (e *ContentError) Is(target error) bool { return target == ErrInvalidContent }
+
+// apiError превращает строку ошибки API в типизированную ошибку
+func apiError(method, code string) error {
+	if rest, ok := strings.CutPrefix(code, "FLOOD_WAIT_"); ok {
+		if sec, err := strconv.Atoi(rest); err == nil {
+			return &FloodWaitError{Method: method, RetryAfter: time.Duration(sec) * time.Second}
+		}
+	}
+	return &APIError{Method: method, Code: code}
+}
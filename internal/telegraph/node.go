@@ -0,0 +1,260 @@
+package telegraph
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MaxContentSize — примерный предел размера сериализованного content, который принимает Telegraph
+const MaxContentSize = 64 * 1024
+
+// Node — узел контента Telegraph: либо текстовая строка, либо элемент с тегом.
+// В JSON текст — это просто строка, элемент — объект {"tag", "attrs", "children"}.
+type Node struct {
+	Text     string
+	Tag      string
+	Attrs    map[string]string
+	Children []Node
+}
+
+// IsText сообщает, что узел — текстовая строка
+func (n Node) IsText() bool {
+	return n.Tag == ""
+}
+
+type elementJSON struct {
+	Tag      string            `json:"tag"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Children []Node            `json:"children,omitempty"`
+}
+
+func (n Node) MarshalJSON() ([]byte, error) {
+	if n.IsText() {
+		return json.Marshal(n.Text)
+	}
+	return json.Marshal(elementJSON{Tag: n.Tag, Attrs: n.Attrs, Children: n.Children})
+}
+
+func (n *Node) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*n = Node{Text: text}
+		return nil
+	}
+	var el elementJSON
+	if err := json.Unmarshal(data, &el); err != nil {
+		return err
+	}
+	if el.Tag == "" {
+		return fmt.Errorf("telegraph node without tag")
+	}
+	*n = Node{Tag: el.Tag, Attrs: el.Attrs, Children: el.Children}
+	return nil
+}
+
+// allowedTags — теги, которые принимает Telegraph API
+var allowedTags = map[string]bool{
+	"a": true, "aside": true, "b": true, "blockquote": true, "br": true, "code": true,
+	"em": true, "figcaption": true, "figure": true, "h3": true, "h4": true, "hr": true,
+	"i": true, "iframe": true, "img": true, "li": true, "ol": true, "p": true,
+	"pre": true, "s": true, "strong": true, "u": true, "ul": true, "video": true,
+}
+
+// allowedAttrs — атрибуты, которые принимает Telegraph API
+var allowedAttrs = map[string]bool{"href": true, "src": true}
+
+// voidTags не могут иметь детей
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true}
+
+// Validate проверяет дерево на соответствие ограничениям Telegraph
+func Validate(content []Node) error {
+	for i, n := range content {
+		if err := validateNode(n, fmt.Sprintf("content[%d]", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateNode(n Node, path string) error {
+	if n.IsText() {
+		if len(n.Attrs) > 0 || len(n.Children) > 0 {
+			return fmt.Errorf("%s: text node cannot have attrs or children", path)
+		}
+		return nil
+	}
+	if !allowedTags[n.Tag] {
+		return fmt.Errorf("%s: tag %q is not allowed", path, n.Tag)
+	}
+	for k := range n.Attrs {
+		if !allowedAttrs[k] {
+			return fmt.Errorf("%s: attribute %q is not allowed on <%s>", path, k, n.Tag)
+		}
+	}
+	if voidTags[n.Tag] && len(n.Children) > 0 {
+		return fmt.Errorf("%s: <%s> cannot have children", path, n.Tag)
+	}
+	if (n.Tag == "img" || n.Tag == "iframe" || n.Tag == "video") && n.Attrs["src"] == "" {
+		return fmt.Errorf("%s: <%s> requires src", path, n.Tag)
+	}
+	for i, c := range n.Children {
+		if err := validateNode(c, fmt.Sprintf("%s.children[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// === Конструкторы узлов ===
+
+// Text создаёт текстовый узел
+func Text(s string) Node {
+	return Node{Text: s}
+}
+
+// Element создаёт элемент с произвольным тегом
+func Element(tag string, attrs map[string]string, children ...Node) Node {
+	return Node{Tag: tag, Attrs: attrs, Children: children}
+}
+
+// Image — картинка
+func Image(src string) Node {
+	return Node{Tag: "img", Attrs: map[string]string{"src": src}}
+}
+
+// Link — ссылка; без детей текстом ссылки становится сам адрес
+func Link(href string, children ...Node) Node {
+	if len(children) == 0 {
+		children = []Node{Text(href)}
+	}
+	return Node{Tag: "a", Attrs: map[string]string{"href": href}, Children: children}
+}
+
+// Paragraph — абзац
+func Paragraph(children ...Node) Node {
+	return Element("p", nil, children...)
+}
+
+// Heading — заголовок h3
+func Heading(text string) Node {
+	return Element("h3", nil, Text(text))
+}
+
+// Subheading — заголовок h4
+func Subheading(text string) Node {
+	return Element("h4", nil, Text(text))
+}
+
+// Figure — картинка с подписью (подпись может быть пустой)
+func Figure(src string, caption ...Node) Node {
+	children := []Node{Image(src)}
+	if len(caption) > 0 {
+		children = append(children, Element("figcaption", nil, caption...))
+	}
+	return Element("figure", nil, children...)
+}
+
+// Blockquote — цитата
+func Blockquote(children ...Node) Node {
+	return Element("blockquote", nil, children...)
+}
+
+// Bold, Italic — выделение текста
+func Bold(children ...Node) Node   { return Element("b", nil, children...) }
+func Italic(children ...Node) Node { return Element("i", nil, children...) }
+
+// Rule — горизонтальная линия
+func Rule() Node {
+	return Element("hr", nil)
+}
+
+// LineBreak — перенос строки
+func LineBreak() Node {
+	return Element("br", nil)
+}
+
+// ImageNodes превращает список ссылок в узлы img (формат страниц глав)
+func ImageNodes(urls []string) []Node {
+	content := make([]Node, 0, len(urls))
+	for _, u := range urls {
+		content = append(content, Image(u))
+	}
+	return content
+}
+
+// === Builder ===
+
+// Builder собирает контент страницы по шагам:
+//
+//	content := telegraph.NewBuilder().Heading("Глава 1").Images(urls...).Paragraph(telegraph.Text("Перевод: ...")).Nodes()
+type Builder struct {
+	nodes []Node
+}
+
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Add добавляет готовые узлы
+func (b *Builder) Add(nodes ...Node) *Builder {
+	b.nodes = append(b.nodes, nodes...)
+	return b
+}
+
+func (b *Builder) Heading(text string) *Builder {
+	return b.Add(Heading(text))
+}
+
+func (b *Builder) Subheading(text string) *Builder {
+	return b.Add(Subheading(text))
+}
+
+func (b *Builder) Paragraph(children ...Node) *Builder {
+	return b.Add(Paragraph(children...))
+}
+
+// Image добавляет картинку; с непустой подписью — в figure с figcaption
+func (b *Builder) Image(src string, caption string) *Builder {
+	if caption == "" {
+		return b.Add(Image(src))
+	}
+	return b.Add(Figure(src, Text(caption)))
+}
+
+func (b *Builder) Images(urls ...string) *Builder {
+	return b.Add(ImageNodes(urls)...)
+}
+
+func (b *Builder) Rule() *Builder {
+	return b.Add(Rule())
+}
+
+// Nodes возвращает собранный контент
+func (b *Builder) Nodes() []Node {
+	return b.nodes
+}
+
+// ContentSize возвращает размер контента в JSON, как он уйдёт в API
+func ContentSize(content []Node) int {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// Images возвращает ссылки на все картинки дерева в порядке следования
+func Images(content []Node) []string {
+	var urls []string
+	var walk func(nodes []Node)
+	walk = func(nodes []Node) {
+		for _, n := range nodes {
+			if n.Tag == "img" && n.Attrs["src"] != "" {
+				urls = append(urls, n.Attrs["src"])
+			}
+			walk(n.Children)
+		}
+	}
+	walk(content)
+	return urls
+}
@@ -0,0 +1,144 @@
+package telegraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNode_JSONRoundTrip(t *testing.T) {
+	content := NewBuilder().
+		Heading("Глава 1").
+		Image("http://img/1.webp", "").
+		Image("http://img/2.webp", "Подпись").
+		Paragraph(Text("Перевод: "), Link("https://t.me/team", Text("команда"))).
+		Rule().
+		Nodes()
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"tag":"h3","children":["Глава 1"]},` +
+		`{"tag":"img","attrs":{"src":"http://img/1.webp"}},` +
+		`{"tag":"figure","children":[{"tag":"img","attrs":{"src":"http://img/2.webp"}},{"tag":"figcaption","children":["Подпись"]}]},` +
+		`{"tag":"p","children":["Перевод: ",{"tag":"a","attrs":{"href":"https://t.me/team"},"children":["команда"]}]},` +
+		`{"tag":"hr"}]`
+	if string(data) != want {
+		t.Errorf("unexpected JSON:\n got %s\nwant %s", data, want)
+	}
+
+	var parsed []Node
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := json.Marshal(parsed)
+	if string(again) != want {
+		t.Errorf("round trip changed JSON: %s", again)
+	}
+	if !parsed[0].Children[0].IsText() || parsed[0].Children[0].Text != "Глава 1" {
+		t.Errorf("text child not parsed: %+v", parsed[0])
+	}
+
+	if imgs := Images(parsed); len(imgs) != 2 || imgs[1] != "http://img/2.webp" {
+		t.Errorf("expected images from img and figure, got %v", imgs)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []Node
+		wantErr string
+	}{
+		{"Valid", []Node{Paragraph(Bold(Text("x")), LineBreak()), Image("http://a")}, ""},
+		{"Unknown tag", []Node{Element("script", nil, Text("x"))}, `tag "script" is not allowed`},
+		{"Unknown attr", []Node{Element("p", map[string]string{"class": "x"})}, `attribute "class"`},
+		{"Void with children", []Node{Element("hr", nil, Text("x"))}, "cannot have children"},
+		{"Image without src", []Node{Element("img", nil)}, "requires src"},
+		{"Nested error path", []Node{Paragraph(Text("ok"), Element("div", nil))}, "content[0].children[1]"},
+		{"Text with children", []Node{{Text: "x", Children: []Node{Text("y")}}}, "text node"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.content)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCreatePage_InvalidContent(t *testing.T) {
+	called := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer ts.Close()
+
+	client := &Client{Token: "t", BaseURL: ts.URL}
+	_, err := client.CreatePage(context.Background(), "T", []Node{Element("div", nil)})
+	if !errors.Is(err, ErrInvalidContent) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+	_, err = client.EditPage(context.Background(), "p", "T", []Node{Element("div", nil)}, "")
+	if !errors.Is(err, ErrInvalidContent) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+	if called {
+		t.Error("invalid content must not be sent to API")
+	}
+}
+
+func TestCreatePage_SendsNodes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var content []Node
+		if err := json.Unmarshal([]byte(r.FormValue("content")), &content); err != nil {
+			t.Errorf("bad content: %v", err)
+		}
+		if len(content) != 2 || content[0].Tag != "h3" || content[1].Tag != "img" {
+			t.Errorf("unexpected content: %s", r.FormValue("content"))
+		}
+		w.Write([]byte(`{"ok": true, "result": {"url": "http://telegra.ph/x"}}`))
+	}))
+	defer ts.Close()
+
+	client := &Client{Token: "t", BaseURL: ts.URL}
+	if url, _ := client.CreatePage(context.Background(), "T", NewBuilder().Heading("H").Images("http://a").Nodes()); url != "http://telegra.ph/x" {
+		t.Errorf("unexpected result %s", url)
+	}
+}
+
+func TestGetPageContent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": true, "result": {"title": "T", "content": [
+			{"tag": "p", "children": ["Команда ", {"tag": "a", "attrs": {"href": "https://x"}, "children": ["X"]}]},
+			{"tag": "figure", "children": [{"tag": "img", "attrs": {"src": "http://img1"}}, {"tag": "figcaption", "children": ["cap"]}]}
+		]}}`))
+	}))
+	defer ts.Close()
+
+	client := &Client{BaseURL: ts.URL}
+	page, err := client.GetPageContent(context.Background(), "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Content) != 2 || page.Content[0].Children[1].Attrs["href"] != "https://x" {
+		t.Errorf("unexpected content: %+v", page.Content)
+	}
+
+	_, images, err := client.GetPage(context.Background(), "x")
+	if err != nil || len(images) != 1 || images[0] != "http://img1" {
+		t.Errorf("expected image inside figure, got %v (%v)", images, err)
+	}
+}
@@ -0,0 +1,336 @@
+package uploader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+
+	"github.com/chai2010/webp"
+)
+
+// Режимы обработки анимированных картинок (ResizeSettings.AnimationMode)
+const (
+	AnimationReencode    = "animated_webp" // пересобрать в анимированный WebP с ресайзом всех кадров
+	AnimationPassthrough = "passthrough"   // загрузить исходный файл как есть
+	AnimationFlatten     = "flatten"       // оставить только первый кадр
+)
+
+// Типы анимированного входа
+const (
+	animatedGIF  = "gif"
+	animatedWebP = "webp"
+)
+
+// animation — раскадровка, где каждый кадр уже скомпонован на полный холст
+type animation struct {
+	width, height int
+	frames        []*image.NRGBA
+	delays        []int // миллисекунды
+	loopCount     int   // в терминах WebP: 0 — бесконечно
+}
+
+// detectAnimation возвращает тип анимации или пустую строку для статичных картинок
+func detectAnimation(data []byte) string {
+	switch {
+	case len(data) > 6 && (string(data[:6]) == "GIF87a" || string(data[:6]) == "GIF89a"):
+		if countGIFFrames(data) > 1 {
+			return animatedGIF
+		}
+	case len(data) > 30 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP" && string(data[12:16]) == "VP8X":
+		if data[20]&0x02 != 0 {
+			return animatedWebP
+		}
+	}
+	return ""
+}
+
+// countGIFFrames считает кадры GIF по дескрипторам изображений, не распаковывая LZW-данные
+// (0 — структура файла битая или обрывается)
+func countGIFFrames(data []byte) int {
+	const headerLen = 13 // сигнатура и Logical Screen Descriptor
+	if len(data) < headerLen {
+		return 0
+	}
+	p := headerLen
+	if flags := data[10]; flags&0x80 != 0 {
+		p += 3 << ((flags & 0x07) + 1)
+	}
+
+	// skipSubBlocks пропускает цепочку подблоков до нулевого терминатора
+	skipSubBlocks := func() bool {
+		for p < len(data) {
+			size := int(data[p])
+			p++
+			if size == 0 {
+				return true
+			}
+			p += size
+		}
+		return false
+	}
+
+	frames := 0
+	for p < len(data) {
+		switch data[p] {
+		case 0x2C: // Image Descriptor
+			if p+10 > len(data) {
+				return 0
+			}
+			flags := data[p+9]
+			p += 10
+			if flags&0x80 != 0 {
+				p += 3 << ((flags & 0x07) + 1)
+			}
+			p++ // минимальный размер кода LZW
+			if !skipSubBlocks() {
+				return 0
+			}
+			frames++
+		case 0x21: // расширение: метка и подблоки
+			p += 2
+			if !skipSubBlocks() {
+				return 0
+			}
+		case 0x3B: // Trailer
+			return frames
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+func decodeAnimation(data []byte, kind string) (*animation, error) {
+	if kind == animatedGIF {
+		return decodeGIFAnimation(data)
+	}
+	return decodeWebPAnimation(data)
+}
+
+func decodeGIFAnimation(data []byte) (*animation, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	anim := &animation{
+		width:  g.Config.Width,
+		height: g.Config.Height,
+	}
+	switch {
+	case g.LoopCount == 0:
+		anim.loopCount = 0
+	case g.LoopCount < 0:
+		anim.loopCount = 1
+	default:
+		anim.loopCount = g.LoopCount + 1
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, anim.width, anim.height))
+	var previous *image.NRGBA
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = cloneNRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		anim.frames = append(anim.frames, cloneNRGBA(canvas))
+		anim.delays = append(anim.delays, gifDelayMs(g.Delay, i))
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			if previous != nil {
+				canvas = previous
+			}
+		}
+	}
+	return anim, nil
+}
+
+// gifDelayMs переводит сотые доли секунды в мс; нулевые задержки браузеры показывают как 100 мс
+func gifDelayMs(delays []int, i int) int {
+	if i >= len(delays) || delays[i] <= 1 {
+		return 100
+	}
+	return delays[i] * 10
+}
+
+type riffChunk struct {
+	fourcc string
+	data   []byte
+}
+
+// riffChunks разбирает чанки WebP-контейнера (после заголовка RIFF/WEBP)
+func riffChunks(data []byte) []riffChunk {
+	var chunks []riffChunk
+	for pos := 0; pos+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		if pos+8+size > len(data) {
+			break
+		}
+		chunks = append(chunks, riffChunk{fourcc: string(data[pos : pos+4]), data: data[pos+8 : pos+8+size]})
+		pos += 8 + size + size&1
+	}
+	return chunks
+}
+
+func appendChunk(dst []byte, fourcc string, payload []byte) []byte {
+	dst = append(dst, fourcc...)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(payload)))
+	dst = append(dst, payload...)
+	if len(payload)&1 == 1 {
+		dst = append(dst, 0)
+	}
+	return dst
+}
+
+func wrapRIFF(body []byte) []byte {
+	out := []byte("RIFF")
+	out = binary.LittleEndian.AppendUint32(out, uint32(4+len(body)))
+	out = append(out, "WEBP"...)
+	return append(out, body...)
+}
+
+func put24(dst []byte, v int) []byte {
+	return append(dst, byte(v), byte(v>>8), byte(v>>16))
+}
+
+func get24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+func decodeWebPAnimation(data []byte) (*animation, error) {
+	chunks := riffChunks(data[12:])
+	if len(chunks) == 0 || chunks[0].fourcc != "VP8X" || len(chunks[0].data) < 10 {
+		return nil, fmt.Errorf("invalid animated webp")
+	}
+	vp8x := chunks[0].data
+	anim := &animation{
+		width:  get24(vp8x[4:]) + 1,
+		height: get24(vp8x[7:]) + 1,
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, anim.width, anim.height))
+	var prevRect image.Rectangle
+	disposePrev := false
+
+	for _, c := range chunks[1:] {
+		switch c.fourcc {
+		case "ANIM":
+			if len(c.data) >= 6 {
+				anim.loopCount = int(binary.LittleEndian.Uint16(c.data[4:]))
+			}
+		case "ANMF":
+			if len(c.data) < 16 {
+				return nil, fmt.Errorf("invalid ANMF chunk")
+			}
+			x, y := get24(c.data)*2, get24(c.data[3:])*2
+			w, h := get24(c.data[6:])+1, get24(c.data[9:])+1
+			duration := get24(c.data[12:])
+			flags := c.data[15]
+
+			frame, err := decodeWebPFrame(c.data[16:], w, h)
+			if err != nil {
+				return nil, err
+			}
+
+			if disposePrev {
+				draw.Draw(canvas, prevRect, image.Transparent, image.Point{}, draw.Src)
+			}
+			rect := image.Rect(x, y, x+w, y+h)
+			op := draw.Over
+			if flags&0x02 != 0 {
+				op = draw.Src
+			}
+			draw.Draw(canvas, rect, frame, frame.Bounds().Min, op)
+
+			anim.frames = append(anim.frames, cloneNRGBA(canvas))
+			anim.delays = append(anim.delays, duration)
+			prevRect = rect
+			disposePrev = flags&0x01 != 0
+		}
+	}
+	if len(anim.frames) == 0 {
+		return nil, fmt.Errorf("animated webp has no frames")
+	}
+	return anim, nil
+}
+
+// decodeWebPFrame собирает из данных кадра самостоятельный WebP и декодирует его
+func decodeWebPFrame(frameData []byte, w, h int) (image.Image, error) {
+	var body []byte
+	hasAlpha := false
+	for _, c := range riffChunks(frameData) {
+		switch c.fourcc {
+		case "ALPH":
+			hasAlpha = true
+			body = appendChunk(body, c.fourcc, c.data)
+		case "VP8 ", "VP8L":
+			body = appendChunk(body, c.fourcc, c.data)
+		}
+	}
+	if hasAlpha {
+		header := []byte{0x10, 0, 0, 0}
+		header = put24(header, w-1)
+		header = put24(header, h-1)
+		body = append(appendChunk(nil, "VP8X", header), body...)
+	}
+	return webp.Decode(bytes.NewReader(wrapRIFF(body)))
+}
+
+// encodeAnimatedWebP кодирует кадры по отдельности и собирает из них анимированный WebP
+func encodeAnimatedWebP(anim *animation, quality float32) ([]byte, error) {
+	width, height := anim.width, anim.height
+	if len(anim.frames) > 0 {
+		width, height = anim.frames[0].Bounds().Dx(), anim.frames[0].Bounds().Dy()
+	}
+
+	header := []byte{0x02 | 0x10, 0, 0, 0}
+	header = put24(header, width-1)
+	header = put24(header, height-1)
+	body := appendChunk(nil, "VP8X", header)
+
+	animChunk := []byte{0, 0, 0, 0}
+	animChunk = binary.LittleEndian.AppendUint16(animChunk, uint16(anim.loopCount))
+	body = appendChunk(body, "ANIM", animChunk)
+
+	for i, frame := range anim.frames {
+		buf := new(bytes.Buffer)
+		if err := webp.Encode(buf, frame, &webp.Options{Lossless: false, Quality: quality}); err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+		still := buf.Bytes()
+		if len(still) < 12 {
+			return nil, fmt.Errorf("frame %d: empty webp", i)
+		}
+
+		// Заголовок ANMF: кадр на весь холст, без смешивания и без очистки
+		frameData := put24(nil, 0)
+		frameData = put24(frameData, 0)
+		frameData = put24(frameData, width-1)
+		frameData = put24(frameData, height-1)
+		frameData = put24(frameData, anim.delays[i])
+		frameData = append(frameData, 0x02)
+		for _, c := range riffChunks(still[12:]) {
+			if c.fourcc == "ALPH" || c.fourcc == "VP8 " || c.fourcc == "VP8L" {
+				frameData = appendChunk(frameData, c.fourcc, c.data)
+			}
+		}
+		body = appendChunk(body, "ANMF", frameData)
+	}
+	return wrapRIFF(body), nil
+}
+
+func cloneNRGBA(src *image.NRGBA) *image.NRGBA {
+	dst := image.NewNRGBA(src.Rect)
+	copy(dst.Pix, src.Pix)
+	return dst
+}
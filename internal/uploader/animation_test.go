@@ -0,0 +1,133 @@
+package uploader
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func createAnimatedGIF(t *testing.T, width, height int, colors []color.RGBA) []byte {
+	anim := &gif.GIF{LoopCount: 0}
+	for _, c := range colors {
+		palette := color.Palette{color.Transparent, c}
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+		for i := range frame.Pix {
+			frame.Pix[i] = 1
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 5)
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessImage_AnimatedGIF(t *testing.T) {
+	data := createAnimatedGIF(t, 400, 200, []color.RGBA{
+		{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255},
+	})
+	if detectAnimation(data) != animatedGIF {
+		t.Fatal("expected animated GIF to be detected")
+	}
+
+	settings := ResizeSettings{Resize: true, ResizeTo: 100, WebpQuality: 80}
+	processed, err := processImage(data, "anim.gif", settings)
+	if err != nil {
+		t.Fatalf("processImage failed: %v", err)
+	}
+	if processed.ContentType != "image/webp" {
+		t.Errorf("expected image/webp, got %s", processed.ContentType)
+	}
+
+	out := processed.Content.Bytes()
+	if detectAnimation(out) != animatedWebP {
+		t.Fatal("expected animated webp output")
+	}
+	anim, err := decodeWebPAnimation(out)
+	if err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if len(anim.frames) != 3 {
+		t.Fatalf("expected 3 frames, got %d", len(anim.frames))
+	}
+	if anim.width != 100 || anim.height != 50 {
+		t.Errorf("expected 100x50 canvas, got %dx%d", anim.width, anim.height)
+	}
+	if anim.delays[0] != 50 {
+		t.Errorf("expected 50ms delay, got %d", anim.delays[0])
+	}
+	// Второй кадр зелёный
+	_, g, b, _ := anim.frames[1].At(10, 10).RGBA()
+	if g>>8 < 200 || b>>8 > 50 {
+		t.Errorf("expected green second frame, got g=%d b=%d", g>>8, b>>8)
+	}
+}
+
+func TestProcessImage_AnimationModes(t *testing.T) {
+	data := createAnimatedGIF(t, 40, 20, []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}})
+
+	processed, err := processImage(data, "anim.gif", ResizeSettings{AnimationMode: AnimationPassthrough})
+	if err != nil {
+		t.Fatalf("passthrough failed: %v", err)
+	}
+	if processed.ContentType != "image/gif" || !bytes.Equal(processed.Content.Bytes(), data) {
+		t.Error("expected original GIF to be passed through")
+	}
+
+	processed, err = processImage(data, "anim.gif", ResizeSettings{AnimationMode: AnimationFlatten, WebpQuality: 80})
+	if err != nil {
+		t.Fatalf("flatten failed: %v", err)
+	}
+	if detectAnimation(processed.Content.Bytes()) != "" {
+		t.Error("expected flattened output to be static")
+	}
+}
+
+func TestDecodeImage_AnimatedWebPFirstFrame(t *testing.T) {
+	data := createAnimatedGIF(t, 40, 20, []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}})
+	processed, err := processImage(data, "anim.gif", ResizeSettings{WebpQuality: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, _, err := decodeImage(processed.Content.Bytes())
+	if err != nil {
+		t.Fatalf("decodeImage failed on animated webp: %v", err)
+	}
+	if r, _, _, _ := img.At(5, 5).RGBA(); r>>8 < 200 {
+		t.Errorf("expected red first frame, got r=%d", r>>8)
+	}
+}
+
+func TestCountGIFFrames(t *testing.T) {
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	anim := createAnimatedGIF(t, 8, 8, []color.RGBA{red, blue, red})
+	if n := countGIFFrames(anim); n != 3 {
+		t.Errorf("expected 3 frames, got %d", n)
+	}
+	if kind := detectAnimation(anim); kind != animatedGIF {
+		t.Errorf("expected animated GIF, got %q", kind)
+	}
+
+	// Статичный GIF с глобальной палитрой
+	var buf bytes.Buffer
+	img := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.Black, color.White})
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := countGIFFrames(buf.Bytes()); n != 1 {
+		t.Errorf("expected 1 frame, got %d", n)
+	}
+	if kind := detectAnimation(buf.Bytes()); kind != "" {
+		t.Errorf("expected static GIF, got %q", kind)
+	}
+
+	if n := countGIFFrames(anim[:len(anim)/2]); n != 0 {
+		t.Errorf("expected 0 for truncated GIF, got %d", n)
+	}
+}
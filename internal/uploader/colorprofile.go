@@ -0,0 +1,669 @@
+package uploader
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/disintegration/imaging"
+)
+
+// ICC-профили: читаем встроенный профиль (JPEG APP2, PNG iCCP, WebP ICCP)
+// и переводим пиксели в sRGB до ресайза. Поддерживаются matrix/TRC RGB-профили
+// (Adobe RGB, Display P3, ProPhoto…) и CMYK-профили с таблицами lut8/lut16.
+
+var errUnsupportedProfile = errors.New("unsupported ICC profile")
+
+// iccProfile — разобранный профиль
+type iccProfile struct {
+	colorSpace  string
+	pcs         string
+	description string
+
+	// matrix/TRC
+	hasMatrix bool
+	matrix    [3][3]float64 // RGB (linear) → XYZ D50, по столбцам rXYZ/gXYZ/bXYZ
+	trc       [3]toneCurve
+
+	// lut8/lut16 (A2B0)
+	lut *iccLUT
+}
+
+// toneCurve — кривая TRC: функция от [0,1] в [0,1]
+type toneCurve func(float64) float64
+
+// iccLUT — таблица lut8Type/lut16Type
+type iccLUT struct {
+	inChannels, outChannels, grid int
+	inTables                      [][]float64
+	clut                          []float64
+	outTables                     [][]float64
+	legacy8                       bool // lut8: Lab кодируется 8 битами
+}
+
+// sRGB (D50, после Bradford-адаптации) → XYZ
+var srgbD50 = [3][3]float64{
+	{0.4360747, 0.3850649, 0.1430804},
+	{0.2225045, 0.7168786, 0.0606169},
+	{0.0139322, 0.0971045, 0.7141733},
+}
+
+var xyzToSRGB = invert3(srgbD50)
+
+// extractICCProfile достаёт встроенный профиль из JPEG, PNG или WebP
+func extractICCProfile(data []byte) []byte {
+	switch {
+	case len(data) > 2 && data[0] == 0xFF && data[1] == 0xD8:
+		return jpegICCProfile(data)
+	case len(data) > 8 && bytes.Equal(data[:8], []byte("\x89PNG\r\n\x1a\n")):
+		return pngICCProfile(data)
+	case len(data) > 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return webpICCProfile(data)
+	}
+	return nil
+}
+
+// jpegSegments перебирает сегменты JPEG до начала данных (SOS)
+func jpegSegments(data []byte, fn func(marker byte, payload []byte)) {
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return
+		}
+		marker := data[pos+1]
+		if marker == 0xD8 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 || marker == 0xFF {
+			pos += 2
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			return
+		}
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if size < 2 || pos+2+size > len(data) {
+			return
+		}
+		fn(marker, data[pos+4:pos+2+size])
+		pos += 2 + size
+	}
+}
+
+func jpegICCProfile(data []byte) []byte {
+	const sig = "ICC_PROFILE\x00"
+	chunks := map[int][]byte{}
+	total := 0
+	jpegSegments(data, func(marker byte, payload []byte) {
+		if marker != 0xE2 || len(payload) < len(sig)+2 || string(payload[:len(sig)]) != sig {
+			return
+		}
+		seq := int(payload[len(sig)])
+		total = int(payload[len(sig)+1])
+		chunks[seq] = payload[len(sig)+2:]
+	})
+	if total == 0 {
+		return nil
+	}
+	var profile []byte
+	for i := 1; i <= total; i++ {
+		chunk, ok := chunks[i]
+		if !ok {
+			return nil
+		}
+		profile = append(profile, chunk...)
+	}
+	return profile
+}
+
+func pngICCProfile(data []byte) []byte {
+	pos := 8
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		typ := string(data[pos+4 : pos+8])
+		if pos+12+length > len(data) {
+			return nil
+		}
+		chunk := data[pos+8 : pos+8+length]
+		switch typ {
+		case "iCCP":
+			nul := bytes.IndexByte(chunk, 0)
+			if nul < 0 || nul+2 > len(chunk) {
+				return nil
+			}
+			r, err := zlib.NewReader(bytes.NewReader(chunk[nul+2:]))
+			if err != nil {
+				return nil
+			}
+			defer r.Close()
+			profile, err := io.ReadAll(r)
+			if err != nil {
+				return nil
+			}
+			return profile
+		case "IDAT", "IEND":
+			return nil
+		}
+		pos += 12 + length
+	}
+	return nil
+}
+
+func webpICCProfile(data []byte) []byte {
+	pos := 12
+	for pos+8 <= len(data) {
+		fourcc := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		if pos+8+size > len(data) {
+			return nil
+		}
+		if fourcc == "ICCP" {
+			return data[pos+8 : pos+8+size]
+		}
+		pos += 8 + size + size&1
+	}
+	return nil
+}
+
+// parseICCProfile разбирает заголовок и нужные теги
+func parseICCProfile(b []byte) (*iccProfile, error) {
+	if len(b) < 132 || string(b[36:40]) != "acsp" {
+		return nil, fmt.Errorf("invalid ICC header")
+	}
+	p := &iccProfile{
+		colorSpace: strings.TrimSpace(string(b[16:20])),
+		pcs:        strings.TrimSpace(string(b[20:24])),
+	}
+
+	tags := map[string][]byte{}
+	count := int(binary.BigEndian.Uint32(b[128:]))
+	for i := 0; i < count; i++ {
+		entry := 132 + i*12
+		if entry+12 > len(b) {
+			break
+		}
+		sig := string(b[entry : entry+4])
+		off := int(binary.BigEndian.Uint32(b[entry+4:]))
+		size := int(binary.BigEndian.Uint32(b[entry+8:]))
+		if off < 0 || size < 0 || off+size > len(b) {
+			continue
+		}
+		tags[sig] = b[off : off+size]
+	}
+
+	p.description = parseDescription(tags["desc"])
+
+	if p.colorSpace == "RGB" {
+		r, okR := parseXYZ(tags["rXYZ"])
+		g, okG := parseXYZ(tags["gXYZ"])
+		bl, okB := parseXYZ(tags["bXYZ"])
+		rt, errR := parseCurve(tags["rTRC"])
+		gt, errG := parseCurve(tags["gTRC"])
+		bt, errB := parseCurve(tags["bTRC"])
+		if okR && okG && okB && errR == nil && errG == nil && errB == nil {
+			p.hasMatrix = true
+			for row := 0; row < 3; row++ {
+				p.matrix[row] = [3]float64{r[row], g[row], bl[row]}
+			}
+			p.trc = [3]toneCurve{rt, gt, bt}
+		}
+	}
+
+	if lut, err := parseLUT(tags["A2B0"]); err == nil {
+		p.lut = lut
+	}
+	return p, nil
+}
+
+// isSRGB — профиль уже sRGB (по описанию или по первичным цветам)
+func (p *iccProfile) isSRGB() bool {
+	if strings.Contains(strings.ToLower(p.description), "srgb") {
+		return true
+	}
+	if !p.hasMatrix {
+		return false
+	}
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			if math.Abs(p.matrix[row][col]-srgbD50[row][col]) > 0.003 {
+				return false
+			}
+		}
+	}
+	// Проверяем, что TRC похожа на sRGB (в середине диапазона)
+	for _, c := range p.trc {
+		if math.Abs(c(0.5)-srgbToLinear(0.5)) > 0.01 {
+			return false
+		}
+	}
+	return true
+}
+
+// name — человекочитаемое имя профиля для предупреждений
+func (p *iccProfile) name() string {
+	if p.description != "" {
+		return p.description
+	}
+	return p.colorSpace + " profile"
+}
+
+func parseDescription(tag []byte) string {
+	if len(tag) < 12 {
+		return ""
+	}
+	switch string(tag[:4]) {
+	case "desc":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		if 12+n > len(tag) {
+			n = len(tag) - 12
+		}
+		return strings.TrimRight(string(tag[12:12+n]), "\x00")
+	case "mluc":
+		if len(tag) < 28 {
+			return ""
+		}
+		length := int(binary.BigEndian.Uint32(tag[20:]))
+		off := int(binary.BigEndian.Uint32(tag[24:]))
+		if off+length > len(tag) {
+			return ""
+		}
+		raw := tag[off : off+length]
+		u := make([]uint16, len(raw)/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(raw[i*2:])
+		}
+		return strings.TrimRight(string(utf16.Decode(u)), "\x00")
+	}
+	return ""
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+func parseXYZ(tag []byte) ([3]float64, bool) {
+	if len(tag) < 20 || string(tag[:4]) != "XYZ " {
+		return [3]float64{}, false
+	}
+	return [3]float64{s15Fixed16(tag[8:]), s15Fixed16(tag[12:]), s15Fixed16(tag[16:])}, true
+}
+
+func parseCurve(tag []byte) (toneCurve, error) {
+	if len(tag) < 12 {
+		return nil, errUnsupportedProfile
+	}
+	switch string(tag[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		if n > (len(tag)-12)/2 {
+			// Обрезанный тег: значений меньше, чем заявлено
+			return nil, errUnsupportedProfile
+		}
+		switch {
+		case n == 0:
+			return func(v float64) float64 { return v }, nil
+		case n == 1:
+			gamma := float64(binary.BigEndian.Uint16(tag[12:])) / 256
+			return func(v float64) float64 { return math.Pow(v, gamma) }, nil
+		default:
+			table := make([]float64, n)
+			for i := range table {
+				table[i] = float64(binary.BigEndian.Uint16(tag[12+i*2:])) / 65535
+			}
+			return func(v float64) float64 { return interpolate(table, v) }, nil
+		}
+	case "para":
+		fn := int(binary.BigEndian.Uint16(tag[8:]))
+		counts := []int{1, 3, 4, 5, 7}
+		if fn >= len(counts) || len(tag) < 12+counts[fn]*4 {
+			return nil, errUnsupportedProfile
+		}
+		var p [7]float64
+		for i := 0; i < counts[fn]; i++ {
+			p[i] = s15Fixed16(tag[12+i*4:])
+		}
+		g, a, b, c, d, e, f := p[0], p[1], p[2], p[3], p[4], p[5], p[6]
+		switch fn {
+		case 0:
+			return func(x float64) float64 { return math.Pow(x, g) }, nil
+		case 1:
+			return func(x float64) float64 {
+				if x >= -b/a {
+					return math.Pow(a*x+b, g)
+				}
+				return 0
+			}, nil
+		case 2:
+			return func(x float64) float64 {
+				if x >= -b/a {
+					return math.Pow(a*x+b, g) + c
+				}
+				return c
+			}, nil
+		case 3:
+			return func(x float64) float64 {
+				if x >= d {
+					return math.Pow(a*x+b, g)
+				}
+				return c * x
+			}, nil
+		case 4:
+			return func(x float64) float64 {
+				if x >= d {
+					return math.Pow(a*x+b, g) + e
+				}
+				return c*x + f
+			}, nil
+		}
+	}
+	return nil, errUnsupportedProfile
+}
+
+func parseLUT(tag []byte) (*iccLUT, error) {
+	if len(tag) < 52 {
+		return nil, errUnsupportedProfile
+	}
+	kind := string(tag[:4])
+	if kind != "mft1" && kind != "mft2" {
+		return nil, errUnsupportedProfile
+	}
+	l := &iccLUT{
+		inChannels:  int(tag[8]),
+		outChannels: int(tag[9]),
+		grid:        int(tag[10]),
+		legacy8:     kind == "mft1",
+	}
+	if l.inChannels < 1 || l.inChannels > 4 || l.outChannels != 3 || l.grid < 2 {
+		return nil, errUnsupportedProfile
+	}
+
+	inEntries, outEntries, width, pos := 256, 256, 1, 48
+	if kind == "mft2" {
+		inEntries = int(binary.BigEndian.Uint16(tag[48:]))
+		outEntries = int(binary.BigEndian.Uint16(tag[50:]))
+		width, pos = 2, 52
+	}
+	clutSize := l.outChannels
+	for i := 0; i < l.inChannels; i++ {
+		clutSize *= l.grid
+	}
+	need := pos + (l.inChannels*inEntries+clutSize+l.outChannels*outEntries)*width
+	if inEntries < 2 || outEntries < 2 || len(tag) < need {
+		return nil, errUnsupportedProfile
+	}
+
+	read := func(n int) []float64 {
+		vals := make([]float64, n)
+		for i := range vals {
+			if width == 2 {
+				vals[i] = float64(binary.BigEndian.Uint16(tag[pos:])) / 65535
+			} else {
+				vals[i] = float64(tag[pos]) / 255
+			}
+			pos += width
+		}
+		return vals
+	}
+	for i := 0; i < l.inChannels; i++ {
+		l.inTables = append(l.inTables, read(inEntries))
+	}
+	l.clut = read(clutSize)
+	for i := 0; i < l.outChannels; i++ {
+		l.outTables = append(l.outTables, read(outEntries))
+	}
+	return l, nil
+}
+
+// eval прогоняет значение через таблицы и многомерную интерполяцию CLUT
+func (l *iccLUT) eval(in []float64, out []float64) {
+	var idx [4]int
+	var frac [4]float64
+	for i := 0; i < l.inChannels; i++ {
+		v := interpolate(l.inTables[i], in[i]) * float64(l.grid-1)
+		base := int(v)
+		if base >= l.grid-1 {
+			base = l.grid - 2
+		}
+		idx[i] = base
+		frac[i] = v - float64(base)
+	}
+
+	for o := 0; o < l.outChannels; o++ {
+		out[o] = 0
+	}
+	corners := 1 << l.inChannels
+	for corner := 0; corner < corners; corner++ {
+		weight := 1.0
+		offset := 0
+		for i := 0; i < l.inChannels; i++ {
+			bit := (corner >> (l.inChannels - 1 - i)) & 1
+			if bit == 1 {
+				weight *= frac[i]
+			} else {
+				weight *= 1 - frac[i]
+			}
+			offset = offset*l.grid + idx[i] + bit
+		}
+		if weight == 0 {
+			continue
+		}
+		for o := 0; o < l.outChannels; o++ {
+			out[o] += weight * l.clut[offset*l.outChannels+o]
+		}
+	}
+	for o := 0; o < l.outChannels; o++ {
+		out[o] = interpolate(l.outTables[o], out[o])
+	}
+}
+
+// pcsToXYZ переводит выход LUT (Lab или XYZ в кодировке ICC v2) в XYZ D50
+func (l *iccLUT) pcsToXYZ(pcs string, v []float64) (float64, float64, float64) {
+	if pcs == "XYZ" {
+		scale := 65535.0 / 32768.0
+		return v[0] * scale, v[1] * scale, v[2] * scale
+	}
+	var L, a, b float64
+	if l.legacy8 {
+		L, a, b = v[0]*100, v[1]*255-128, v[2]*255-128
+	} else {
+		k := 65535.0 / 65280.0
+		L, a, b = v[0]*100*k, v[1]*255*k-128, v[2]*255*k-128
+	}
+	return labToXYZ(L, a, b)
+}
+
+func labToXYZ(L, a, b float64) (float64, float64, float64) {
+	const xn, yn, zn = 0.9642, 1.0, 0.8249
+	fy := (L + 16) / 116
+	fx := fy + a/500
+	fz := fy - b/200
+	finv := func(t float64) float64 {
+		if t > 6.0/29 {
+			return t * t * t
+		}
+		return 3 * (6.0 / 29) * (6.0 / 29) * (t - 4.0/29)
+	}
+	return xn * finv(fx), yn * finv(fy), zn * finv(fz)
+}
+
+func interpolate(table []float64, v float64) float64 {
+	if v <= 0 {
+		return table[0]
+	}
+	if v >= 1 {
+		return table[len(table)-1]
+	}
+	pos := v * float64(len(table)-1)
+	i := int(pos)
+	f := pos - float64(i)
+	return table[i]*(1-f) + table[i+1]*f
+}
+
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// encodeLUT — таблица линейный → 8-бит sRGB с шагом 1/4095
+var encodeLUT = func() [4096]uint8 {
+	var t [4096]uint8
+	for i := range t {
+		t[i] = clamp8(linearToSRGB(float64(i) / 4095))
+	}
+	return t
+}()
+
+func encodeSRGB(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return encodeLUT[int(v*4095+0.5)]
+}
+
+func clamp8(v float64) uint8 {
+	v = math.Round(v * 255)
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+func invert3(m [3][3]float64) [3][3]float64 {
+	a, b, c := m[0][0], m[0][1], m[0][2]
+	d, e, f := m[1][0], m[1][1], m[1][2]
+	g, h, i := m[2][0], m[2][1], m[2][2]
+	det := a*(e*i-f*h) - b*(d*i-f*g) + c*(d*h-e*g)
+	return [3][3]float64{
+		{(e*i - f*h) / det, (c*h - b*i) / det, (b*f - c*e) / det},
+		{(f*g - d*i) / det, (a*i - c*g) / det, (c*d - a*f) / det},
+		{(d*h - e*g) / det, (b*g - a*h) / det, (a*e - b*d) / det},
+	}
+}
+
+func mul3(a, b [3][3]float64) [3][3]float64 {
+	var r [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return r
+}
+
+// convertToSRGB применяет профиль к декодированному изображению.
+// Возвращает новое изображение и предупреждение (пустое, если всё штатно).
+func convertToSRGB(img image.Image, profileData []byte) (image.Image, string) {
+	cmyk, isCMYK := img.(*image.CMYK)
+
+	if len(profileData) == 0 {
+		if isCMYK {
+			return cmykNaive(cmyk), "CMYK без ICC-профиля: цвета переведены приблизительно"
+		}
+		return img, ""
+	}
+
+	profile, err := parseICCProfile(profileData)
+	if err != nil {
+		if isCMYK {
+			return cmykNaive(cmyk), "повреждённый ICC-профиль CMYK: цвета переведены приблизительно"
+		}
+		return img, "повреждённый ICC-профиль проигнорирован"
+	}
+
+	switch {
+	case isCMYK && profile.colorSpace == "CMYK" && profile.lut != nil && profile.lut.inChannels == 4:
+		return cmykWithLUT(cmyk, profile), fmt.Sprintf("CMYK-профиль «%s» преобразован в sRGB", profile.name())
+	case isCMYK:
+		return cmykNaive(cmyk), fmt.Sprintf("CMYK-профиль «%s» не поддерживается: цвета переведены приблизительно", profile.name())
+	case profile.colorSpace == "RGB" && profile.isSRGB():
+		return img, ""
+	case profile.colorSpace == "RGB" && profile.hasMatrix:
+		return rgbWithMatrix(img, profile), fmt.Sprintf("профиль «%s» преобразован в sRGB", profile.name())
+	case profile.colorSpace == "RGB":
+		return img, fmt.Sprintf("профиль «%s» не поддерживается: цвета могут отличаться", profile.name())
+	}
+	return img, ""
+}
+
+func rgbWithMatrix(img image.Image, p *iccProfile) image.Image {
+	dst := imaging.Clone(img)
+	m := mul3(xyzToSRGB, p.matrix)
+
+	var lin [3][256]float64
+	for c := 0; c < 3; c++ {
+		for v := 0; v < 256; v++ {
+			lin[c][v] = p.trc[c](float64(v) / 255)
+		}
+	}
+
+	pix := dst.Pix
+	for i := 0; i+3 < len(pix); i += 4 {
+		r, g, b := lin[0][pix[i]], lin[1][pix[i+1]], lin[2][pix[i+2]]
+		pix[i] = encodeSRGB(m[0][0]*r + m[0][1]*g + m[0][2]*b)
+		pix[i+1] = encodeSRGB(m[1][0]*r + m[1][1]*g + m[1][2]*b)
+		pix[i+2] = encodeSRGB(m[2][0]*r + m[2][1]*g + m[2][2]*b)
+	}
+	return dst
+}
+
+func cmykWithLUT(src *image.CMYK, p *iccProfile) image.Image {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+
+	// Кэш по исходному цвету: в сканах много повторяющихся значений
+	cache := make(map[uint32][3]uint8)
+	in := make([]float64, 4)
+	out := make([]float64, 3)
+
+	for y := 0; y < b.Dy(); y++ {
+		srcRow := src.Pix[y*src.Stride : y*src.Stride+b.Dx()*4]
+		dstRow := dst.Pix[y*dst.Stride : y*dst.Stride+b.Dx()*4]
+		for x := 0; x+3 < len(srcRow); x += 4 {
+			key := binary.BigEndian.Uint32(srcRow[x:])
+			rgb, ok := cache[key]
+			if !ok {
+				for c := 0; c < 4; c++ {
+					in[c] = float64(srcRow[x+c]) / 255
+				}
+				p.lut.eval(in, out)
+				X, Y, Z := p.lut.pcsToXYZ(p.pcs, out)
+				m := xyzToSRGB
+				rgb = [3]uint8{
+					encodeSRGB(m[0][0]*X + m[0][1]*Y + m[0][2]*Z),
+					encodeSRGB(m[1][0]*X + m[1][1]*Y + m[1][2]*Z),
+					encodeSRGB(m[2][0]*X + m[2][1]*Y + m[2][2]*Z),
+				}
+				if len(cache) < 1<<20 {
+					cache[key] = rgb
+				}
+			}
+			dstRow[x], dstRow[x+1], dstRow[x+2], dstRow[x+3] = rgb[0], rgb[1], rgb[2], 255
+		}
+	}
+	return dst
+}
+
+func cmykNaive(src *image.CMYK) image.Image {
+	return imaging.Clone(src)
+}
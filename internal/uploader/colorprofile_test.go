@@ -0,0 +1,269 @@
+package uploader
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// buildICC собирает минимальный ICC-профиль из тегов
+func buildICC(colorSpace, pcs string, tags map[string][]byte) []byte {
+	sigs := make([]string, 0, len(tags))
+	for sig := range tags {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+
+	header := make([]byte, 128)
+	copy(header[16:], colorSpace)
+	copy(header[20:], pcs)
+	copy(header[36:], "acsp")
+
+	table := make([]byte, 4+12*len(sigs))
+	binary.BigEndian.PutUint32(table, uint32(len(sigs)))
+	var body []byte
+	offset := 128 + len(table)
+	for i, sig := range sigs {
+		data := tags[sig]
+		entry := table[4+i*12:]
+		copy(entry, sig)
+		binary.BigEndian.PutUint32(entry[4:], uint32(offset+len(body)))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(data)))
+		body = append(body, data...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+	profile := append(append(header, table...), body...)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	return profile
+}
+
+func xyzTag(x, y, z float64) []byte {
+	b := make([]byte, 20)
+	copy(b, "XYZ ")
+	for i, v := range []float64{x, y, z} {
+		binary.BigEndian.PutUint32(b[8+i*4:], uint32(int32(v*65536)))
+	}
+	return b
+}
+
+func gammaTag(g float64) []byte {
+	b := make([]byte, 14)
+	copy(b, "curv")
+	binary.BigEndian.PutUint32(b[8:], 1)
+	binary.BigEndian.PutUint16(b[12:], uint16(g*256))
+	return b
+}
+
+func descTag(s string) []byte {
+	b := make([]byte, 12+len(s)+1)
+	copy(b, "desc")
+	binary.BigEndian.PutUint32(b[8:], uint32(len(s)+1))
+	copy(b[12:], s)
+	return b
+}
+
+// Adobe RGB (1998), адаптированный к D50
+func adobeRGBProfile() []byte {
+	return buildICC("RGB ", "XYZ ", map[string][]byte{
+		"desc": descTag("Adobe RGB (1998)"),
+		"rXYZ": xyzTag(0.6097, 0.3111, 0.0195),
+		"gXYZ": xyzTag(0.2053, 0.6257, 0.0609),
+		"bXYZ": xyzTag(0.1492, 0.0632, 0.7446),
+		"rTRC": gammaTag(2.2),
+		"gTRC": gammaTag(2.2),
+		"bTRC": gammaTag(2.2),
+	})
+}
+
+// embedPNGProfile вставляет iCCP сразу после IHDR
+func embedPNGProfile(t *testing.T, pngData, profile []byte) []byte {
+	var z bytes.Buffer
+	w := zlib.NewWriter(&z)
+	w.Write(profile)
+	w.Close()
+
+	payload := append([]byte("test\x00\x00"), z.Bytes()...)
+	chunk := make([]byte, 8+len(payload)+4)
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	copy(chunk[4:], "iCCP")
+	copy(chunk[8:], payload)
+	binary.BigEndian.PutUint32(chunk[8+len(payload):], crc32.ChecksumIEEE(chunk[4:8+len(payload)]))
+
+	ihdrEnd := 8 + 8 + 13 + 4
+	out := append([]byte{}, pngData[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, pngData[ihdrEnd:]...)
+}
+
+func solidPNG(t *testing.T, c color.NRGBA) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeImage_AdobeRGB(t *testing.T) {
+	data := embedPNGProfile(t, solidPNG(t, color.NRGBA{0, 200, 0, 255}), adobeRGBProfile())
+
+	if extractICCProfile(data) == nil {
+		t.Fatal("expected embedded profile to be found")
+	}
+
+	img, warnings, err := decodeImage(data)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "Adobe RGB") {
+		t.Errorf("expected Adobe RGB warning, got %v", warnings)
+	}
+
+	// Насыщенный зелёный Adobe RGB выходит за sRGB: красный уходит в 0, зелёный растёт
+	r, g, _, _ := img.At(0, 0).RGBA()
+	if r>>8 != 0 || g>>8 <= 200 {
+		t.Errorf("expected converted green, got r=%d g=%d", r>>8, g>>8)
+	}
+}
+
+func TestDecodeImage_SRGBProfileUntouched(t *testing.T) {
+	profile := buildICC("RGB ", "XYZ ", map[string][]byte{
+		"desc": descTag("sRGB IEC61966-2.1"),
+	})
+	data := embedPNGProfile(t, solidPNG(t, color.NRGBA{10, 200, 30, 255}), profile)
+
+	img, warnings, err := decodeImage(data)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings for sRGB, got %v", warnings)
+	}
+	r, g, b, _ := img.At(0, 0).RGBA()
+	if r>>8 != 10 || g>>8 != 200 || b>>8 != 30 {
+		t.Errorf("expected untouched pixel, got %d %d %d", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestConvertToSRGB_CMYK(t *testing.T) {
+	src := image.NewCMYK(image.Rect(0, 0, 2, 1))
+	// Без краски и полный чёрный
+	copy(src.Pix, []byte{0, 0, 0, 0, 0, 0, 0, 255})
+
+	out, warning := convertToSRGB(src, nil)
+	if warning == "" {
+		t.Error("expected warning for CMYK without profile")
+	}
+	if r, _, _, _ := out.At(0, 0).RGBA(); r>>8 != 255 {
+		t.Errorf("expected white, got %d", r>>8)
+	}
+
+	// CMYK → Lab lut16 с сеткой 2: L падает с чёрной краской, a/b нейтральны
+	lut := make([]byte, 52)
+	copy(lut, "mft2")
+	lut[8], lut[9], lut[10] = 4, 3, 2
+	for i, v := range []float64{1, 0, 0, 0, 1, 0, 0, 0, 1} {
+		binary.BigEndian.PutUint32(lut[12+i*4:], uint32(int32(v*65536)))
+	}
+	binary.BigEndian.PutUint16(lut[48:], 2)
+	binary.BigEndian.PutUint16(lut[50:], 2)
+	put := func(v uint16) { lut = binary.BigEndian.AppendUint16(lut, v) }
+	for i := 0; i < 4; i++ {
+		put(0)
+		put(0xFFFF)
+	}
+	for c := 0; c < 2; c++ {
+		for m := 0; m < 2; m++ {
+			for y := 0; y < 2; y++ {
+				for k := 0; k < 2; k++ {
+					if k == 1 {
+						put(0)
+					} else {
+						put(0xFF00)
+					}
+					put(0x8080)
+					put(0x8080)
+				}
+			}
+		}
+	}
+	for i := 0; i < 3; i++ {
+		put(0)
+		put(0xFFFF)
+	}
+	profile := buildICC("CMYK", "Lab ", map[string][]byte{
+		"desc": descTag("Test CMYK"),
+		"A2B0": lut,
+	})
+
+	out, warning = convertToSRGB(src, profile)
+	if !strings.Contains(warning, "Test CMYK") {
+		t.Errorf("expected conversion warning, got %q", warning)
+	}
+	if r, g, b, _ := out.At(0, 0).RGBA(); r>>8 < 250 || g>>8 < 250 || b>>8 < 250 {
+		t.Errorf("expected white for no ink, got %d %d %d", r>>8, g>>8, b>>8)
+	}
+	if r, _, _, _ := out.At(1, 0).RGBA(); r>>8 > 5 {
+		t.Errorf("expected black for full K, got %d", r>>8)
+	}
+}
+
+func TestReadOrientation(t *testing.T) {
+	// SOI + APP1 с EXIF (II, IFD0 с одним тегом Orientation=6)
+	tiff := []byte{'I', 'I', 42, 0, 8, 0, 0, 0, 1, 0, 0x12, 0x01, 3, 0, 1, 0, 0, 0, 6, 0, 0, 0, 0, 0, 0, 0}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(data[4:], uint16(len(payload)+2))
+	data = append(data, payload...)
+	data = append(data, 0xFF, 0xD9)
+
+	if o := readOrientation(data); o != 6 {
+		t.Errorf("expected orientation 6, got %d", o)
+	}
+	if o := readOrientation([]byte("not a jpeg")); o != 0 {
+		t.Errorf("expected 0 for non-JPEG, got %d", o)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	if b := applyOrientation(img, 6).Bounds(); b.Dx() != 2 || b.Dy() != 4 {
+		t.Errorf("expected rotated bounds 2x4, got %v", b)
+	}
+}
+
+func TestParseCurve_Truncated(t *testing.T) {
+	// curv с count=1, но без значения гаммы: 12 байт вместо 14
+	tag := make([]byte, 12)
+	copy(tag, "curv")
+	binary.BigEndian.PutUint32(tag[8:], 1)
+	if _, err := parseCurve(tag); err != errUnsupportedProfile {
+		t.Errorf("expected errUnsupportedProfile for truncated gamma, got %v", err)
+	}
+
+	// Таблица из 4 значений, а в теге только 3
+	tag = append(tag, 0, 0, 0x80, 0, 0xFF, 0xFF)
+	binary.BigEndian.PutUint32(tag[8:], 4)
+	if _, err := parseCurve(tag); err != errUnsupportedProfile {
+		t.Errorf("expected errUnsupportedProfile for truncated table, got %v", err)
+	}
+
+	binary.BigEndian.PutUint32(tag[8:], 3)
+	curve, err := parseCurve(tag)
+	if err != nil {
+		t.Fatalf("parseCurve failed: %v", err)
+	}
+	if v := curve(1); v < 0.99 {
+		t.Errorf("expected curve(1) ≈ 1, got %f", v)
+	}
+}
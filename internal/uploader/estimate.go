@@ -0,0 +1,243 @@
+package uploader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"telegraph_uploader_v2/internal/imageformat"
+	"telegraph_uploader_v2/internal/telegraph"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// MaxWebPDimension — предел стороны WebP; более длинные стрипы не закодировать
+const MaxWebPDimension = 16383
+
+// TelegraphContentLimit — примерный лимит размера content в Telegraph
+const TelegraphContentLimit = telegraph.MaxContentSize
+
+// PageEstimate — прогноз по одной странице
+type PageEstimate struct {
+	Path       string `json:"path"`
+	InputSize  int64  `json:"input_size"`
+	OutputSize int64  `json:"output_size"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Cached     bool   `json:"cached"`
+	Sampled    bool   `json:"sampled"`
+	Error      string `json:"error,omitempty"`
+	// Outputs — сколько картинок получится из файла (разрезанный разворот даёт две)
+	Outputs int `json:"outputs"`
+}
+
+// EstimateResult — итог пробного прогона главы без загрузки
+type EstimateResult struct {
+	Success              bool           `json:"success"`
+	Error                string         `json:"error"`
+	Files                int            `json:"files"`
+	Sampled              int            `json:"sampled"`
+	CacheHits            int            `json:"cache_hits"`
+	InputBytes           int64          `json:"input_bytes"`
+	EstimatedOutputBytes int64          `json:"estimated_output_bytes"`
+	CompressionRatio     float64        `json:"compression_ratio"`
+	Errors               int            `json:"errors"`
+	OutputImages         int            `json:"output_images"`
+	PageContentBytes     int            `json:"page_content_bytes"`
+	ExceedsPageLimit     bool           `json:"exceeds_page_limit"`
+	Pages                []PageEstimate `json:"pages"`
+}
+
+// EstimateChapter прогоняет главу через обработку без загрузки в R2.
+// sampleSize > 0 ограничивает число реально кодируемых страниц (берутся равномерно),
+// для остальных размер экстраполируется по среднему коэффициенту сжатия.
+func (u *R2Uploader) EstimateChapter(ctx context.Context, filePaths []string, resizeSettings ResizeSettings, sampleSize int, onProgress func(int, int)) EstimateResult {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.NumCPU())
+
+	pages := make([]PageEstimate, len(filePaths))
+	urls := make([][]string, len(filePaths))
+	sampled := sampleIndices(len(filePaths), sampleSize)
+
+	var processedCount int32
+	totalFiles := len(filePaths)
+	domain := u.normalizeDomain()
+
+	for i, path := range filePaths {
+		i, path := i, path
+		g.Go(func() error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			defer func() {
+				newCount := atomic.AddInt32(&processedCount, 1)
+				if onProgress != nil {
+					onProgress(int(newCount), totalFiles)
+				}
+			}()
+
+			page := PageEstimate{Path: path, Sampled: sampled[i]}
+
+			fileData, err := os.ReadFile(path)
+			if err != nil {
+				page.Error = fmt.Sprintf("Read error: %v", err)
+				pages[i] = page
+				return nil
+			}
+			page.InputSize = int64(len(fileData))
+
+			// Кэш проверяется так же, как при загрузке: у разрезанных разворотов несколько ссылок
+			if u.cacheRepo != nil {
+				if cached := u.cachedPageURLs(calculateHash(fileData), fileData, resizeSettings); len(cached) > 0 {
+					page.Cached = true
+					page.Outputs = len(cached)
+					urls[i] = cached
+				}
+			}
+
+			cfg, _, err := imageformat.DecodeConfig(fileData)
+			if err != nil && !page.Cached {
+				page.Error = fmt.Sprintf("Decode error: %v", err)
+				pages[i] = page
+				return nil
+			}
+			if err == nil {
+				page.Width, page.Height = targetSize(cfg.Width, cfg.Height, resizeSettings)
+			}
+			if page.Cached {
+				// Картинки уже загружены, кодировать и проверять их не нужно
+				pages[i] = page
+				return nil
+			}
+			if page.Width > MaxWebPDimension || page.Height > MaxWebPDimension {
+				// Страница не пройдёт кодирование в WebP: её нужно уменьшить или разрезать заранее
+				page.Error = fmt.Sprintf("Page %dx%d exceeds WebP limit of %d px", page.Width, page.Height, MaxWebPDimension)
+				pages[i] = page
+				return nil
+			}
+
+			if !page.Sampled {
+				page.Outputs = 1
+				if isSplitMode(resizeSettings.SpreadMode) && !keepsWholePage(fileData, resizeSettings) {
+					page.Outputs = 2
+				}
+				urls[i] = projectedURLs(domain, path, page.Outputs)
+				pages[i] = page
+				return nil
+			}
+
+			processedPages, _, err := processPage(fileData, filepath.Base(path), resizeSettings)
+			if err != nil {
+				page.Error = fmt.Sprintf("Processing failed: %v", err)
+				pages[i] = page
+				return nil
+			}
+			for _, processed := range processedPages {
+				page.OutputSize += processed.Size
+			}
+			page.Outputs = len(processedPages)
+			urls[i] = projectedURLs(domain, path, page.Outputs)
+			pages[i] = page
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return EstimateResult{Success: false, Error: "Estimate cancelled or failed: " + err.Error()}
+	}
+
+	result := EstimateResult{Success: true, Files: len(filePaths), Pages: pages}
+
+	// Средний коэффициент сжатия по реально закодированным страницам
+	var sampleIn, sampleOut int64
+	for _, p := range pages {
+		if p.Sampled && !p.Cached && p.Error == "" {
+			sampleIn += p.InputSize
+			sampleOut += p.OutputSize
+			result.Sampled++
+		}
+	}
+	ratio := 1.0
+	if sampleIn > 0 {
+		ratio = float64(sampleOut) / float64(sampleIn)
+	}
+
+	for i := range pages {
+		p := &pages[i]
+		result.InputBytes += p.InputSize
+		if p.Error != "" {
+			result.Errors++
+		}
+		result.OutputImages += p.Outputs
+		if p.Cached {
+			result.CacheHits++
+			continue
+		}
+		if p.Error == "" && !p.Sampled {
+			p.OutputSize = int64(float64(p.InputSize) * ratio)
+		}
+		result.EstimatedOutputBytes += p.OutputSize
+	}
+	if result.InputBytes > 0 {
+		result.CompressionRatio = float64(result.EstimatedOutputBytes) / float64(result.InputBytes)
+	}
+
+	result.PageContentBytes = projectedContentSize(urls)
+	result.ExceedsPageLimit = result.PageContentBytes > TelegraphContentLimit
+	return result
+}
+
+// sampleIndices равномерно выбирает sampleSize индексов из n
+func sampleIndices(n, sampleSize int) []bool {
+	picked := make([]bool, n)
+	if sampleSize <= 0 || sampleSize >= n {
+		for i := range picked {
+			picked[i] = true
+		}
+		return picked
+	}
+	step := float64(n) / float64(sampleSize)
+	for k := 0; k < sampleSize; k++ {
+		picked[int(float64(k)*step)] = true
+	}
+	return picked
+}
+
+// projectedFileName повторяет формат имени из processImage (19 цифр UnixNano)
+func projectedFileName(path string) string {
+	name := filepath.Base(path)
+	nameWithoutExt := strings.TrimSuffix(name, filepath.Ext(name))
+	return fmt.Sprintf("%019d_%s.webp", 0, nameWithoutExt)
+}
+
+// projectedURLs — адреса, под которыми будут загружены outputs картинок файла
+// (части разворота получают суффикс _1, _2, как в processPage)
+func projectedURLs(domain, path string, outputs int) []string {
+	if outputs <= 1 {
+		return []string{fmt.Sprintf("%s/%s", domain, projectedFileName(path))}
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	urls := make([]string, outputs)
+	for part := range urls {
+		urls[part] = fmt.Sprintf("%s/%s", domain, projectedFileName(fmt.Sprintf("%s_%d%s", base, part+1, ext)))
+	}
+	return urls
+}
+
+// projectedContentSize считает размер JSON, который уйдёт в Telegraph
+func projectedContentSize(urls [][]string) int {
+	var all []string
+	for _, fileURLs := range urls {
+		all = append(all, fileURLs...)
+	}
+	data, _ := json.Marshal(telegraph.ImageNodes(all))
+	return len(data)
+}
@@ -0,0 +1,176 @@
+package uploader
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"telegraph_uploader_v2/internal/config"
+	"telegraph_uploader_v2/internal/database"
+)
+
+type mockCache struct {
+	urls    map[string]string
+	sources map[string]string
+}
+
+func (m *mockCache) GetURL(hash string) (string, bool) {
+	u, ok := m.urls[hash]
+	return u, ok
+}
+
+func (m *mockCache) FindByURL(url string) (database.UploadedFile, bool) {
+	for hash, u := range m.urls {
+		if u == url {
+			return database.UploadedFile{Hash: hash, URL: u, SourcePath: m.sources[hash]}, true
+		}
+	}
+	return database.UploadedFile{}, false
+}
+
+func (m *mockCache) ReplacePrefix(oldPrefix, newPrefix string) (int64, error) {
+	var n int64
+	for hash, u := range m.urls {
+		if strings.HasPrefix(u, oldPrefix) {
+			m.urls[hash] = newPrefix + strings.TrimPrefix(u, oldPrefix)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockCache) Save(hash, url, sourcePath string) error {
+	m.urls[hash] = url
+	if m.sources == nil {
+		m.sources = map[string]string{}
+	}
+	m.sources[hash] = sourcePath
+	return nil
+}
+
+func TestEstimateChapter(t *testing.T) {
+	tmpDir := t.TempDir()
+	p1 := createPatternImage(t, tmpDir, "001.png", 400, 600, 1)
+	p2 := createPatternImage(t, tmpDir, "002.png", 400, 600, 2)
+	p3 := createPatternImage(t, tmpDir, "003.png", 400, 600, 3)
+
+	cache := &mockCache{urls: map[string]string{}}
+	u := NewWithClient(nil, &config.Config{PublicDomain: "cdn.example.com"}, cache)
+
+	settings := ResizeSettings{Resize: true, ResizeTo: 200, WebpQuality: 80}
+	res := u.EstimateChapter(context.Background(), []string{p1, p2, p3}, settings, 0, nil)
+	if !res.Success {
+		t.Fatalf("estimate failed: %s", res.Error)
+	}
+	if res.Files != 3 || res.Sampled != 3 {
+		t.Errorf("expected 3 files sampled, got %d/%d", res.Sampled, res.Files)
+	}
+	if res.EstimatedOutputBytes == 0 || res.CompressionRatio <= 0 {
+		t.Errorf("expected output estimate, got %+v", res)
+	}
+	if res.Pages[0].Width != 200 || res.Pages[0].Height != 300 {
+		t.Errorf("expected projected 200x300, got %dx%d", res.Pages[0].Width, res.Pages[0].Height)
+	}
+	if res.Errors != 0 {
+		t.Errorf("expected no page errors, got %d", res.Errors)
+	}
+	if res.PageContentBytes == 0 || res.ExceedsPageLimit {
+		t.Errorf("unexpected page content estimate: %d", res.PageContentBytes)
+	}
+
+	// Sampling + cache hit
+	data, err := os.ReadFile(p1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.urls[calculateHash(data)] = "https://cdn.example.com/cached.webp"
+	res = u.EstimateChapter(context.Background(), []string{p1, p2, p3}, settings, 1, nil)
+	if res.CacheHits != 1 {
+		t.Errorf("expected 1 cache hit, got %d", res.CacheHits)
+	}
+	if res.Sampled != 0 {
+		// Первая (и единственная выбранная) страница в кэше — кодировать нечего
+		t.Errorf("expected 0 encoded samples, got %d", res.Sampled)
+	}
+	if res.Pages[1].OutputSize == 0 {
+		t.Error("expected extrapolated size for unsampled page")
+	}
+}
+
+func TestEstimateChapter_TallStrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	p := createTestImage(t, tmpDir, "strip.png", 10, MaxWebPDimension+10)
+
+	u := NewWithClient(nil, &config.Config{PublicDomain: "d"}, nil)
+	res := u.EstimateChapter(context.Background(), []string{p}, ResizeSettings{}, 1, nil)
+	if res.Errors != 1 || !strings.Contains(res.Pages[0].Error, "exceeds WebP limit") {
+		t.Errorf("expected error for a strip taller than WebP limit, got %d %q", res.Errors, res.Pages[0].Error)
+	}
+}
+
+func TestEstimateChapter_SplitSpreads(t *testing.T) {
+	tmpDir := t.TempDir()
+	spread := createTestImage(t, tmpDir, "001.png", 800, 400)
+	single := createTestImage(t, tmpDir, "002.png", 400, 600)
+
+	u := NewWithClient(nil, &config.Config{PublicDomain: "d"}, &mockCache{urls: map[string]string{}})
+	settings := ResizeSettings{WebpQuality: 80, SpreadMode: SpreadSplitRTL}
+	for _, sample := range []int{0, 1} {
+		res := u.EstimateChapter(context.Background(), []string{spread, single}, settings, sample, nil)
+		if res.Pages[0].Outputs != 2 || res.Pages[1].Outputs != 1 || res.OutputImages != 3 {
+			t.Errorf("sample %d: expected a split spread to give two images, got %d+%d=%d",
+				sample, res.Pages[0].Outputs, res.Pages[1].Outputs, res.OutputImages)
+		}
+		if want := projectedContentSize([][]string{projectedURLs(u.normalizeDomain(), spread, 2), projectedURLs(u.normalizeDomain(), single, 1)}); res.PageContentBytes != want {
+			t.Errorf("sample %d: expected content size %d, got %d", sample, want, res.PageContentBytes)
+		}
+	}
+}
+
+func TestEstimateChapter_CachedPages(t *testing.T) {
+	tmpDir := t.TempDir()
+	spread := createTestImage(t, tmpDir, "001.png", 800, 400)
+	strip := createTestImage(t, tmpDir, "002.png", 10, MaxWebPDimension+10)
+
+	cache := &mockCache{urls: map[string]string{}}
+	for path, urls := range map[string][]string{spread: {"d/a_1.webp", "d/a_2.webp"}, strip: {"d/b.webp"}} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := calculateHash(data)
+		if len(urls) == 1 {
+			cache.urls[hash] = urls[0]
+			continue
+		}
+		for part, url := range urls {
+			cache.urls[spreadCacheKey(hash, SpreadSplitRTL, part)] = url
+		}
+	}
+
+	u := NewWithClient(nil, &config.Config{PublicDomain: "d"}, cache)
+	res := u.EstimateChapter(context.Background(), []string{spread, strip}, ResizeSettings{SpreadMode: SpreadSplitRTL}, 0, nil)
+	if res.CacheHits != 2 || res.Errors != 0 {
+		t.Errorf("cached pages must be hits without errors, got %d hits, %d errors", res.CacheHits, res.Errors)
+	}
+	if res.OutputImages != 3 {
+		t.Errorf("expected cached spread halves counted, got %d", res.OutputImages)
+	}
+	if want := projectedContentSize([][]string{{"d/a_1.webp", "d/a_2.webp"}, {"d/b.webp"}}); res.PageContentBytes != want {
+		t.Errorf("expected content size of cached urls %d, got %d", want, res.PageContentBytes)
+	}
+}
+
+func TestSampleIndices(t *testing.T) {
+	picked := sampleIndices(10, 3)
+	n := 0
+	for _, p := range picked {
+		if p {
+			n++
+		}
+	}
+	if n != 3 || !picked[0] {
+		t.Errorf("expected 3 evenly spaced picks starting at 0, got %v", picked)
+	}
+}
@@ -2,38 +2,146 @@ package uploader
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"image"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"telegraph_uploader_v2/internal/imageformat"
+
 	"github.com/chai2010/webp"
 	"github.com/disintegration/imaging"
 )
 
 // ProcessedImage содержит готовые данные для отправки
 type ProcessedImage struct {
-	Content  *bytes.Buffer
-	FileName string
-	Size     int64
+	Content     *bytes.Buffer
+	FileName    string
+	ContentType string
+	Size        int64
+	Warnings    []string
+}
+
+// decodeImage декодирует картинку, переводит цвета в sRGB по встроенному ICC-профилю
+// и только потом применяет EXIF-ориентацию (поворот imaging терял бы CMYK).
+// Для анимаций возвращается первый кадр.
+func decodeImage(data []byte) (image.Image, []string, error) {
+	if kind := detectAnimation(data); kind != "" {
+		anim, err := decodeAnimation(data, kind)
+		if err != nil {
+			return nil, nil, err
+		}
+		return anim.frames[0], nil, nil
+	}
+
+	img, _, err := imageformat.Decode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	img, warning := convertToSRGB(img, extractICCProfile(data))
+	if warning != "" {
+		warnings = append(warnings, warning)
+	}
+
+	return applyOrientation(img, readOrientation(data)), warnings, nil
+}
+
+// readOrientation читает тег Orientation из EXIF (только JPEG)
+func readOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0
+	}
+	orientation := 0
+	jpegSegments(data, func(marker byte, payload []byte) {
+		if orientation != 0 || marker != 0xE1 || len(payload) < 14 || string(payload[:6]) != "Exif\x00\x00" {
+			return
+		}
+		tiff := payload[6:]
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return
+		}
+		ifd := int(order.Uint32(tiff[4:]))
+		if ifd < 8 || ifd+2 > len(tiff) {
+			return
+		}
+		count := int(order.Uint16(tiff[ifd:]))
+		for i := 0; i < count; i++ {
+			entry := ifd + 2 + i*12
+			if entry+12 > len(tiff) {
+				return
+			}
+			if order.Uint16(tiff[entry:]) == 0x0112 {
+				if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+					orientation = v
+				}
+				return
+			}
+		}
+	})
+	return orientation
+}
+
+// applyOrientation повторяет преобразования imaging.AutoOrientation
+func applyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	}
+	return img
 }
 
 // processImage берет данные и имя файла, обрабатывает картинку и возвращает буфер + имя
 func processImage(data []byte, filename string, resizeSettings ResizeSettings) (*ProcessedImage, error) {
-	// 1. Открытие
-	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	// 0. Анимация (GIF / анимированный WebP)
+	if kind := detectAnimation(data); kind != "" {
+		switch resizeSettings.AnimationMode {
+		case AnimationPassthrough:
+			return passthroughImage(data, filename, kind), nil
+		case AnimationFlatten:
+			// decodeImage вернёт первый кадр
+		default:
+			return processAnimation(data, filename, kind, resizeSettings)
+		}
+	}
+
+	// 1. Открытие (+ перевод в sRGB)
+	img, warnings, err := decodeImage(data)
 	if err != nil {
 		return nil, fmt.Errorf("decode error: %w", err)
 	}
 
-	// 2. Ресайз (бизнес-логика: ширина > 1200)
-	if img.Bounds().Dx() > resizeSettings.ResizeTo && resizeSettings.Resize {
-		img = imaging.Resize(img, resizeSettings.ResizeTo, 0, imaging.MitchellNetravali)
-	}
+	// 2. Ресайз и кодирование в WebP
+	return encodePage(img, filename, resizeSettings, warnings)
+}
+
+// encodePage ресайзит картинку, кодирует её в WebP и генерирует имя
+func encodePage(img image.Image, filename string, resizeSettings ResizeSettings, warnings []string) (*ProcessedImage, error) {
+	img = resizeImage(img, resizeSettings)
 
-	// 3. Кодирование в WebP
 	buf := new(bytes.Buffer)
-	err = webp.Encode(buf, img, &webp.Options{
+	err := webp.Encode(buf, img, &webp.Options{
 		Lossless: false,
 		Quality:  float32(resizeSettings.WebpQuality),
 	})
@@ -41,15 +149,102 @@ func processImage(data []byte, filename string, resizeSettings ResizeSettings) (
 		return nil, fmt.Errorf("encode error: %w", err)
 	}
 
-	// 4. Генерация имени
-	originalName := filename
-	ext := filepath.Ext(originalName)
-	nameWithoutExt := strings.TrimSuffix(originalName, ext)
-	fileName := fmt.Sprintf("%d_%s.webp", time.Now().UnixNano(), nameWithoutExt)
+	return &ProcessedImage{
+		Content:     buf,
+		FileName:    outputFileName(filename, ".webp"),
+		ContentType: "image/webp",
+		Size:        int64(buf.Len()),
+		Warnings:    warnings,
+	}, nil
+}
+
+// processPage обрабатывает исходную страницу с учётом разворотов (ResizeSettings.SpreadMode).
+// Разрезанный разворот даёт две картинки в порядке чтения.
+func processPage(data []byte, filename string, resizeSettings ResizeSettings) ([]*ProcessedImage, SpreadDecision, error) {
+	decision := SpreadDecision{Action: SpreadActionNone, Pages: 1}
 
+	// Анимации не режем и не поворачиваем
+	if detectAnimation(data) != "" && resizeSettings.AnimationMode != AnimationFlatten {
+		processed, err := processImage(data, filename, resizeSettings)
+		if err != nil {
+			return nil, decision, err
+		}
+		return []*ProcessedImage{processed}, decision, nil
+	}
+
+	img, warnings, err := decodeImage(data)
+	if err != nil {
+		return nil, decision, fmt.Errorf("decode error: %w", err)
+	}
+	decision.Width, decision.Height = img.Bounds().Dx(), img.Bounds().Dy()
+
+	parts, action, info := applySpreadMode(img, resizeSettings.SpreadMode)
+	decision.Action = action
+	decision.Spread = info.spread
+	decision.Gutter = info.gutter
+	decision.Pages = len(parts)
+
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	var results []*ProcessedImage
+	for i, part := range parts {
+		name := filename
+		if len(parts) > 1 {
+			name = fmt.Sprintf("%s_%d%s", base, i+1, ext)
+		}
+		processed, err := encodePage(part, name, resizeSettings, warnings)
+		if err != nil {
+			return nil, decision, err
+		}
+		// Предупреждения относятся к исходному файлу — отдаём их один раз
+		warnings = nil
+		results = append(results, processed)
+	}
+	return results, decision, nil
+}
+
+// processAnimation ресайзит все кадры и собирает анимированный WebP
+func processAnimation(data []byte, filename string, kind string, resizeSettings ResizeSettings) (*ProcessedImage, error) {
+	anim, err := decodeAnimation(data, kind)
+	if err != nil {
+		return nil, fmt.Errorf("decode error: %w", err)
+	}
+	for i, frame := range anim.frames {
+		anim.frames[i] = imaging.Clone(resizeImage(frame, resizeSettings))
+	}
+
+	encoded, err := encodeAnimatedWebP(anim, float32(resizeSettings.WebpQuality))
+	if err != nil {
+		return nil, fmt.Errorf("encode error: %w", err)
+	}
+
+	buf := bytes.NewBuffer(encoded)
 	return &ProcessedImage{
-		Content:  buf,
-		FileName: fileName,
-		Size:     int64(buf.Len()),
+		Content:     buf,
+		FileName:    outputFileName(filename, ".webp"),
+		ContentType: "image/webp",
+		Size:        int64(buf.Len()),
 	}, nil
 }
+
+// passthroughImage загружает анимацию без изменений
+func passthroughImage(data []byte, filename string, kind string) *ProcessedImage {
+	contentType, ext := "image/webp", ".webp"
+	if kind == animatedGIF {
+		contentType, ext = "image/gif", ".gif"
+	}
+	buf := bytes.NewBuffer(append([]byte(nil), data...))
+	return &ProcessedImage{
+		Content:     buf,
+		FileName:    outputFileName(filename, ext),
+		ContentType: contentType,
+		Size:        int64(buf.Len()),
+	}
+}
+
+// outputFileName генерирует уникальное имя в бакете
+func outputFileName(originalName string, newExt string) string {
+	ext := filepath.Ext(originalName)
+	nameWithoutExt := strings.TrimSuffix(originalName, ext)
+	return fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), nameWithoutExt, newExt)
+}
@@ -0,0 +1,360 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/sync/errgroup"
+)
+
+// Типы проблем, которые находит проверка главы
+const (
+	LintDuplicate     = "duplicate"
+	LintNearDuplicate = "near_duplicate"
+	LintBlank         = "blank"
+	LintCorrupt       = "corrupt"
+	LintWidthOutlier  = "width_outlier"
+	LintNumberingGap  = "numbering_gap"
+)
+
+// Уровни серьёзности проблем
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// LintOptions задаёт пороги проверки главы
+type LintOptions struct {
+	// NearDuplicateDistance — максимальное расстояние Хэмминга между dHash страниц
+	NearDuplicateDistance int `json:"near_duplicate_distance"`
+	// BlankStdDev — стандартное отклонение яркости, ниже которого страница считается пустой
+	BlankStdDev float64 `json:"blank_std_dev"`
+	// WidthTolerance — допустимое отклонение ширины от медианы главы (0.15 = 15%)
+	WidthTolerance float64 `json:"width_tolerance"`
+}
+
+// DefaultLintOptions возвращает пороги, подобранные под типичные главы манги
+func DefaultLintOptions() LintOptions {
+	return LintOptions{
+		NearDuplicateDistance: 4,
+		BlankStdDev:           3.0,
+		WidthTolerance:        0.15,
+	}
+}
+
+// LintIssue описывает одну найденную проблему
+type LintIssue struct {
+	Kind     string   `json:"kind"`
+	Severity string   `json:"severity"`
+	Files    []string `json:"files"`
+	Message  string   `json:"message"`
+}
+
+// LintPage содержит сведения об одной странице главы
+type LintPage struct {
+	Path   string `json:"path"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// LintReport — результат проверки главы для фронтенда
+type LintReport struct {
+	Pages  []LintPage  `json:"pages"`
+	Issues []LintIssue `json:"issues"`
+	Errors int         `json:"errors"`
+}
+
+// pageAnalysis — промежуточные данные по странице
+type pageAnalysis struct {
+	path    string
+	hash    string
+	dhash   uint64
+	width   int
+	height  int
+	stdDev  float64
+	decoded bool
+	err     error
+}
+
+var lastNumberRe = regexp.MustCompile(`(\d+)\D*$`)
+
+// LintChapter анализирует выбранные файлы перед загрузкой
+func LintChapter(ctx context.Context, filePaths []string, opts LintOptions) LintReport {
+	pages := make([]pageAnalysis, len(filePaths))
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.NumCPU())
+
+	for i, path := range filePaths {
+		i, path := i, path
+		g.Go(func() error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			pages[i] = analyzePage(path)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	report := LintReport{
+		Pages:  make([]LintPage, len(pages)),
+		Issues: []LintIssue{},
+	}
+	for i, p := range pages {
+		report.Pages[i] = LintPage{Path: p.path, Width: p.width, Height: p.height, Hash: p.hash}
+	}
+
+	report.Issues = append(report.Issues, lintCorrupt(pages)...)
+	report.Issues = append(report.Issues, lintBlank(pages, opts)...)
+	report.Issues = append(report.Issues, lintDuplicates(pages, opts)...)
+	report.Issues = append(report.Issues, lintWidths(pages, opts)...)
+	report.Issues = append(report.Issues, lintNumbering(filePaths)...)
+
+	for _, issue := range report.Issues {
+		if issue.Severity == SeverityError {
+			report.Errors++
+		}
+	}
+	return report
+}
+
+func analyzePage(path string) pageAnalysis {
+	res := pageAnalysis{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	res.hash = calculateHash(data)
+
+	img, _, err := decodeImage(data)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	res.decoded = true
+	res.width = img.Bounds().Dx()
+	res.height = img.Bounds().Dy()
+	res.dhash = differenceHash(img)
+	res.stdDev = luminanceStdDev(img)
+	return res
+}
+
+// differenceHash считает 64-битный dHash по уменьшенной серой копии
+func differenceHash(img image.Image) uint64 {
+	small := imaging.Grayscale(imaging.Resize(img, 9, 8, imaging.Box))
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			left := small.Pix[small.PixOffset(x, y)]
+			right := small.Pix[small.PixOffset(x+1, y)]
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// luminanceStdDev оценивает разброс яркости по уменьшенной копии
+func luminanceStdDev(img image.Image) float64 {
+	small := imaging.Grayscale(imaging.Resize(img, 64, 64, imaging.Box))
+	var sum, sumSq float64
+	n := 0
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			v := float64(small.Pix[small.PixOffset(x, y)])
+			sum += v
+			sumSq += v * v
+			n++
+		}
+	}
+	mean := sum / float64(n)
+	return math.Sqrt(math.Max(sumSq/float64(n)-mean*mean, 0))
+}
+
+func lintCorrupt(pages []pageAnalysis) []LintIssue {
+	var issues []LintIssue
+	for _, p := range pages {
+		if p.err == nil {
+			continue
+		}
+		issues = append(issues, LintIssue{
+			Kind:     LintCorrupt,
+			Severity: SeverityError,
+			Files:    []string{p.path},
+			Message:  fmt.Sprintf("Не удалось прочитать %s: %v", filepath.Base(p.path), p.err),
+		})
+	}
+	return issues
+}
+
+func lintBlank(pages []pageAnalysis, opts LintOptions) []LintIssue {
+	var issues []LintIssue
+	for _, p := range pages {
+		if !p.decoded || p.stdDev >= opts.BlankStdDev {
+			continue
+		}
+		issues = append(issues, LintIssue{
+			Kind:     LintBlank,
+			Severity: SeverityWarning,
+			Files:    []string{p.path},
+			Message:  fmt.Sprintf("Страница %s пустая или почти однотонная", filepath.Base(p.path)),
+		})
+	}
+	return issues
+}
+
+func lintDuplicates(pages []pageAnalysis, opts LintOptions) []LintIssue {
+	var issues []LintIssue
+
+	// Точные дубликаты группируем по SHA-256
+	groups := make(map[string][]string)
+	var order []string
+	for _, p := range pages {
+		if p.hash == "" {
+			continue
+		}
+		if _, ok := groups[p.hash]; !ok {
+			order = append(order, p.hash)
+		}
+		groups[p.hash] = append(groups[p.hash], p.path)
+	}
+	for _, h := range order {
+		files := groups[h]
+		if len(files) < 2 {
+			continue
+		}
+		issues = append(issues, LintIssue{
+			Kind:     LintDuplicate,
+			Severity: SeverityError,
+			Files:    files,
+			Message:  fmt.Sprintf("Одинаковые файлы: %s", joinBaseNames(files)),
+		})
+	}
+
+	// Похожие страницы сравниваем попарно; пустые пропускаем, они все похожи друг на друга
+	for i := 0; i < len(pages); i++ {
+		a := pages[i]
+		if !a.decoded || a.stdDev < opts.BlankStdDev {
+			continue
+		}
+		for j := i + 1; j < len(pages); j++ {
+			b := pages[j]
+			if !b.decoded || b.stdDev < opts.BlankStdDev || a.hash == b.hash {
+				continue
+			}
+			if bits.OnesCount64(a.dhash^b.dhash) > opts.NearDuplicateDistance {
+				continue
+			}
+			issues = append(issues, LintIssue{
+				Kind:     LintNearDuplicate,
+				Severity: SeverityWarning,
+				Files:    []string{a.path, b.path},
+				Message:  fmt.Sprintf("Страницы %s и %s почти совпадают", filepath.Base(a.path), filepath.Base(b.path)),
+			})
+		}
+	}
+	return issues
+}
+
+func lintWidths(pages []pageAnalysis, opts LintOptions) []LintIssue {
+	var widths []int
+	for _, p := range pages {
+		if p.decoded {
+			widths = append(widths, p.width)
+		}
+	}
+	if len(widths) < 3 {
+		return nil
+	}
+	sort.Ints(widths)
+	median := widths[len(widths)/2]
+
+	var issues []LintIssue
+	for _, p := range pages {
+		if !p.decoded {
+			continue
+		}
+		deviation := math.Abs(float64(p.width-median)) / float64(median)
+		if deviation <= opts.WidthTolerance {
+			continue
+		}
+		issues = append(issues, LintIssue{
+			Kind:     LintWidthOutlier,
+			Severity: SeverityWarning,
+			Files:    []string{p.path},
+			Message:  fmt.Sprintf("Ширина %s — %dpx, у остальных страниц около %dpx", filepath.Base(p.path), p.width, median),
+		})
+	}
+	return issues
+}
+
+func lintNumbering(filePaths []string) []LintIssue {
+	type numbered struct {
+		path string
+		num  int
+	}
+	var items []numbered
+	for _, path := range filePaths {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		m := lastNumberRe.FindStringSubmatch(name)
+		if m == nil {
+			// Нумерация не у всех файлов — пропуски не проверяем
+			return nil
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil
+		}
+		items = append(items, numbered{path: path, num: n})
+	}
+	if len(items) < 2 {
+		return nil
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].num < items[j].num })
+
+	var issues []LintIssue
+	for i := 1; i < len(items); i++ {
+		prev, cur := items[i-1], items[i]
+		if cur.num-prev.num <= 1 {
+			continue
+		}
+		missing := strconv.Itoa(prev.num + 1)
+		if cur.num-prev.num > 2 {
+			missing += "–" + strconv.Itoa(cur.num-1)
+		}
+		issues = append(issues, LintIssue{
+			Kind:     LintNumberingGap,
+			Severity: SeverityWarning,
+			Files:    []string{prev.path, cur.path},
+			Message:  fmt.Sprintf("Пропущены номера %s между %s и %s", missing, filepath.Base(prev.path), filepath.Base(cur.path)),
+		})
+	}
+	return issues
+}
+
+func joinBaseNames(paths []string) string {
+	names := make([]string, len(paths))
+	for i, p := range paths {
+		names[i] = filepath.Base(p)
+	}
+	return strings.Join(names, ", ")
+}
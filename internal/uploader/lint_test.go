@@ -0,0 +1,108 @@
+package uploader
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Helper to create a non-uniform test image; seed changes the pattern
+func createPatternImage(t *testing.T, dir string, name string, width, height int, seed int) string {
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((x*7 + y*13 + (x/(seed+3))*(y/(seed+5))*31 + seed*47) % 256)
+			img.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func countIssues(report LintReport, kind string) int {
+	n := 0
+	for _, issue := range report.Issues {
+		if issue.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLintChapter(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	p1 := createPatternImage(t, tmpDir, "001.png", 200, 300, 1)
+	p2 := createPatternImage(t, tmpDir, "002.png", 200, 300, 9)
+	// Exact copy of 002
+	data, _ := os.ReadFile(p2)
+	p3 := filepath.Join(tmpDir, "003.png")
+	os.WriteFile(p3, data, 0644)
+	// Blank page
+	p4 := createTestImage(t, tmpDir, "004.png", 200, 300)
+	// Wrong resolution, gap after 004
+	p7 := createPatternImage(t, tmpDir, "007.png", 400, 600, 20)
+	// Corrupt
+	p8 := filepath.Join(tmpDir, "008.png")
+	os.WriteFile(p8, []byte("\x89PNG\r\n\x1a\n...broken..."), 0644)
+
+	report := LintChapter(context.Background(), []string{p1, p2, p3, p4, p7, p8}, DefaultLintOptions())
+
+	if len(report.Pages) != 6 {
+		t.Fatalf("expected 6 pages, got %d", len(report.Pages))
+	}
+	if countIssues(report, LintDuplicate) != 1 {
+		t.Errorf("expected 1 duplicate issue, got %+v", report.Issues)
+	}
+	if countIssues(report, LintBlank) != 1 {
+		t.Errorf("expected 1 blank issue, got %+v", report.Issues)
+	}
+	if countIssues(report, LintCorrupt) != 1 {
+		t.Errorf("expected 1 corrupt issue, got %+v", report.Issues)
+	}
+	if countIssues(report, LintWidthOutlier) != 1 {
+		t.Errorf("expected 1 width outlier, got %+v", report.Issues)
+	}
+	if countIssues(report, LintNumberingGap) != 1 {
+		t.Errorf("expected 1 numbering gap, got %+v", report.Issues)
+	}
+	if report.Errors != 2 {
+		t.Errorf("expected 2 errors (duplicate + corrupt), got %d", report.Errors)
+	}
+}
+
+func TestLintChapter_NearDuplicate(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	p1 := createPatternImage(t, tmpDir, "a.png", 200, 300, 4)
+	// Same picture with a trailing byte → different SHA-256, same dHash
+	p2 := createPatternImage(t, tmpDir, "b.png", 200, 300, 4)
+	img, _ := os.ReadFile(p2)
+	os.WriteFile(p2, append(img, 0), 0644)
+
+	report := LintChapter(context.Background(), []string{p1, p2}, DefaultLintOptions())
+	if countIssues(report, LintNearDuplicate) != 1 {
+		t.Errorf("expected near duplicate, got %+v", report.Issues)
+	}
+	if countIssues(report, LintDuplicate) != 0 {
+		t.Errorf("expected no exact duplicate, got %+v", report.Issues)
+	}
+	// Names without numbers → no numbering check
+	if countIssues(report, LintNumberingGap) != 0 {
+		t.Errorf("expected no numbering gaps, got %+v", report.Issues)
+	}
+}
@@ -23,9 +23,14 @@ import (
 
 // Структуры ответа для фронтенда
 type UploadResult struct {
-	Success bool     `json:"success"`
-	Links   []string `json:"links"`
-	Error   string   `json:"error"`
+	Success  bool     `json:"success"`
+	Links    []string `json:"links"`
+	Error    string   `json:"error"`
+	Warnings []string `json:"warnings"`
+	// FileLinks — ссылки по исходным файлам (разрезанный разворот даёт две)
+	FileLinks [][]string `json:"file_links"`
+	// Spreads — решение по разворотам для каждого исходного файла
+	Spreads []SpreadDecision `json:"spreads"`
 }
 
 // R2Uploader хранит состояние: готовый клиент и конфиг
@@ -47,6 +52,17 @@ type ResizeSettings struct {
 	ResizeTo    int  `json:"resize_to"`
 	WebpQuality int  `json:"webp_quality"`
 	MockR2      bool `json:"mock_r2"`
+	// ResizeMode — как уменьшать картинку (по умолчанию ResizeWidth); см. resize.go
+	ResizeMode    string  `json:"resize_mode"`
+	ResizeHeight  int     `json:"resize_height"`
+	MaxMegapixels float64 `json:"max_megapixels"`
+	ResizePercent int     `json:"resize_percent"`
+	StripHeight   int     `json:"strip_height"`
+	ResizeFilter  string  `json:"resize_filter"`
+	// AnimationMode — что делать с анимированными GIF/WebP (по умолчанию AnimationReencode)
+	AnimationMode string `json:"animation_mode"`
+	// SpreadMode — что делать с разворотами (по умолчанию SpreadKeep)
+	SpreadMode string `json:"spread_mode"`
 }
 
 // New создает новый экземпляр загрузчика. Вызывается 1 раз при старте.
@@ -150,8 +166,10 @@ func (u *R2Uploader) UploadChapter(ctx context.Context, filePaths []string, resi
 	g, ctx := errgroup.WithContext(ctx)
 	g.SetLimit(runtime.NumCPU())
 
-	uploadedLinks := make([]string, len(filePaths))
+	uploadedLinks := make([][]string, len(filePaths))
+	spreads := make([]SpreadDecision, len(filePaths))
 	var uploadErrors []string
+	var warnings []string
 	var mu sync.Mutex
 
 	var processedCount int32
@@ -167,10 +185,12 @@ func (u *R2Uploader) UploadChapter(ctx context.Context, filePaths []string, resi
 			default:
 			}
 
+			spreads[i] = SpreadDecision{Path: path, Action: SpreadActionNone, Pages: 1}
+
 			if resizeSettings.MockR2 {
 				// MOCK MODE: Just wait a bit and return fake link
 				time.Sleep(time.Millisecond * 2000)
-				uploadedLinks[i] = fmt.Sprintf("https://cxc-images.khudoberdi.uz/1767902262155077900_D1000.webp?id=%d", i)
+				uploadedLinks[i] = []string{fmt.Sprintf("https://cxc-images.khudoberdi.uz/1767902262155077900_D1000.webp?id=%d", i)}
 
 				// Progress update
 				newCount := atomic.AddInt32(&processedCount, 1)
@@ -196,9 +216,11 @@ func (u *R2Uploader) UploadChapter(ctx context.Context, filePaths []string, resi
 
 			// 2. Проверяем в базе
 			if u.cacheRepo != nil { // Check if repo is available
-				if cachedURL, found := u.cacheRepo.GetURL(fileHash); found {
+				if cachedURLs := u.cachedPageURLs(fileHash, fileData, resizeSettings); len(cachedURLs) > 0 {
 					// УРА! Файл уже был загружен.
-					uploadedLinks[i] = cachedURL
+					uploadedLinks[i] = cachedURLs
+					spreads[i].Action = SpreadActionCached
+					spreads[i].Pages = len(cachedURLs)
 					// Progress update
 					newCount := atomic.AddInt32(&processedCount, 1)
 					if onProgress != nil {
@@ -209,38 +231,46 @@ func (u *R2Uploader) UploadChapter(ctx context.Context, filePaths []string, resi
 			}
 			// ----------------------------------
 
-			// ШАГ 1: Обработка изображения
-			processed, err := processImage(fileData, filepath.Base(path), resizeSettings)
+			// ШАГ 1: Обработка изображения (разворот может дать две картинки)
+			processedPages, decision, err := processPage(fileData, filepath.Base(path), resizeSettings)
 			if err != nil {
 				mu.Lock()
 				uploadErrors = append(uploadErrors, fmt.Sprintf("[%s] Processing failed: %v", filepath.Base(path), err))
 				mu.Unlock()
 				return nil
 			}
+			decision.Path = path
+			spreads[i] = decision
+
+			links := make([]string, 0, len(processedPages))
+			for _, processed := range processedPages {
+				if len(processed.Warnings) > 0 {
+					mu.Lock()
+					for _, w := range processed.Warnings {
+						warnings = append(warnings, fmt.Sprintf("[%s] %s", filepath.Base(path), w))
+					}
+					mu.Unlock()
+				}
 
-			// ШАГ 2: Загрузка
-			_, err = u.client.PutObject(ctx, u.cfg.BucketName, processed.FileName, processed.Content, processed.Size, minio.PutObjectOptions{
-				ContentType: "image/webp",
-			})
-			if err != nil {
-				mu.Lock()
-				uploadErrors = append(uploadErrors, fmt.Sprintf("[%s] Upload error: %v", filepath.Base(path), err))
-				mu.Unlock()
-				return nil
+				// ШАГ 2: Загрузка и формирование ссылки
+				link, err := u.putProcessed(ctx, processed)
+				if err != nil {
+					mu.Lock()
+					uploadErrors = append(uploadErrors, fmt.Sprintf("[%s] Upload error: %v", filepath.Base(path), err))
+					mu.Unlock()
+					return nil
+				}
+				links = append(links, link)
 			}
 
-			// ШАГ 3: Формирование ссылки
-			domain := u.normalizeDomain()
-			finalUrl := fmt.Sprintf("%s/%s", domain, processed.FileName)
-
 			// --- НОВАЯ ЛОГИКА: СОХРАНЕНИЕ В КЭШ ---
 			if u.cacheRepo != nil {
-				_ = u.cacheRepo.Save(fileHash, finalUrl)
+				u.savePageURLs(fileHash, resizeSettings.SpreadMode, decision, links, path)
 			}
 			// --------------------------------------
 
 			// Индексы уникальны, мьютекс не нужен для uploadedLinks
-			uploadedLinks[i] = finalUrl
+			uploadedLinks[i] = links
 
 			// Progress update
 			newCount := atomic.AddInt32(&processedCount, 1)
@@ -258,10 +288,88 @@ func (u *R2Uploader) UploadChapter(ctx context.Context, filePaths []string, resi
 	}
 
 	if len(uploadErrors) > 0 {
-		return UploadResult{Success: false, Error: fmt.Sprintf("Ошибок: %d. Первая: %s", len(uploadErrors), uploadErrors[0])}
+		return UploadResult{Success: false, Error: fmt.Sprintf("Ошибок: %d. Первая: %s", len(uploadErrors), uploadErrors[0]), Warnings: warnings, Spreads: spreads}
+	}
+
+	var flat []string
+	for _, links := range uploadedLinks {
+		flat = append(flat, links...)
+	}
+	return UploadResult{Success: true, Links: flat, Warnings: warnings, FileLinks: uploadedLinks, Spreads: spreads}
+}
+
+// spreadCacheKey — ключ кэша для части разворота, обработанного в режиме mode.
+// Обычные страницы кэшируются по чистому хэшу файла.
+func spreadCacheKey(fileHash, mode string, part int) string {
+	return fmt.Sprintf("%s:%s:%d", fileHash, mode, part)
+}
+
+// cachedPageURLs ищет в кэше результат для файла: сначала части разворота в текущем режиме, затем обычную запись.
+// Обычная запись годится, только если страница и в текущем режиме осталась бы целой: иначе разворот,
+// залитый раньше в режиме keep, не разрезался бы после смены режима тайтла.
+func (u *R2Uploader) cachedPageURLs(fileHash string, data []byte, settings ResizeSettings) []string {
+	spreadMode := settings.SpreadMode
+	if spreadMode == SpreadSplitRTL || spreadMode == SpreadSplitLTR || spreadMode == SpreadRotate {
+		var urls []string
+		for part := 0; ; part++ {
+			url, found := u.cacheRepo.GetURL(spreadCacheKey(fileHash, spreadMode, part))
+			if !found {
+				break
+			}
+			urls = append(urls, url)
+		}
+		if len(urls) > 0 {
+			return urls
+		}
+	}
+	url, found := u.cacheRepo.GetURL(fileHash)
+	if !found {
+		return nil
+	}
+	if spreadMode == SpreadSplitRTL || spreadMode == SpreadSplitLTR || spreadMode == SpreadRotate {
+		if !keepsWholePage(data, settings) {
+			return nil
+		}
 	}
+	return []string{url}
+}
 
-	return UploadResult{Success: true, Links: uploadedLinks}
+// keepsWholePage повторяет решение processPage: останется ли страница одной картинкой без изменений
+func keepsWholePage(data []byte, settings ResizeSettings) bool {
+	if detectAnimation(data) != "" && settings.AnimationMode != AnimationFlatten {
+		return true
+	}
+	img, _, err := decodeImage(data)
+	if err != nil {
+		// Файл уже был обработан под этим хэшем — доверяем кэшу
+		return true
+	}
+	return !detectSpread(img).spread
+}
+
+// savePageURLs сохраняет ссылки в кэш; изменённые развороты — под ключами режима.
+// sourcePath запоминается, чтобы потерянную картинку можно было перезалить (см. Reupload).
+func (u *R2Uploader) savePageURLs(fileHash, spreadMode string, decision SpreadDecision, urls []string, sourcePath string) {
+	if decision.Action == SpreadActionSplit || decision.Action == SpreadActionRotate {
+		for part, url := range urls {
+			_ = u.cacheRepo.Save(spreadCacheKey(fileHash, spreadMode, part), url, sourcePath)
+		}
+		return
+	}
+	if len(urls) == 1 {
+		_ = u.cacheRepo.Save(fileHash, urls[0], sourcePath)
+	}
+}
+
+// putProcessed загружает обработанную картинку в бакет и возвращает её публичный адрес
+func (u *R2Uploader) putProcessed(ctx context.Context, processed *ProcessedImage) (string, error) {
+	_, err := u.client.PutObject(ctx, u.cfg.BucketName, processed.FileName, processed.Content, processed.Size, minio.PutObjectOptions{
+		ContentType: processed.ContentType,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s", u.normalizeDomain(), processed.FileName), nil
 }
 
 func calculateHash(data []byte) string {
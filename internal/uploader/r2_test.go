@@ -145,6 +145,8 @@ func TestUploadChapter_PartialFailure(t *testing.T) {
 
 	// Mock server always succeeds for the good file
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("ETag", "\"1234567890abcdef\"")
 		w.WriteHeader(http.StatusOK)
 	}))
 	defer ts.Close()
@@ -153,9 +155,10 @@ func TestUploadChapter_PartialFailure(t *testing.T) {
 	minioClient, _ := minio.New(endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4("key", "secret", ""),
 		Secure: false,
+		Region: "us-east-1",
 	})
 	
-	uploader := NewWithClient(minioClient, &config.Config{BucketName: "b", PublicDomain: "http://d"}, nil)
+	uploader := NewWithClient(minioClient, &config.Config{BucketName: "bucket", PublicDomain: "http://d"}, nil)
 
 	result := uploader.UploadChapter(context.Background(), []string{goodPath, badPath}, ResizeSettings{}, nil)
 	
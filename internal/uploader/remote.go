@@ -0,0 +1,89 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// MaxRemoteImageSize — предел размера скачиваемой сторонней картинки
+const MaxRemoteImageSize = 50 << 20
+
+// remoteHTTPClient скачивает сторонние картинки при перезаливке
+var remoteHTTPClient = &http.Client{Timeout: 60 * time.Second}
+
+// IsHosted — лежит ли картинка уже в нашем бакете (адрес начинается с публичного домена)
+func (u *R2Uploader) IsHosted(imageURL string) bool {
+	return strings.HasPrefix(imageURL, u.normalizeDomain()+"/")
+}
+
+// UploadRemote скачивает стороннюю картинку, обрабатывает её как обычную страницу
+// (processImage, без резки разворотов — один адрес заменяется одним) и загружает в бакет.
+// Одинаковые картинки не загружаются повторно благодаря кэшу по хэшу содержимого.
+func (u *R2Uploader) UploadRemote(ctx context.Context, imageURL string, settings ResizeSettings) (string, error) {
+	data, err := Download(ctx, imageURL)
+	if err != nil {
+		return "", err
+	}
+
+	fileHash := calculateHash(data)
+	if u.cacheRepo != nil {
+		if cached, found := u.cacheRepo.GetURL(fileHash); found {
+			return cached, nil
+		}
+	}
+
+	processed, err := processImage(data, remoteFileName(imageURL), settings)
+	if err != nil {
+		return "", fmt.Errorf("processing failed: %w", err)
+	}
+	link, err := u.putProcessed(ctx, processed)
+	if err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+	if u.cacheRepo != nil {
+		_ = u.cacheRepo.Save(fileHash, link, "")
+	}
+	return link, nil
+}
+
+// Download читает картинку по HTTP с ограничением размера MaxRemoteImageSize
+func Download(ctx context.Context, imageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := remoteHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxRemoteImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	if len(data) > MaxRemoteImageSize {
+		return nil, fmt.Errorf("download: image is larger than %d bytes", MaxRemoteImageSize)
+	}
+	return data, nil
+}
+
+// remoteFileName — имя файла для outputFileName из последнего сегмента адреса
+func remoteFileName(imageURL string) string {
+	name := "image"
+	if parsed, err := url.Parse(imageURL); err == nil {
+		if base := path.Base(parsed.Path); base != "." && base != "/" {
+			name = base
+		}
+	}
+	return name
+}
@@ -0,0 +1,72 @@
+package uploader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"telegraph_uploader_v2/internal/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+func TestUploadRemote(t *testing.T) {
+	var puts int32
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.Method == "PUT" {
+			atomic.AddInt32(&puts, 1)
+			w.Header().Set("ETag", "\"1234567890abcdef\"")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer bucket.Close()
+
+	png, err := os.ReadFile(createPatternImage(t, t.TempDir(), "page.png", 300, 450, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/file/page.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(png)
+	}))
+	defer host.Close()
+
+	minioClient, _ := minio.New(bucket.Listener.Addr().String(), &minio.Options{
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+		Secure: false,
+		Region: "us-east-1",
+	})
+	u := NewWithClient(minioClient, &config.Config{BucketName: "bucket", PublicDomain: "cdn.example.com"}, &mockCache{urls: map[string]string{}})
+	settings := ResizeSettings{WebpQuality: 80}
+
+	link, err := u.UploadRemote(context.Background(), host.URL+"/file/page.png", settings)
+	if err != nil {
+		t.Fatalf("UploadRemote failed: %v", err)
+	}
+	if !u.IsHosted(link) || !strings.HasSuffix(link, "_page.webp") {
+		t.Errorf("unexpected link %s", link)
+	}
+	if u.IsHosted(host.URL + "/file/page.png") {
+		t.Error("third-party url must not be hosted")
+	}
+
+	// Та же картинка второй раз берётся из кэша
+	again, err := u.UploadRemote(context.Background(), host.URL+"/file/page.png", settings)
+	if err != nil || again != link || atomic.LoadInt32(&puts) != 1 {
+		t.Errorf("expected cached link without upload, got %s (%d puts, %v)", again, puts, err)
+	}
+
+	if _, err := u.UploadRemote(context.Background(), host.URL+"/file/missing.png", settings); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected download error, got %v", err)
+	}
+}
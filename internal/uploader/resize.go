@@ -0,0 +1,101 @@
+package uploader
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Режимы ресайза (ResizeSettings.ResizeMode). Все режимы, кроме процентного, только уменьшают картинку.
+const (
+	ResizeWidth      = "width"      // уменьшить до ResizeTo по ширине (по умолчанию)
+	ResizeHeight     = "height"     // уменьшить до ResizeHeight по высоте
+	ResizeBox        = "box"        // вписать в ResizeTo × ResizeHeight
+	ResizeMegapixels = "megapixels" // не больше MaxMegapixels мегапикселей
+	ResizePercent    = "percent"    // масштабировать на ResizePercent процентов
+	ResizeStrip      = "strip"      // уменьшать до ResizeTo по ширине только стрипы выше StripHeight
+)
+
+// Фильтры ресампла (ResizeSettings.ResizeFilter)
+const (
+	FilterMitchell   = "mitchell" // по умолчанию
+	FilterLanczos    = "lanczos"
+	FilterCatmullRom = "catmullrom"
+	FilterLinear     = "linear"
+	FilterBox        = "box"
+	FilterNearest    = "nearest"
+)
+
+var resampleFilters = map[string]imaging.ResampleFilter{
+	FilterMitchell:   imaging.MitchellNetravali,
+	FilterLanczos:    imaging.Lanczos,
+	FilterCatmullRom: imaging.CatmullRom,
+	FilterLinear:     imaging.Linear,
+	FilterBox:        imaging.Box,
+	FilterNearest:    imaging.NearestNeighbor,
+}
+
+// resampleFilter возвращает выбранный фильтр; неизвестное значение — Mitchell
+func resampleFilter(name string) imaging.ResampleFilter {
+	if f, ok := resampleFilters[name]; ok {
+		return f
+	}
+	return imaging.MitchellNetravali
+}
+
+// targetSize считает итоговый размер по настройкам, не трогая пиксели.
+// Используется и при обработке, и в пробном прогоне.
+func targetSize(width, height int, s ResizeSettings) (int, int) {
+	if !s.Resize || width <= 0 || height <= 0 {
+		return width, height
+	}
+
+	scale := 1.0
+	switch s.ResizeMode {
+	case ResizeHeight:
+		if s.ResizeHeight > 0 && height > s.ResizeHeight {
+			scale = float64(s.ResizeHeight) / float64(height)
+		}
+	case ResizeBox:
+		if s.ResizeTo > 0 && width > s.ResizeTo {
+			scale = float64(s.ResizeTo) / float64(width)
+		}
+		if s.ResizeHeight > 0 && height > s.ResizeHeight {
+			scale = math.Min(scale, float64(s.ResizeHeight)/float64(height))
+		}
+	case ResizeMegapixels:
+		pixels := float64(width) * float64(height)
+		if limit := s.MaxMegapixels * 1e6; limit > 0 && pixels > limit {
+			scale = math.Sqrt(limit / pixels)
+		}
+	case ResizePercent:
+		if s.ResizePercent > 0 {
+			scale = float64(s.ResizePercent) / 100
+		}
+	case ResizeStrip:
+		if s.StripHeight > 0 && height > s.StripHeight && s.ResizeTo > 0 && width > s.ResizeTo {
+			scale = float64(s.ResizeTo) / float64(width)
+		}
+	default:
+		if s.ResizeTo > 0 && width > s.ResizeTo {
+			// Ширина ровно ResizeTo, как и раньше
+			return s.ResizeTo, max(1, height*s.ResizeTo/width)
+		}
+	}
+
+	if scale == 1.0 {
+		return width, height
+	}
+	return max(1, int(math.Round(float64(width)*scale))), max(1, int(math.Round(float64(height)*scale)))
+}
+
+// resizeImage применяет правило ресайза из настроек
+func resizeImage(img image.Image, resizeSettings ResizeSettings) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	tw, th := targetSize(w, h, resizeSettings)
+	if tw == w && th == h {
+		return img
+	}
+	return imaging.Resize(img, tw, th, resampleFilter(resizeSettings.ResizeFilter))
+}
@@ -0,0 +1,62 @@
+package uploader
+
+import (
+	"image"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestTargetSize(t *testing.T) {
+	tests := []struct {
+		name         string
+		w, h         int
+		settings     ResizeSettings
+		wantW, wantH int
+	}{
+		{"Disabled", 2000, 3000, ResizeSettings{ResizeTo: 1000}, 2000, 3000},
+		{"Width default mode", 2000, 3000, ResizeSettings{Resize: true, ResizeTo: 1000}, 1000, 1500},
+		{"Width no upscale", 800, 1200, ResizeSettings{Resize: true, ResizeTo: 1000}, 800, 1200},
+		{"Width zero target", 800, 1200, ResizeSettings{Resize: true}, 800, 1200},
+		{"Fit height", 2000, 3000, ResizeSettings{Resize: true, ResizeMode: ResizeHeight, ResizeHeight: 1500}, 1000, 1500},
+		{"Fit box by height", 2000, 4000, ResizeSettings{Resize: true, ResizeMode: ResizeBox, ResizeTo: 1000, ResizeHeight: 1000}, 500, 1000},
+		{"Fit box by width", 4000, 2000, ResizeSettings{Resize: true, ResizeMode: ResizeBox, ResizeTo: 1000, ResizeHeight: 1000}, 1000, 500},
+		{"Fit box inside", 900, 900, ResizeSettings{Resize: true, ResizeMode: ResizeBox, ResizeTo: 1000, ResizeHeight: 1000}, 900, 900},
+		{"Megapixels", 4000, 4000, ResizeSettings{Resize: true, ResizeMode: ResizeMegapixels, MaxMegapixels: 4}, 2000, 2000},
+		{"Megapixels under limit", 1000, 1000, ResizeSettings{Resize: true, ResizeMode: ResizeMegapixels, MaxMegapixels: 4}, 1000, 1000},
+		{"Percent down", 1000, 2000, ResizeSettings{Resize: true, ResizeMode: ResizePercent, ResizePercent: 50}, 500, 1000},
+		{"Percent up", 100, 200, ResizeSettings{Resize: true, ResizeMode: ResizePercent, ResizePercent: 150}, 150, 300},
+		{"Strip taller than limit", 1600, 20000, ResizeSettings{Resize: true, ResizeMode: ResizeStrip, ResizeTo: 800, StripHeight: 5000}, 800, 10000},
+		{"Strip short page untouched", 1600, 2400, ResizeSettings{Resize: true, ResizeMode: ResizeStrip, ResizeTo: 800, StripHeight: 5000}, 1600, 2400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := targetSize(tt.w, tt.h, tt.settings)
+			if w != tt.wantW || h != tt.wantH {
+				t.Errorf("got %dx%d, want %dx%d", w, h, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestResizeImage_Filter(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	settings := ResizeSettings{Resize: true, ResizeMode: ResizeBox, ResizeTo: 200, ResizeHeight: 200, ResizeFilter: FilterLanczos}
+
+	out := resizeImage(img, settings)
+	if out.Bounds().Dx() != 200 || out.Bounds().Dy() != 150 {
+		t.Errorf("unexpected size %v", out.Bounds())
+	}
+
+	if resampleFilter("unknown").Support != imaging.MitchellNetravali.Support {
+		t.Error("unknown filter must fall back to Mitchell")
+	}
+	if resampleFilter(FilterNearest).Support != imaging.NearestNeighbor.Support {
+		t.Error("nearest filter not selected")
+	}
+
+	// Без изменения размера картинка возвращается как есть
+	if same := resizeImage(img, ResizeSettings{}); same != image.Image(img) {
+		t.Error("expected original image when resize is disabled")
+	}
+}
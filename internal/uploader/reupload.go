@@ -0,0 +1,77 @@
+package uploader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrSourceUnknown — картинка загружена не этим приложением или до того, как начали запоминать исходники
+	ErrSourceUnknown = errors.New("source file is unknown")
+	// ErrSourceChanged — исходный файл изменился после загрузки, перезаливать его вместо старой картинки нельзя
+	ErrSourceChanged = errors.New("source file has changed since upload")
+)
+
+// Reupload заново обрабатывает и загружает исходный файл картинки brokenURL
+// (например, если объект удалили из бакета или сменился домен) и возвращает новый адрес.
+// Исходник ищется по кэшу загрузок; для частей разворота используется режим, в котором они были получены.
+func (u *R2Uploader) Reupload(ctx context.Context, brokenURL string, settings ResizeSettings) (string, error) {
+	if u.cacheRepo == nil {
+		return "", ErrSourceUnknown
+	}
+	entry, found := u.cacheRepo.FindByURL(brokenURL)
+	if !found || entry.SourcePath == "" {
+		return "", ErrSourceUnknown
+	}
+
+	fileHash, mode, part, err := parseCacheKey(entry.Hash)
+	if err != nil {
+		return "", err
+	}
+	settings.SpreadMode = mode
+
+	data, err := os.ReadFile(entry.SourcePath)
+	if err != nil {
+		return "", fmt.Errorf("read source: %w", err)
+	}
+	if calculateHash(data) != fileHash {
+		return "", ErrSourceChanged
+	}
+
+	pages, _, err := processPage(data, filepath.Base(entry.SourcePath), settings)
+	if err != nil {
+		return "", fmt.Errorf("processing failed: %w", err)
+	}
+	if part >= len(pages) {
+		return "", fmt.Errorf("source produced %d pages, part %d is missing", len(pages), part)
+	}
+
+	link, err := u.putProcessed(ctx, pages[part])
+	if err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+	_ = u.cacheRepo.Save(entry.Hash, link, entry.SourcePath)
+	return link, nil
+}
+
+// parseCacheKey разбирает ключ кэша: чистый хэш — страница целиком (SpreadKeep),
+// «хэш:режим:часть» — часть разворота (см. spreadCacheKey)
+func parseCacheKey(key string) (fileHash, mode string, part int, err error) {
+	fields := strings.Split(key, ":")
+	switch len(fields) {
+	case 1:
+		return key, SpreadKeep, 0, nil
+	case 3:
+		part, err = strconv.Atoi(fields[2])
+		if err != nil {
+			return "", "", 0, fmt.Errorf("bad cache key %q", key)
+		}
+		return fields[0], fields[1], part, nil
+	}
+	return "", "", 0, fmt.Errorf("bad cache key %q", key)
+}
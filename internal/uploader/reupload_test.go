@@ -0,0 +1,87 @@
+package uploader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"telegraph_uploader_v2/internal/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+func TestReupload(t *testing.T) {
+	var puts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.Method == "PUT" {
+			atomic.AddInt32(&puts, 1)
+			w.Header().Set("ETag", "\"1234567890abcdef\"")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	minioClient, _ := minio.New(ts.Listener.Addr().String(), &minio.Options{
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+		Secure: false,
+		Region: "us-east-1",
+	})
+	cache := &mockCache{urls: map[string]string{}}
+	u := NewWithClient(minioClient, &config.Config{BucketName: "bucket", PublicDomain: "http://test.com"}, cache)
+
+	tmpDir := t.TempDir()
+	page := createPatternImage(t, tmpDir, "001.png", 300, 450, 1)
+	spread := writePNG(t, tmpDir, "002.png", spreadImage(600, 450, true))
+	settings := ResizeSettings{SpreadMode: SpreadSplitRTL, WebpQuality: 80}
+
+	result := u.UploadChapter(context.Background(), []string{page, spread}, settings, nil)
+	if !result.Success || len(result.Links) != 3 {
+		t.Fatalf("upload failed: %s %v", result.Error, result.Links)
+	}
+
+	// Вторая половина разворота перезаливается в режиме, в котором была разрезана
+	atomic.StoreInt32(&puts, 0)
+	broken := result.FileLinks[1][1]
+	link, err := u.Reupload(context.Background(), broken, ResizeSettings{WebpQuality: 80})
+	if err != nil {
+		t.Fatalf("Reupload failed: %v", err)
+	}
+	if atomic.LoadInt32(&puts) != 1 {
+		t.Errorf("expected exactly one upload, got %d", puts)
+	}
+	if entry, found := cache.FindByURL(link); !found || entry.SourcePath != spread {
+		t.Errorf("cache must point to new link, got %v %+v", found, entry)
+	}
+
+	if _, err := u.Reupload(context.Background(), "http://test.com/unknown.webp", settings); !errors.Is(err, ErrSourceUnknown) {
+		t.Errorf("expected ErrSourceUnknown, got %v", err)
+	}
+
+	// Исходник подменили — перезаливать нельзя
+	data, _ := os.ReadFile(spread)
+	if err := os.WriteFile(page, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Reupload(context.Background(), result.FileLinks[0][0], settings); !errors.Is(err, ErrSourceChanged) {
+		t.Errorf("expected ErrSourceChanged, got %v", err)
+	}
+}
+
+func TestParseCacheKey(t *testing.T) {
+	if h, mode, part, err := parseCacheKey("abc"); err != nil || h != "abc" || mode != SpreadKeep || part != 0 {
+		t.Errorf("plain key: %s %s %d %v", h, mode, part, err)
+	}
+	if h, mode, part, err := parseCacheKey(spreadCacheKey("abc", SpreadRotate, 1)); err != nil || h != "abc" || mode != SpreadRotate || part != 1 {
+		t.Errorf("spread key: %s %s %d %v", h, mode, part, err)
+	}
+	if _, _, _, err := parseCacheKey("a:b"); err == nil {
+		t.Error("expected error for malformed key")
+	}
+}
@@ -0,0 +1,145 @@
+package uploader
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Что делать с разворотами (ResizeSettings.SpreadMode)
+const (
+	SpreadKeep     = "keep"      // оставить как есть (по умолчанию)
+	SpreadSplitRTL = "split_rtl" // разрезать, правая половина первой (манга)
+	SpreadSplitLTR = "split_ltr" // разрезать, левая половина первой
+	SpreadRotate   = "rotate"    // оставить целым и повернуть на 90°
+)
+
+// Действия, которые попадают в отчёт по страницам
+const (
+	SpreadActionNone   = "none"   // не разворот
+	SpreadActionKeep   = "keep"   // разворот оставлен как есть
+	SpreadActionSplit  = "split"  // разрезан на две страницы
+	SpreadActionRotate = "rotate" // повернут
+	SpreadActionCached = "cached" // взят из кэша, не анализировался
+)
+
+const (
+	// spreadMinAspect — разворотом может быть только страница шире этого соотношения
+	spreadMinAspect = 1.2
+	// spreadSureAspect — при таком соотношении разворот признаётся и без видимого сгиба
+	spreadSureAspect = 1.35
+	// gutterMaxStdDev — разброс яркости столбца, ниже которого он считается полосой сгиба
+	gutterMaxStdDev = 10.0
+	// gutterMinContrast — средний разброс по странице, при котором поиск сгиба имеет смысл
+	gutterMinContrast = 20.0
+)
+
+// SpreadDecision — решение по одной исходной странице
+type SpreadDecision struct {
+	Path   string `json:"path"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Spread bool   `json:"spread"`
+	Gutter bool   `json:"gutter"`
+	Action string `json:"action"`
+	Pages  int    `json:"pages"`
+}
+
+// spreadInfo — результат анализа картинки
+type spreadInfo struct {
+	spread bool
+	gutter bool
+	splitX int // колонка, по которой резать
+}
+
+// isSplitMode — режутся ли развороты в этом режиме
+func isSplitMode(spreadMode string) bool {
+	return spreadMode == SpreadSplitRTL || spreadMode == SpreadSplitLTR
+}
+
+// detectSpread проверяет соотношение сторон и ищет вертикальную полосу сгиба в центре
+func detectSpread(img image.Image) spreadInfo {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	info := spreadInfo{splitX: w / 2}
+	if h == 0 || float64(w)/float64(h) < spreadMinAspect {
+		return info
+	}
+
+	if x, ok := findGutter(img); ok {
+		info.gutter = true
+		info.splitX = x
+	}
+	info.spread = info.gutter || float64(w)/float64(h) >= spreadSureAspect
+	return info
+}
+
+// findGutter ищет в центральной полосе (40–60% ширины) самый однородный столбец.
+// Возвращает координату в исходной картинке.
+func findGutter(img image.Image) (int, bool) {
+	const sampleW, sampleH = 200, 100
+	small := imaging.Grayscale(imaging.Resize(img, sampleW, sampleH, imaging.Box))
+
+	stdDevs := make([]float64, sampleW)
+	var total float64
+	for x := 0; x < sampleW; x++ {
+		var sum, sumSq float64
+		for y := 0; y < sampleH; y++ {
+			v := float64(small.Pix[small.PixOffset(x, y)])
+			sum += v
+			sumSq += v * v
+		}
+		mean := sum / sampleH
+		stdDevs[x] = math.Sqrt(math.Max(sumSq/sampleH-mean*mean, 0))
+		total += stdDevs[x]
+	}
+	// Однотонная картинка — сгиб искать бессмысленно
+	if total/sampleW < gutterMinContrast {
+		return 0, false
+	}
+
+	center := sampleW / 2
+	best, bestX := math.MaxFloat64, -1
+	for x := sampleW * 2 / 5; x < sampleW*3/5; x++ {
+		// при равенстве предпочитаем столбец ближе к центру
+		if stdDevs[x] < best || (stdDevs[x] == best && abs(x-center) < abs(bestX-center)) {
+			best, bestX = stdDevs[x], x
+		}
+	}
+	if bestX < 0 || best > gutterMaxStdDev {
+		return 0, false
+	}
+	w := img.Bounds().Dx()
+	return (bestX*w + w/2) / sampleW, true
+}
+
+// applySpreadMode превращает страницу в одну или несколько выходных картинок
+func applySpreadMode(img image.Image, mode string) ([]image.Image, string, spreadInfo) {
+	info := detectSpread(img)
+	if !info.spread {
+		return []image.Image{img}, SpreadActionNone, info
+	}
+
+	b := img.Bounds()
+	switch mode {
+	case SpreadSplitRTL, SpreadSplitLTR:
+		left := imaging.Crop(img, image.Rect(b.Min.X, b.Min.Y, b.Min.X+info.splitX, b.Max.Y))
+		right := imaging.Crop(img, image.Rect(b.Min.X+info.splitX, b.Min.Y, b.Max.X, b.Max.Y))
+		if mode == SpreadSplitRTL {
+			return []image.Image{right, left}, SpreadActionSplit, info
+		}
+		return []image.Image{left, right}, SpreadActionSplit, info
+	case SpreadRotate:
+		// По часовой стрелке: верх разворота оказывается справа
+		return []image.Image{imaging.Rotate270(img)}, SpreadActionRotate, info
+	}
+	return []image.Image{img}, SpreadActionKeep, info
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
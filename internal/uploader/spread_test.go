@@ -0,0 +1,230 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"telegraph_uploader_v2/internal/config"
+
+	"github.com/chai2010/webp"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// spreadImage рисует разворот: слева красноватая страница, справа синеватая, между ними белый сгиб
+func spreadImage(width, height int, gutter bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((y/15%2)*180 + (x/25%2)*20)
+			c := color.RGBA{v + 55, v / 2, v / 2, 255}
+			if x >= width/2 {
+				c = color.RGBA{v / 2, v / 2, v + 55, 255}
+			}
+			if gutter && x >= width/2-4 && x < width/2+4 {
+				c = color.RGBA{255, 255, 255, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func writePNG(t *testing.T, dir, name string, img image.Image) string {
+	path := filepath.Join(dir, name)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// isReddish декодирует WebP и проверяет, что в центре картинки преобладает красный
+func isReddish(t *testing.T, p *ProcessedImage) bool {
+	img, err := webp.Decode(bytes.NewReader(p.Content.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := img.Bounds()
+	r, _, bl, _ := img.At(b.Min.X+b.Dx()/4, b.Min.Y+b.Dy()/2).RGBA()
+	return r > bl
+}
+
+func TestDetectSpread(t *testing.T) {
+	tests := []struct {
+		name       string
+		img        image.Image
+		wantSpread bool
+		wantGutter bool
+	}{
+		{"Portrait page", spreadImage(300, 450, true), false, false},
+		{"Spread with gutter", spreadImage(600, 450, true), true, true},
+		{"Slightly wide with gutter", spreadImage(560, 450, true), true, true},
+		{"Slightly wide without gutter", spreadImage(560, 450, false), false, false},
+		{"Wide without gutter", spreadImage(800, 450, false), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := detectSpread(tt.img)
+			if info.spread != tt.wantSpread || info.gutter != tt.wantGutter {
+				t.Errorf("got spread=%v gutter=%v, want %v/%v", info.spread, info.gutter, tt.wantSpread, tt.wantGutter)
+			}
+			if mid := tt.img.Bounds().Dx() / 2; info.gutter && abs(info.splitX-mid) > 10 {
+				t.Errorf("split column %d is not at the gutter", info.splitX)
+			}
+		})
+	}
+}
+
+func TestProcessPage_SpreadModes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, spreadImage(600, 450, true)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// RTL: первой идёт правая (синяя) половина
+	pages, decision, err := processPage(data, "spread.png", ResizeSettings{SpreadMode: SpreadSplitRTL, WebpQuality: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 || decision.Action != SpreadActionSplit || decision.Pages != 2 || !decision.Spread {
+		t.Fatalf("unexpected split result: %d pages, %+v", len(pages), decision)
+	}
+	if isReddish(t, pages[0]) || !isReddish(t, pages[1]) {
+		t.Error("RTL order must start with the right half")
+	}
+	if pages[0].FileName == pages[1].FileName {
+		t.Error("split halves must have different names")
+	}
+
+	pages, _, err = processPage(data, "spread.png", ResizeSettings{SpreadMode: SpreadSplitLTR, WebpQuality: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isReddish(t, pages[0]) || isReddish(t, pages[1]) {
+		t.Error("LTR order must start with the left half")
+	}
+
+	pages, decision, err = processPage(data, "spread.png", ResizeSettings{SpreadMode: SpreadRotate, WebpQuality: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, _ := webp.Decode(bytes.NewReader(pages[0].Content.Bytes()))
+	if decision.Action != SpreadActionRotate || img.Bounds().Dx() != 450 || img.Bounds().Dy() != 600 {
+		t.Errorf("expected rotated 450x600, got %v (%+v)", img.Bounds(), decision)
+	}
+
+	pages, decision, err = processPage(data, "spread.png", ResizeSettings{WebpQuality: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || decision.Action != SpreadActionKeep || !decision.Spread {
+		t.Errorf("default mode must keep spread whole, got %+v", decision)
+	}
+}
+
+func TestUploadChapter_SplitSpread(t *testing.T) {
+	var puts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.Method == "PUT" {
+			atomic.AddInt32(&puts, 1)
+			w.Header().Set("ETag", "\"1234567890abcdef\"")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	minioClient, _ := minio.New(ts.Listener.Addr().String(), &minio.Options{
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+		Secure: false,
+		Region: "us-east-1",
+	})
+	cache := &mockCache{urls: map[string]string{}}
+	u := NewWithClient(minioClient, &config.Config{BucketName: "bucket", PublicDomain: "http://test.com"}, cache)
+
+	tmpDir := t.TempDir()
+	spread := writePNG(t, tmpDir, "002.png", spreadImage(600, 450, true))
+	page := createPatternImage(t, tmpDir, "001.png", 300, 450, 1)
+	settings := ResizeSettings{SpreadMode: SpreadSplitRTL, WebpQuality: 80}
+
+	result := u.UploadChapter(context.Background(), []string{page, spread}, settings, nil)
+	if !result.Success {
+		t.Fatalf("upload failed: %s", result.Error)
+	}
+	if len(result.Links) != 3 || len(result.FileLinks) != 2 || len(result.FileLinks[1]) != 2 {
+		t.Fatalf("expected 3 links (1 + 2 halves), got %v / %v", result.Links, result.FileLinks)
+	}
+	if result.Spreads[0].Action != SpreadActionNone || result.Spreads[1].Action != SpreadActionSplit {
+		t.Errorf("unexpected decisions: %+v", result.Spreads)
+	}
+
+	// Повторная загрузка берёт обе половины из кэша
+	atomic.StoreInt32(&puts, 0)
+	result = u.UploadChapter(context.Background(), []string{page, spread}, settings, nil)
+	if !result.Success || len(result.Links) != 3 || atomic.LoadInt32(&puts) != 0 {
+		t.Errorf("expected cached halves without uploads, got %d links, %d puts", len(result.Links), puts)
+	}
+	if result.Spreads[1].Action != SpreadActionCached || result.Spreads[1].Pages != 2 {
+		t.Errorf("unexpected cached decision: %+v", result.Spreads[1])
+	}
+}
+
+func TestUploadChapter_SpreadModeChanged(t *testing.T) {
+	var puts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.Method == "PUT" {
+			atomic.AddInt32(&puts, 1)
+			w.Header().Set("ETag", "\"1234567890abcdef\"")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	minioClient, _ := minio.New(ts.Listener.Addr().String(), &minio.Options{
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+		Secure: false,
+		Region: "us-east-1",
+	})
+	cache := &mockCache{urls: map[string]string{}}
+	u := NewWithClient(minioClient, &config.Config{BucketName: "bucket", PublicDomain: "http://test.com"}, cache)
+
+	tmpDir := t.TempDir()
+	spread := writePNG(t, tmpDir, "002.png", spreadImage(600, 450, true))
+	page := createPatternImage(t, tmpDir, "001.png", 300, 450, 1)
+	files := []string{page, spread}
+
+	result := u.UploadChapter(context.Background(), files, ResizeSettings{SpreadMode: SpreadKeep, WebpQuality: 80}, nil)
+	if !result.Success || len(result.Links) != 2 {
+		t.Fatalf("keep upload failed: %s %v", result.Error, result.Links)
+	}
+
+	// Тайтл переключили на разрезание: разворот из кэша режима keep не годится
+	atomic.StoreInt32(&puts, 0)
+	result = u.UploadChapter(context.Background(), files, ResizeSettings{SpreadMode: SpreadSplitRTL, WebpQuality: 80}, nil)
+	if !result.Success {
+		t.Fatalf("split upload failed: %s", result.Error)
+	}
+	if len(result.Links) != 3 || result.Spreads[1].Action != SpreadActionSplit {
+		t.Errorf("expected spread to be split after mode change, got %v / %+v", result.Links, result.Spreads[1])
+	}
+	// Обычная страница по-прежнему берётся из кэша
+	if result.Spreads[0].Action != SpreadActionCached || atomic.LoadInt32(&puts) != 2 {
+		t.Errorf("expected cached page and 2 uploads, got %+v, %d puts", result.Spreads[0], puts)
+	}
+}